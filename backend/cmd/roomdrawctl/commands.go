@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"roomdraw/backend/pkg/config"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/handlers"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/middleware"
+	"roomdraw/backend/pkg/models"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// action is one handler call. route is the pattern the server registers the
+// handler under and path the concrete URL, so c.Param and c.FullPath behave
+// exactly as they do behind the HTTP API.
+type action struct {
+	method  string
+	route   string
+	path    string
+	body    interface{}
+	handler gin.HandlerFunc
+
+	// operation, entityType and entityID describe the write for
+	// transaction_logs when the handler does not log it itself
+	operation  string
+	entityType string
+	entityID   string
+}
+
+var exportEntityTypes = map[string]string{
+	"rooms":       models.EntityTypeRoom,
+	"suites":      models.EntityTypeSuite,
+	"users":       models.EntityTypeUser,
+	"suitegroups": models.EntityTypeSuiteGroup,
+	"ratelimits":  models.EntityTypeRateLimit,
+}
+
+// run executes a command and returns the process exit code
+func run(cfg *config.Config, actor string, command []string) int {
+	name, args := command[0], command[1:]
+
+	switch {
+	case name == "preplace" && len(args) == 2 && args[0] == "remove":
+		roomUUID, ok := parseUUID(args[1])
+		if !ok {
+			return 2
+		}
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/rooms/preplace/remove/:roomuuid", path: "/rooms/preplace/remove/" + roomUUID,
+			handler:   handlers.RemovePreplacedOccupantsHandler,
+			operation: "REMOVE_PREPLACED_OCCUPANTS", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "preplace" && len(args) >= 2:
+		roomUUID, ok := parseUUID(args[0])
+		if !ok {
+			return 2
+		}
+		occupants := models.IntArray{}
+		for _, arg := range args[1:] {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid user id %q\n", arg)
+				return 2
+			}
+			occupants = append(occupants, id)
+		}
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/rooms/preplace/:roomuuid", path: "/rooms/preplace/" + roomUUID,
+			body:      models.PreplacedRequest{ProposedOccupants: occupants},
+			handler:   handlers.PreplaceOccupants,
+			operation: "PREPLACE_OCCUPANTS", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "clear" && len(args) == 1:
+		roomUUID, ok := parseUUID(args[0])
+		if !ok {
+			return 2
+		}
+		// operators are not subject to the daily clear limit meant for students
+		draw := cfg.Draw
+		draw.MaxDailyClears = math.MaxInt32
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/rooms/clear/:roomuuid", path: "/rooms/clear/" + roomUUID,
+			handler:   handlers.ClearRoomHandler(draw),
+			operation: "CLEAR_ROOM", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "frosh" && len(args) == 2 && args[0] == "add":
+		roomUUID, ok := parseUUID(args[1])
+		if !ok {
+			return 2
+		}
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/frosh/:roomuuid", path: "/frosh/" + roomUUID,
+			handler:   handlers.AddFroshHandler,
+			operation: "ADD_FROSH", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "frosh" && len(args) == 2 && args[0] == "remove":
+		roomUUID, ok := parseUUID(args[1])
+		if !ok {
+			return 2
+		}
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/frosh/remove/:roomuuid", path: "/frosh/remove/" + roomUUID,
+			handler:   handlers.RemoveFroshHandler,
+			operation: "REMOVE_FROSH", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "frosh" && len(args) == 3 && args[0] == "move":
+		roomUUID, ok := parseUUID(args[1])
+		if !ok {
+			return 2
+		}
+		targetUUID, ok := parseUUID(args[2])
+		if !ok {
+			return 2
+		}
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/frosh/bump/:roomuuid", path: "/frosh/bump/" + roomUUID,
+			body:      models.BumpFroshRequest{TargetRoomUUID: uuid.MustParse(targetUUID)},
+			handler:   handlers.BumpFroshHandler,
+			operation: "BUMP_FROSH", entityType: models.EntityTypeRoom, entityID: roomUUID,
+		})
+
+	case name == "blocklist" && len(args) == 1 && args[0] == "list":
+		return dispatch(actor, command, action{
+			method: http.MethodGet, route: "/admin/blocklist", path: "/admin/blocklist",
+			handler: handlers.GetBlocklistedUsers,
+		})
+
+	case name == "blocklist" && len(args) == 2 && args[0] == "remove":
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/admin/blocklist/remove/:email", path: "/admin/blocklist/remove/" + args[1],
+			handler:   handlers.RemoveUserBlocklist,
+			operation: "REMOVE_BLOCKLIST", entityType: models.EntityTypeRateLimit, entityID: args[1],
+		})
+
+	case name == "gender-prefs" && len(args) == 1 && args[0] == "recompute":
+		return dispatch(actor, command, action{
+			method: http.MethodPost, route: "/admin/suites/update-gender-preferences", path: "/admin/suites/update-gender-preferences",
+			handler:   handlers.UpdateSuiteGenderPreference,
+			operation: "RECOMPUTE_GENDER_PREFERENCES", entityType: models.EntityTypeSuite, entityID: "all",
+		})
+
+	case name == "integrity" && len(args) == 0:
+		issues, err := handlers.CheckIntegrity(context.Background())
+		if err != nil {
+			slog.Error("Integrity check failed", "error", err)
+			return 1
+		}
+		printJSON(issues)
+		if len(issues) > 0 {
+			fmt.Fprintf(os.Stderr, "%d integrity issues found\n", len(issues))
+			return 1
+		}
+		return 0
+
+	case name == "export" && (len(args) == 1 || len(args) == 2):
+		entityType, ok := exportEntityTypes[args[0]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "cannot export %q\n", args[0])
+			return 2
+		}
+		var dorm *int
+		if len(args) == 2 {
+			d, err := strconv.Atoi(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid dorm %q\n", args[1])
+				return 2
+			}
+			dorm = &d
+		}
+		data, err := handlers.ExportEntities(context.Background(), entityType, dorm)
+		if err != nil {
+			slog.Error("Export failed", "entity_type", entityType, "error", err)
+			return 1
+		}
+		printJSON(data)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, usage)
+	return 2
+}
+
+// dispatch runs a handler for the CLI actor and prints the response
+func dispatch(actor string, command []string, a action) int {
+	recorder, err := serve(actor, command, a)
+	if err != nil {
+		slog.Error("Failed to run command", "command", strings.Join(command, " "), "error", err)
+		return 1
+	}
+
+	var response interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err == nil {
+		printJSON(response)
+	} else {
+		fmt.Println(recorder.Body.String())
+	}
+
+	if recorder.Code >= 300 {
+		fmt.Fprintf(os.Stderr, "%s failed with status %d\n", strings.Join(command, " "), recorder.Code)
+		return 1
+	}
+	return 0
+}
+
+// serve runs a handler in-process behind the same middleware the server
+// applies to authenticated writes, with actor standing in for the signed in
+// user, and returns the recorded response
+func serve(actor string, command []string, a action) (*httptest.ResponseRecorder, error) {
+	engine := gin.New()
+	engine.Use(
+		logging.RequestLoggerMiddleware(),
+		logging.TransactionLogMiddleware(),
+		cliActorMiddleware(actor),
+		middleware.OpenTermMiddleware(),
+		middleware.WriteLockMiddleware(),
+		auditMiddleware(a, command),
+		handlers.FavoriteAlertMiddleware(),
+	)
+	engine.Handle(a.method, a.route, a.handler)
+
+	var body bytes.Buffer
+	if a.body != nil {
+		if err := json.NewEncoder(&body).Encode(a.body); err != nil {
+			return nil, fmt.Errorf("error encoding request: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(a.method, a.path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "127.0.0.1:0"
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+
+	return recorder, nil
+}
+
+// cliActorMiddleware sets the context keys JWTAuthMiddleware would, so
+// handlers and LogOperation attribute the action to the CLI actor
+func cliActorMiddleware(actor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("email", actor)
+		c.Set("user_full_name", "roomdrawctl ("+actor+")")
+		logging.AddLogAttrs(c, "email", actor)
+		c.Next()
+	}
+}
+
+// auditMiddleware records a successful write in transaction_logs unless the
+// handler already logged it under this request ID
+func auditMiddleware(a action, command []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if a.operation == "" || c.Writer.Status() >= 300 {
+			return
+		}
+
+		var logged bool
+		err := database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM transaction_logs WHERE request_id = $1)", c.MustGet("request_id")).Scan(&logged)
+		if err != nil {
+			logging.FromContext(c).Warn("Failed to check transaction logs", "operation", a.operation, "error", err)
+		}
+		if logged {
+			return
+		}
+
+		details := map[string]interface{}{"source": "roomdrawctl", "command": strings.Join(command, " ")}
+		if a.body != nil {
+			details["request"] = a.body
+		}
+		if err := logging.LogOperation(c, a.operation, a.entityType, a.entityID, nil, nil, details); err != nil {
+			logging.FromContext(c).Warn("Failed to log operation", "operation", a.operation, "entity_id", a.entityID, "error", err)
+		}
+	}
+}
+
+func parseUUID(s string) (string, bool) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid uuid %q\n", s)
+		return "", false
+	}
+	return id.String(), true
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		slog.Error("Failed to write output", "error", err)
+	}
+}
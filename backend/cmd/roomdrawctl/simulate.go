@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"roomdraw/backend/pkg/config"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/handlers"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// defaultMaxAttempts caps how many rooms one student tries per turn
+	defaultMaxAttempts = 20
+	// maxChainLength stops a bump chain that keeps growing
+	maxChainLength = 100
+)
+
+// simScenario is the input to the simulator. The snapshot fields hold rows in
+// the format printed by `roomdrawctl export` and returned by the term
+// snapshot endpoints, either inline or as a path to such a file.
+type simScenario struct {
+	Suites      json.RawMessage `json:"suites"`
+	SuiteGroups json.RawMessage `json:"suitegroups"`
+	Users       json.RawMessage `json:"users"`
+	Rooms       json.RawMessage `json:"rooms"`
+
+	// ResetPlacements empties every room that was not preplaced before the
+	// draw starts, so a snapshot taken mid-draw can be replayed from scratch
+	ResetPlacements bool `json:"resetPlacements"`
+
+	DefaultStrategy simStrategy         `json:"defaultStrategy"`
+	Strategies      map[int]simStrategy `json:"strategies"`
+
+	// RoomRanking orders rooms for greedy students, best first. Rooms not
+	// listed come after, by dorm and room number.
+	RoomRanking []uuid.UUID `json:"roomRanking"`
+	MaxAttempts int         `json:"maxAttempts"`
+
+	// KeepDatabase leaves the throwaway database in place for inspection
+	KeepDatabase bool `json:"keepDatabase"`
+}
+
+// simStrategy is how one student picks rooms
+type simStrategy struct {
+	Type  string      `json:"type"` // ranked, greedy or none
+	Rooms []simChoice `json:"rooms"`
+}
+
+// simChoice is one ranked room, the roommates pulled into it and the pull to use.
+// Without a pullType the simulator uses a pull the eligibility search says would
+// succeed, and a self pull if there is none.
+type simChoice struct {
+	Room           uuid.UUID `json:"room"`
+	With           []int     `json:"with"`
+	PullType       int       `json:"pullType"`
+	PullLeaderRoom uuid.UUID `json:"pullLeaderRoom"`
+}
+
+// simEligibleRoom is the part of an eligible rooms answer the simulator uses
+type simEligibleRoom struct {
+	RoomUUID         uuid.UUID               `json:"roomUuid"`
+	CurrentOccupancy int                     `json:"currentOccupancy"`
+	Pulls            []handlers.EligiblePull `json:"pulls"`
+}
+
+type simPlacement struct {
+	UserID   int       `json:"userId"`
+	Name     string    `json:"name"`
+	RoomUUID uuid.UUID `json:"roomUuid"`
+	RoomID   string    `json:"roomId"`
+	DormName string    `json:"dormName"`
+}
+
+type simRejection struct {
+	UserID   int       `json:"userId"`
+	RoomUUID uuid.UUID `json:"roomUuid"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error"`
+}
+
+type simBumpStep struct {
+	UserID int        `json:"userId"`
+	Room   *uuid.UUID `json:"room"` // where the user ended up, nil if nowhere
+	Bumped []int      `json:"bumped"`
+}
+
+type simBumpChain struct {
+	StartedBy int           `json:"startedBy"`
+	Steps     []simBumpStep `json:"steps"`
+}
+
+type simReport struct {
+	Database          string         `json:"database"`
+	Turns             int            `json:"turns"`
+	Placements        []simPlacement `json:"placements"`
+	Unplaced          []int          `json:"unplaced"`
+	DormAbsorbed      map[string]int `json:"dormAbsorbed"`
+	BumpChains        []simBumpChain `json:"bumpChains"`
+	RejectedAttempts  []simRejection `json:"rejectedAttempts"`
+	RejectionsByError map[string]int `json:"rejectionsByError"`
+}
+
+type simulation struct {
+	scenario simScenario
+	report   simReport
+}
+
+// runSimulate replays a draw against a freshly created database on the
+// configured server and prints a report. The configured database itself is
+// only used to create and drop the throwaway one.
+func runSimulate(cfg *config.Config, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: roomdrawctl simulate <scenario.json> [flags]")
+		return 2
+	}
+
+	scenario, err := loadScenario(args[0])
+	if err != nil {
+		slog.Error("Failed to load scenario", "path", args[0], "error", err)
+		return 1
+	}
+
+	simDB := cfg.Database
+	simDB.Name = "roomdraw_sim_" + time.Now().Format("20060102150405")
+
+	if err := createSimDatabase(cfg.Database, simDB.Name); err != nil {
+		slog.Error("Failed to create simulation database", "database", simDB.Name, "error", err)
+		return 1
+	}
+	if !scenario.KeepDatabase {
+		defer dropSimDatabase(cfg.Database, simDB.Name)
+	}
+
+	if err := database.InitDB(simDB); err != nil {
+		slog.Error("Failed to connect to simulation database", "database", simDB.Name, "error", err)
+		return 1
+	}
+	defer database.DB.Close()
+
+	// never load or pull anything unless we are certain this is the throwaway database
+	var current string
+	if err := database.DB.QueryRow("SELECT current_database()").Scan(&current); err != nil || current != simDB.Name {
+		slog.Error("Refusing to simulate outside the simulation database", "connected", current, "database", simDB.Name, "error", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	if _, err := database.MigrateUp(ctx); err != nil {
+		slog.Error("Failed to migrate simulation database", "database", simDB.Name, "error", err)
+		return 1
+	}
+	if err := scenario.load(ctx); err != nil {
+		slog.Error("Failed to load snapshot", "error", err)
+		return 1
+	}
+	if err := handlers.LoadDorms(ctx); err != nil {
+		slog.Error("Failed to load dorms", "error", err)
+		return 1
+	}
+
+	sim := &simulation{scenario: scenario}
+	sim.report.Database = simDB.Name
+	if err := sim.run(ctx); err != nil {
+		slog.Error("Simulation failed", "error", err)
+		return 1
+	}
+
+	printJSON(sim.report)
+	return 0
+}
+
+func loadScenario(path string) (simScenario, error) {
+	var scenario simScenario
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return scenario, err
+	}
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return scenario, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	// snapshot fields given as a string are paths relative to the scenario
+	dir := filepath.Dir(path)
+	for _, rows := range []*json.RawMessage{&scenario.Suites, &scenario.SuiteGroups, &scenario.Users, &scenario.Rooms} {
+		var file string
+		if json.Unmarshal(*rows, &file) != nil {
+			continue
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if *rows, err = os.ReadFile(file); err != nil {
+			return scenario, err
+		}
+	}
+
+	if scenario.DefaultStrategy.Type == "" {
+		scenario.DefaultStrategy.Type = "greedy"
+	}
+	if scenario.MaxAttempts <= 0 {
+		scenario.MaxAttempts = defaultMaxAttempts
+	}
+	return scenario, nil
+}
+
+func createSimDatabase(admin config.DatabaseConfig, name string) error {
+	if err := database.InitDB(admin); err != nil {
+		return err
+	}
+	defer database.DB.Close()
+
+	slog.Info("Creating simulation database", "database", name)
+	_, err := database.DB.Exec("CREATE DATABASE " + pq.QuoteIdentifier(name))
+	return err
+}
+
+func dropSimDatabase(admin config.DatabaseConfig, name string) {
+	// the simulation connections must be gone before the database can be dropped
+	database.DB.Close()
+
+	if err := database.InitDB(admin); err != nil {
+		slog.Error("Failed to reconnect to drop the simulation database, drop it by hand", "database", name, "error", err)
+		return
+	}
+	defer database.DB.Close()
+
+	if _, err := database.DB.Exec("DROP DATABASE IF EXISTS " + pq.QuoteIdentifier(name)); err != nil {
+		slog.Error("Failed to drop the simulation database, drop it by hand", "database", name, "error", err)
+		return
+	}
+	slog.Info("Dropped simulation database", "database", name)
+}
+
+// load copies the snapshot into the simulation database
+func (s simScenario) load(ctx context.Context) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// parents before children for the foreign keys
+	tables := []struct {
+		name string
+		rows json.RawMessage
+	}{
+		{"suites", s.Suites},
+		{"suitegroups", s.SuiteGroups},
+		{"users", s.Users},
+		{"rooms", s.Rooms},
+	}
+	for _, table := range tables {
+		if len(table.rows) == 0 {
+			continue
+		}
+		result, err := tx.Exec("INSERT INTO "+table.name+" SELECT * FROM jsonb_populate_recordset(NULL::"+table.name+", $1::jsonb)", []byte(table.rows))
+		if err != nil {
+			return fmt.Errorf("error loading %s: %v", table.name, err)
+		}
+		count, _ := result.RowsAffected()
+		slog.Info("Loaded snapshot table", "table", table.name, "rows", count)
+	}
+
+	statements := []string{
+		// nobody gets bump emails from a simulation
+		"UPDATE users SET notifications_enabled = false",
+		"SELECT setval(pg_get_serial_sequence('users', 'id'), COALESCE(MAX(id), 1)) FROM users",
+	}
+	if s.ResetPlacements {
+		statements = append(statements,
+			`UPDATE rooms SET occupants = '{}', current_occupancy = 0, pull_priority = DEFAULT, sgroup_uuid = NULL
+             WHERE NOT COALESCE((pull_priority->>'isPreplaced')::bool, false)`,
+			`UPDATE users SET room_uuid = NULL, participated = false, participation_time = NULL
+             WHERE NOT preplaced`,
+			"UPDATE rooms SET sgroup_uuid = NULL",
+			"UPDATE users SET sgroup_uuid = NULL",
+			"DELETE FROM suitegroups",
+			"UPDATE suites SET lock_pulled_room = NULL",
+		)
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// run gives every unplaced student a turn in draw order. A student who is
+// bumped gets another turn straight away, which is how bump chains form.
+func (s *simulation) run(ctx context.Context) error {
+	rows, err := database.DB.QueryContext(ctx, "SELECT id, year, draw_number, preplaced, in_dorm FROM users")
+	if err != nil {
+		return err
+	}
+	var users []models.UserRaw
+	for rows.Next() {
+		var user models.UserRaw
+		if err := rows.Scan(&user.Id, &user.Year, &user.DrawNumber, &user.Preplaced, &user.InDorm); err != nil {
+			rows.Close()
+			return err
+		}
+		users = append(users, user)
+	}
+	rows.Close()
+
+	initial, err := placements(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.report.RejectedAttempts = []simRejection{}
+	s.report.RejectionsByError = map[string]int{}
+	s.report.BumpChains = []simBumpChain{}
+
+	for _, user := range handlers.DrawOrder(users) {
+		placed, err := placements(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := placed[user.Id]; ok {
+			continue
+		}
+
+		room, bumped, err := s.turn(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+		if len(bumped) == 0 {
+			continue
+		}
+
+		chain := simBumpChain{StartedBy: user.Id, Steps: []simBumpStep{{UserID: user.Id, Room: room, Bumped: bumped}}}
+		queue := bumped
+		for len(queue) > 0 && len(chain.Steps) < maxChainLength {
+			next := queue[0]
+			queue = queue[1:]
+			room, bumped, err := s.turn(ctx, next)
+			if err != nil {
+				return err
+			}
+			chain.Steps = append(chain.Steps, simBumpStep{UserID: next, Room: room, Bumped: bumped})
+			queue = append(queue, bumped...)
+		}
+		s.report.BumpChains = append(s.report.BumpChains, chain)
+	}
+
+	return s.summarize(ctx, users, initial)
+}
+
+// turn lets one student try rooms until a pull succeeds. It returns the room
+// they ended up in, if any, and the students their pull bumped.
+func (s *simulation) turn(ctx context.Context, userID int) (*uuid.UUID, []int, error) {
+	s.report.Turns++
+
+	choices, err := s.choices(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// failed attempts change nothing, so each group's eligible rooms hold for the turn
+	eligible := map[string][]simEligibleRoom{}
+	for i, choice := range choices {
+		if i >= s.scenario.MaxAttempts {
+			break
+		}
+
+		if choice.PullType == 0 {
+			key := fmt.Sprint(choice.With)
+			rooms, ok := eligible[key]
+			if !ok {
+				if rooms, err = eligibleRooms(userID, choice.With); err != nil {
+					return nil, nil, err
+				}
+				eligible[key] = rooms
+			}
+			choice.PullType = 1
+			for _, room := range rooms {
+				if room.RoomUUID == choice.Room {
+					choice.PullType, choice.PullLeaderRoom = choosePull(room.Pulls)
+					break
+				}
+			}
+		}
+
+		before, err := placements(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		roomUUID := choice.Room.String()
+		recorder, err := serve("sim:user-"+strconv.Itoa(userID), nil, action{
+			method: http.MethodPost, route: "/rooms/:roomuuid", path: "/rooms/" + roomUUID,
+			body: models.OccupantUpdateRequest{
+				ProposedOccupants: append(models.IntArray{userID}, choice.With...),
+				PullType:          choice.PullType,
+				PullLeaderRoom:    choice.PullLeaderRoom,
+			},
+			handler: handlers.UpdateRoomOccupants,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if recorder.Code >= 300 {
+			var response struct {
+				Error string `json:"error"`
+			}
+			json.Unmarshal(recorder.Body.Bytes(), &response)
+			s.report.RejectedAttempts = append(s.report.RejectedAttempts, simRejection{
+				UserID: userID, RoomUUID: choice.Room, Status: recorder.Code, Error: response.Error,
+			})
+			s.report.RejectionsByError[response.Error]++
+			continue
+		}
+
+		after, err := placements(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		bumped := []int{}
+		for id := range before {
+			if _, ok := after[id]; !ok {
+				bumped = append(bumped, id)
+			}
+		}
+		sort.Ints(bumped)
+
+		room := choice.Room
+		return &room, bumped, nil
+	}
+
+	return nil, nil, nil
+}
+
+// choices lists the rooms a student will try this turn, best first
+func (s *simulation) choices(ctx context.Context, userID int) ([]simChoice, error) {
+	strategy, ok := s.scenario.Strategies[userID]
+	if !ok {
+		strategy = s.scenario.DefaultStrategy
+	}
+
+	switch strategy.Type {
+	case "none":
+		return nil, nil
+	case "ranked":
+		return strategy.Rooms, nil
+	case "greedy":
+	default:
+		return nil, fmt.Errorf("user %d has unknown strategy %q", userID, strategy.Type)
+	}
+
+	// greedy: every room the student could take alone by some pull, empty ones
+	// first, in ranking order and then in the order the eligibility search ranks them
+	rooms, err := eligibleRooms(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	rank := func(room simEligibleRoom) int {
+		if i := slices.Index(s.scenario.RoomRanking, room.RoomUUID); i >= 0 {
+			return i
+		}
+		return len(s.scenario.RoomRanking)
+	}
+	slices.SortStableFunc(rooms, func(a, b simEligibleRoom) int {
+		if (a.CurrentOccupancy > 0) != (b.CurrentOccupancy > 0) {
+			if a.CurrentOccupancy > 0 {
+				return 1
+			}
+			return -1
+		}
+		return rank(a) - rank(b)
+	})
+
+	choices := make([]simChoice, 0, len(rooms))
+	for _, room := range rooms {
+		choice := simChoice{Room: room.RoomUUID}
+		choice.PullType, choice.PullLeaderRoom = choosePull(room.Pulls)
+		choices = append(choices, choice)
+	}
+	return choices, nil
+}
+
+// eligibleRooms asks the eligibility search which rooms a student and their
+// roommates could pull into right now, and by which pulls. A group the search
+// refuses, e.g. because a roommate is already placed, has none.
+func eligibleRooms(userID int, with []int) ([]simEligibleRoom, error) {
+	path := "/users/" + strconv.Itoa(userID) + "/eligible-rooms"
+	if len(with) > 0 {
+		ids := make([]string, 0, len(with))
+		for _, id := range with {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		path += "?with=" + strings.Join(ids, ",")
+	}
+
+	recorder, err := serve("sim:user-"+strconv.Itoa(userID), nil, action{
+		method: http.MethodGet, route: "/users/:userid/eligible-rooms", path: path,
+		handler: handlers.GetEligibleRooms,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if recorder.Code >= 500 {
+		return nil, fmt.Errorf("eligible rooms of user %d: %s", userID, recorder.Body.String())
+	}
+	if recorder.Code >= 300 {
+		return nil, nil
+	}
+
+	var response struct {
+		Rooms []simEligibleRoom `json:"rooms"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return nil, err
+	}
+	return response.Rooms, nil
+}
+
+// choosePull picks the pull a student makes from those that would succeed: a lock
+// pull if there is one, since nobody can bump it, and otherwise the first listed
+func choosePull(pulls []handlers.EligiblePull) (int, uuid.UUID) {
+	if len(pulls) == 0 {
+		return 1, uuid.Nil
+	}
+	pull := pulls[0]
+	if i := slices.IndexFunc(pulls, func(p handlers.EligiblePull) bool { return p.PullType == 3 }); i >= 0 {
+		pull = pulls[i]
+	}
+	if pull.PullLeaderRoom == nil {
+		return pull.PullType, uuid.Nil
+	}
+	return pull.PullType, *pull.PullLeaderRoom
+}
+
+// placements maps every placed student to their room
+func placements(ctx context.Context) (map[int]uuid.UUID, error) {
+	rows, err := database.DB.QueryContext(ctx, "SELECT id, room_uuid FROM users WHERE room_uuid IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	placed := map[int]uuid.UUID{}
+	for rows.Next() {
+		var id int
+		var room uuid.UUID
+		if err := rows.Scan(&id, &room); err != nil {
+			return nil, err
+		}
+		placed[id] = room
+	}
+	return placed, rows.Err()
+}
+
+// summarize fills in final placements and per-dorm totals. A dorm absorbs a
+// student who was placed in it during the simulation.
+func (s *simulation) summarize(ctx context.Context, users []models.UserRaw, initial map[int]uuid.UUID) error {
+	rows, err := database.DB.QueryContext(ctx, `
+        SELECT u.id, u.first_name || ' ' || u.last_name, r.room_uuid, r.room_id, r.dorm_name
+        FROM users u JOIN rooms r ON r.room_uuid = u.room_uuid
+        ORDER BY r.dorm, r.room_id, u.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	s.report.Placements = []simPlacement{}
+	s.report.DormAbsorbed = map[string]int{}
+	placed := map[int]bool{}
+	for rows.Next() {
+		var p simPlacement
+		if err := rows.Scan(&p.UserID, &p.Name, &p.RoomUUID, &p.RoomID, &p.DormName); err != nil {
+			return err
+		}
+		s.report.Placements = append(s.report.Placements, p)
+		placed[p.UserID] = true
+		if room, ok := initial[p.UserID]; !ok || room != p.RoomUUID {
+			s.report.DormAbsorbed[p.DormName]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.report.Unplaced = []int{}
+	for _, user := range users {
+		strategy, ok := s.scenario.Strategies[user.Id]
+		if !ok {
+			strategy = s.scenario.DefaultStrategy
+		}
+		if !placed[user.Id] && strategy.Type != "none" {
+			s.report.Unplaced = append(s.report.Unplaced, user.Id)
+		}
+	}
+	sort.Ints(s.report.Unplaced)
+
+	return nil
+}
@@ -1,119 +1,282 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
 	"roomdraw/backend/pkg/handlers"
-	"roomdraw/backend/pkg/middleware"
 	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/middleware"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout bounds how long a SIGTERM waits for writes and notifications
+// to finish. It is longer than the per-request queue deadline.
+const shutdownTimeout = 45 * time.Second
+
 func main() {
-	// Load configuration once
-	if err := config.LoadConfig(); err != nil {
-		panic(err)
+	// `server migrate ...` manages the schema instead of starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// Load configuration once, from defaults, an optional YAML file, the environment and flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Set up leveled logging before anything else logs
+	logLevel, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		log.Fatalf("Invalid LOG_LEVEL: %v", err)
 	}
+	logger, err := logging.NewLogger(os.Stdout, logLevel, cfg.Log.Format)
+	if err != nil {
+		log.Fatalf("Invalid LOG_FORMAT: %v", err)
+	}
+	logging.Setup(logger)
 
 	// Initialize email service after config is loaded
-	handlers.InitializeEmailService()
+	handlers.InitializeEmailService(cfg.Email)
 
-	err := database.InitDB()
+	err = database.InitDB(cfg.Database)
 	if err != nil {
-		panic(err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer database.DB.Close()
 
+	// Refuse to serve against a schema this build was not written for
+	if err := database.CheckSchemaVersion(context.Background()); err != nil {
+		log.Fatalf("Schema check failed: %v", err)
+	}
+
+	// Dorm rules are looked up from the dorms table
+	if err := handlers.LoadDorms(context.Background()); err != nil {
+		log.Fatalf("Failed to load dorms: %v", err)
+	}
+
+	// Keep Google's keys for JWT validation fresh in the background
+	if cfg.Auth.Required {
+		go middleware.RefreshGooglePublicKeys(context.Background(), time.Hour)
+	}
+
 	router := gin.Default()
 
 	// Configure CORS middleware options
 	corsConfig := cors.Config{
-		AllowOrigins:     []string{"http://localhost:3001", "http://localhost:8081", "https://www.cs.hmc.edu"},
+		AllowOrigins:     cfg.Server.CORSOrigins,
 		AllowMethods:     []string{"PUT", "PATCH", "GET", "POST", "DELETE"},
 		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		AllowOriginFunc: func(origin string) bool {
-			return origin == "http://localhost:3001" || origin == "http://localhost:8081" || origin == "https://www.cs.hmc.edu"
+			return slices.Contains(cfg.Server.CORSOrigins, origin)
 		},
 		MaxAge: 12 * time.Hour,
 	}
 
-	// Initialize the request queue with concurrency of 1
-	// This ensures that all write operations are processed one at a time
-	requestQueue := middleware.NewRequestQueue(1)
+	// Initialize the request queue
+	// Writes to the same suite are processed one at a time, writes to different suites run concurrently
+	// Once the queue is WriteQueueMaxDepth deep new writes are turned away with a 503
+	requestQueue := middleware.NewRequestQueue(cfg.WriteQueue.MaxDepth)
 
 	// Apply the middleware globally
 	router.Use(cors.New(corsConfig))
+	router.Use(logging.RequestLoggerMiddleware())
 
 	// Group routes by read and write operations
 	readGroup := router.Group("/")
-	if config.RequireAuth {
+	if cfg.Auth.Required {
 		// Apply JWT only if required, non-blocking for read? Adjust as needed.
 		// If JWTAuthMiddleware aborts on failure, unauthenticated users can't read.
 		// Consider a less strict auth check for reads if needed.
-		readGroup.Use(middleware.JWTAuthMiddleware(false)) // Check token if present, but don't *require* it? Or require for all?
+		readGroup.Use(middleware.JWTAuthMiddleware(cfg.Auth, false)) // Check token if present, but don't *require* it? Or require for all?
 	}
 
 	// Write group - applies Queue, Logging, JWT (required), Blocklist
 	writeGroup := router.Group("/")
-	writeGroup.Use(middleware.QueueMiddleware(requestQueue))       // 1. Serialize
-	if config.RequireAuth {                                        // Only add Auth/Blocklist if required
-		writeGroup.Use(logging.TransactionLogMiddleware())         // 2. Add Request ID
-		writeGroup.Use(middleware.JWTAuthMiddleware(false))    // 3. Authenticate (non-admin) & add user info to context
-		writeGroup.Use(middleware.BlocklistCheckMiddleware())  // 4. Check blocklist
+	writeGroup.Use(middleware.QueueMiddleware(requestQueue)) // 1. Serialize per suite
+	if cfg.Auth.Required {                                   // Only add Auth/Blocklist if required
+		writeGroup.Use(logging.TransactionLogMiddleware())            // 2. Add Request ID
+		writeGroup.Use(middleware.JWTAuthMiddleware(cfg.Auth, false)) // 3. Authenticate (non-admin) & add user info to context
+		writeGroup.Use(middleware.BlocklistCheckMiddleware())         // 4. Check blocklist
 	}
+	writeGroup.Use(middleware.OpenTermMiddleware())    // 5. Reject writes while the draw term is closed
+	writeGroup.Use(handlers.FavoriteAlertMiddleware()) // 6. Alert users whose favorites the write changed
 
 	// Admin Write group - applies Queue, Logging, JWT (admin required)
 	writeGroupAdmin := router.Group("/")
-	writeGroupAdmin.Use(middleware.QueueMiddleware(requestQueue))  // 1. Serialize
-	if config.RequireAuth {                                       // Only add Auth if required
-		writeGroupAdmin.Use(logging.TransactionLogMiddleware())    // 2. Add Request ID
-		writeGroupAdmin.Use(middleware.JWTAuthMiddleware(true)) // 3. Authenticate (admin required) & add user info
+	writeGroupAdmin.Use(middleware.QueueMiddleware(requestQueue)) // 1. Serialize per suite
+	if cfg.Auth.Required {                                        // Only add Auth if required
+		writeGroupAdmin.Use(logging.TransactionLogMiddleware())           // 2. Add Request ID
+		writeGroupAdmin.Use(middleware.JWTAuthMiddleware(cfg.Auth, true)) // 3. Authenticate (admin required) & add user info
 		// No BlocklistCheck needed for admins? Add if needed.
 	}
+	writeGroupAdmin.Use(middleware.OpenTermMiddleware())    // 4. Reject writes while the draw term is closed
+	writeGroupAdmin.Use(handlers.FavoriteAlertMiddleware()) // 5. Alert users whose favorites the write changed
+
+	// Term admin group - same as the admin write group, but usable while the term is closed
+	termGroupAdmin := router.Group("/")
+	termGroupAdmin.Use(middleware.QueueMiddleware(requestQueue))
+	if cfg.Auth.Required {
+		termGroupAdmin.Use(logging.TransactionLogMiddleware())
+		termGroupAdmin.Use(middleware.JWTAuthMiddleware(cfg.Auth, true))
+	}
+
+	// Admin Read group - JWT (admin required), kept out of the queue so it stays responsive under load
+	readGroupAdmin := router.Group("/")
+	if cfg.Auth.Required {
+		readGroupAdmin.Use(middleware.JWTAuthMiddleware(cfg.Auth, true))
+	}
 
 	// Define read-only routes
 	readGroup.GET("/rooms", handlers.GetRoomsHandler)
 	readGroup.GET("/rooms/simple/:dormName", handlers.GetSimpleFormattedDorm)
 	readGroup.GET("/rooms/simpler/:dormName", handlers.GetSimplerFormattedDorm)
 	readGroup.GET("/rooms/:roomuuid", handlers.GetRoom)
+	readGroup.GET("/frosh/chain/:roomuuid", handlers.PreviewFroshChain)
+	readGroup.GET("/suites/:suiteuuid/gender-preference", handlers.GetSuiteGenderPreferenceDetails)
+	readGroup.GET("/dorms", handlers.GetDorms)
+	readGroup.GET("/dorms/policies", handlers.GetSuitePullPolicies)
 	readGroup.GET("/users", handlers.GetUsers)
 	readGroup.GET("/users/idmap", handlers.GetUsersIdMap)
 	readGroup.GET("/users/email", handlers.GetUserByEmail)
 	readGroup.GET("/users/:userid", handlers.GetUser)
+	readGroup.GET("/users/me", handlers.GetMyProfile)
+	readGroup.GET("/users/me/eligible-rooms", handlers.GetMyEligibleRooms)
+	readGroup.GET("/users/me/favorites", handlers.GetFavorites)
+	readGroup.GET("/users/me/alerts", handlers.GetFavoriteAlerts)
+	readGroup.GET("/users/me/groups", handlers.GetMyDrawGroups)
+	readGroup.GET("/groups/:groupid", handlers.GetDrawGroup)
 	readGroup.GET("/users/notifications", handlers.GetNotificationPreference)
-	readGroup.GET("/users/clear-room-stats", handlers.GetUserClearRoomStats)
+	readGroup.GET("/users/clear-room-stats", handlers.GetUserClearRoomStats(cfg.Draw))
 
 	// New paginated and sorted endpoints
 	readGroup.GET("/search/rooms", handlers.GetRoomsPagedAndSorted)
 	readGroup.GET("/search/users", handlers.GetUsersPagedAndSorted)
 
+	// Draw term history
+	readGroup.GET("/terms", handlers.GetTerms)
+	readGroup.GET("/terms/:termid/rooms", handlers.GetTermSnapshot(models.EntityTypeRoom))
+	readGroup.GET("/terms/:termid/suites", handlers.GetTermSnapshot(models.EntityTypeSuite))
+	readGroup.GET("/terms/:termid/users", handlers.GetTermSnapshot(models.EntityTypeUser))
+
 	// Define write routes
 	writeGroup.POST("/rooms/:roomuuid", handlers.UpdateRoomOccupants)
+	writeGroup.POST("/rooms/preview/:roomuuid", handlers.PreviewPull)
 	writeGroup.POST("/rooms/indorm/:roomuuid", handlers.ToggleInDorm)
-	writeGroup.POST("/rooms/clear/:roomuuid", handlers.ClearRoomHandler)
-	writeGroup.POST("/suites/design/:suiteuuid", handlers.SetSuiteDesign)
+	writeGroup.POST("/rooms/clear/:roomuuid", handlers.ClearRoomHandler(cfg.Draw))
+	writeGroup.POST("/suites/design/:suiteuuid", handlers.SetSuiteDesign(cfg.BunnyNet))
 	writeGroup.POST("/suites/design/remove/:suiteuuid", handlers.DeleteSuiteDesign)
 	writeGroup.POST("/suites/flags/:suiteuuid", handlers.SetSuiteFlags)
+	writeGroup.POST("/suites/:suiteuuid/gender-preference/proposals", handlers.ProposeSuiteGenderPreference)
+	writeGroup.POST("/suites/:suiteuuid/gender-preference/proposals/:proposalid/approve", handlers.ApproveGenderPreferenceProposal)
+	writeGroup.POST("/suites/:suiteuuid/gender-preference/proposals/:proposalid/reject", handlers.RejectGenderPreferenceProposal)
 	writeGroup.POST("/frosh/bump/:roomuuid", handlers.BumpFroshHandler)
+	writeGroup.POST("/frosh/chain/:roomuuid", handlers.ExecuteFroshChain)
 	writeGroup.POST("/users/notifications", handlers.SetNotificationPreference)
+	writeGroup.PATCH("/users/me", handlers.UpdateMyProfile)
+	writeGroup.POST("/users/me/favorites", handlers.AddFavorite)
+	writeGroup.PATCH("/users/me/favorites/:favoriteid", handlers.UpdateFavorite)
+	writeGroup.DELETE("/users/me/favorites/:favoriteid", handlers.DeleteFavorite)
+	writeGroup.POST("/users/me/alerts/read", handlers.MarkFavoriteAlertsRead)
+	writeGroup.POST("/groups", handlers.CreateDrawGroup)
+	writeGroup.POST("/groups/:groupid/invite", handlers.InviteToDrawGroup)
+	writeGroup.POST("/groups/:groupid/accept", handlers.AcceptDrawGroupInvite)
+	writeGroup.POST("/groups/:groupid/decline", handlers.DeclineDrawGroupInvite)
+	writeGroup.POST("/groups/:groupid/leave", handlers.LeaveDrawGroup)
+	writeGroup.POST("/groups/:groupid/confirm", handlers.ConfirmDrawGroup)
+	writeGroup.POST("/groups/:groupid/pull/:roomuuid", handlers.PullDrawGroup)
 
 	// Define admin write routes
 	writeGroupAdmin.POST("/frosh/:roomuuid", handlers.AddFroshHandler)
 	writeGroupAdmin.POST("/frosh/remove/:roomuuid", handlers.RemoveFroshHandler)
 	writeGroupAdmin.POST("/rooms/preplace/:roomuuid", handlers.PreplaceOccupants)
 	writeGroupAdmin.POST("/rooms/preplace/remove/:roomuuid", handlers.RemovePreplacedOccupantsHandler)
+	writeGroupAdmin.POST("/suites/pull-policy/:suiteuuid", handlers.SetSuitePullPolicy)
+	writeGroupAdmin.POST("/admin/dorms/:dormid/gender-preference-mode", handlers.SetDormGenderPreferenceMode)
+	writeGroupAdmin.POST("/admin/frosh/plan/apply", handlers.ApplyFroshPlan)
+	writeGroupAdmin.POST("/admin/reslife/suites/:suiteuuid", handlers.SetReslifeRoom)
+	writeGroupAdmin.POST("/admin/reslife/suites/:suiteuuid/assign", handlers.AssignReslifeStaff)
+	writeGroupAdmin.POST("/admin/reslife/suites/:suiteuuid/unassign", handlers.UnassignReslifeStaff)
+	writeGroupAdmin.POST("/admin/reslife/users/:userid", handlers.SetReslifeRole)
+	writeGroupAdmin.POST("/admin/profile-windows", handlers.CreateProfileEditWindow)
+	writeGroupAdmin.DELETE("/admin/profile-windows/:windowid", handlers.DeleteProfileEditWindow)
 	writeGroupAdmin.GET("/admin/blocklist", handlers.GetBlocklistedUsers)
 	writeGroupAdmin.POST("/admin/blocklist/remove/:email", handlers.RemoveUserBlocklist)
 	writeGroupAdmin.POST("/admin/suites/update-gender-preferences", handlers.UpdateSuiteGenderPreference)
 
-	log.Println("RequireAuth:", config.RequireAuth)
+	// Define admin read routes
+	readGroupAdmin.GET("/admin/queue/stats", middleware.QueueStatsHandler(requestQueue))
+	readGroupAdmin.GET("/admin/terms/:termid/logs", handlers.GetTermLogs)
+	readGroupAdmin.GET("/admin/bumps", handlers.GetBumpGraph)
+	readGroupAdmin.GET("/users/:userid/eligible-rooms", handlers.GetEligibleRooms)
+	readGroupAdmin.POST("/admin/frosh/plan", handlers.PlanFrosh)
+	readGroupAdmin.GET("/frosh/history", handlers.GetFroshHistory)
+	readGroupAdmin.GET("/admin/profile-windows", handlers.GetProfileEditWindows)
+	readGroupAdmin.GET("/admin/reslife", handlers.GetReslifeAllocations)
+
+	// Define term admin routes
+	termGroupAdmin.POST("/admin/terms", handlers.OpenTerm)
+	termGroupAdmin.POST("/admin/terms/close", handlers.CloseTerm)
+
+	// Prometheus scrape endpoint
+	router.GET("/metrics", metrics.Handler())
+
+	// Liveness and readiness probes
+	router.GET("/healthz", handlers.HealthzHandler)
+	router.GET("/readyz", handlers.ReadyzHandler(cfg.Auth, requestQueue))
+
+	log.Println("RequireAuth:", cfg.Auth.Required)
 
 	// Start the server
-	router.Run(config.ServerAddress)
+	srv := &http.Server{
+		Addr:    cfg.Server.Address,
+		Handler: router,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// Wait for a redeploy or Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	log.Println("Shutting down, draining write queue...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// New writes get a 503 from here on, in-flight ones are allowed to commit
+	if err := requestQueue.Drain(shutdownCtx); err != nil {
+		log.Printf("Write queue did not drain in time: %v", err)
+	}
+
+	// Bump notifications are sent after commit, so wait for them too
+	if err := handlers.WaitForNotifications(shutdownCtx); err != nil {
+		log.Printf("Pending notifications were not all sent: %v", err)
+	}
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown error: %v", err)
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"roomdraw/backend/pkg/config"
+	"roomdraw/backend/pkg/database"
+	"strconv"
+)
+
+const migrateUsage = `usage: server migrate <command> [flags]
+
+commands:
+  up           apply every pending migration
+  down [n]     revert the last n migrations (default 1)
+  status       print the current and latest schema versions
+
+flags are the same as for the server, e.g. -env-file or -config`
+
+// runMigrate handles the migrate subcommand and exits when done
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+	command, args := args[0], args[1:]
+
+	steps := 1
+	if command == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			if n < 1 {
+				log.Fatalf("down needs a positive number of steps, got %d", n)
+			}
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := database.InitDB(cfg.Database); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.DB.Close()
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(ctx)
+		for _, m := range applied {
+			log.Printf("Applied %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migrate up failed: %v", err)
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is already up to date")
+		}
+
+	case "down":
+		reverted, err := database.MigrateDown(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Reverted %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migrate down failed: %v", err)
+		}
+
+	case "status":
+		current, err := database.SchemaVersion(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read schema version: %v", err)
+		}
+		latest, err := database.LatestSchemaVersion()
+		if err != nil {
+			log.Fatalf("Failed to load migrations: %v", err)
+		}
+		fmt.Printf("current version: %d\nlatest version:  %d\n", current, latest)
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
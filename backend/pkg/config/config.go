@@ -1,76 +1,370 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	// Database configuration
-	SQLPass   string
-	SQLIP     string
-	SQLDBName string
-	SQLUser   string
-	SQLPort   string
-	UseSSL    string
+// Config holds every setting the server needs. It is built once by Load and
+// handed to the pieces that need it.
+type Config struct {
+	Server     ServerConfig     `yaml:"server"`
+	Database   DatabaseConfig   `yaml:"database"`
+	Auth       AuthConfig       `yaml:"auth"`
+	BunnyNet   BunnyNetConfig   `yaml:"bunnynet"`
+	Email      EmailConfig      `yaml:"email"`
+	Draw       DrawConfig       `yaml:"draw"`
+	WriteQueue WriteQueueConfig `yaml:"writeQueue"`
+	Log        LogConfig        `yaml:"log"`
+}
 
-	// Authentication
-	RequireAuth bool
+type ServerConfig struct {
+	Address     string   `yaml:"address"`
+	CORSOrigins []string `yaml:"corsOrigins"`
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Name     string `yaml:"name"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	SSLMode  string `yaml:"sslMode"`
+}
 
-	// BunnyNet configuration
-	BunnyNetReadAPIKey  string
-	BunnyNetWriteAPIKey string
-	BunnyNetStorageZone string
+type AuthConfig struct {
+	Required bool     `yaml:"required"`
+	Admins   []string `yaml:"admins"` // usernames before @g.hmc.edu
+}
 
-	// CDN configuration
-	CDNURL string
+type BunnyNetConfig struct {
+	ReadAPIKey  string `yaml:"readApiKey"`
+	WriteAPIKey string `yaml:"writeApiKey"`
+	StorageZone string `yaml:"storageZone"`
+	CDNURL      string `yaml:"cdnUrl"`
+}
 
-	// Email configuration
-	EmailUsername string
-	EmailPassword string
-)
+type EmailConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	SMTPHost string `yaml:"smtpHost"`
+	SMTPPort string `yaml:"smtpPort"`
+}
 
-// Server configuration
-const (
-	ServerAddress = ":8000"
-)
+type DrawConfig struct {
+	MaxDailyClears int    `yaml:"maxDailyClears"`
+	Timezone       string `yaml:"timezone"` // clear room limits reset at midnight here
+}
+
+type WriteQueueConfig struct {
+	MaxDepth int `yaml:"maxDepth"` // 0 turns admission control off
+}
 
-// LoadConfig loads all environment variables once
-func LoadConfig() error {
-	envFile := ".env"
-	err := godotenv.Load(envFile)
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+}
+
+// Location returns the draw timezone, falling back to UTC if it cannot be
+// loaded. Load has already checked that it can.
+func (d DrawConfig) Location() *time.Location {
+	loc, err := time.LoadLocation(d.Timezone)
 	if err != nil {
-		return fmt.Errorf("error loading %s file: %v", envFile, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// IsAdmin reports whether the user (the part of the email before the @) is an admin
+func (a AuthConfig) IsAdmin(user string) bool {
+	for _, admin := range a.Admins {
+		if admin == user {
+			return true
+		}
+	}
+	return false
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Address:     ":8000",
+			CORSOrigins: []string{"http://localhost:3001", "http://localhost:8081", "https://www.cs.hmc.edu"},
+		},
+		Database: DatabaseConfig{
+			Port:    "5432",
+			SSLMode: "require",
+		},
+		Auth: AuthConfig{
+			Admins: []string{"smao", "tlam", "aniksharma", "elli"},
+		},
+		Email: EmailConfig{
+			SMTPHost: "smtp.cs.hmc.edu",
+			SMTPPort: "587",
+		},
+		Draw: DrawConfig{
+			MaxDailyClears: 10,
+			Timezone:       "America/Los_Angeles",
+		},
+		WriteQueue: WriteQueueConfig{
+			MaxDepth: 100,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+	}
+}
+
+// Load builds the configuration. Later sources override earlier ones:
+// defaults, then the YAML file given by -config or CONFIG_FILE, then the
+// environment (including the optional .env file), then command line flags.
+// Every missing or invalid setting is reported in the returned error.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML config file")
+	envFile := fs.String("env-file", ".env", "path to an env file, ignored if it does not exist")
+	address := fs.String("addr", "", "address to listen on, e.g. :8000")
+	requireAuth := fs.String("require-auth", "", "require Google sign in (true or false)")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error")
+	logFormat := fs.String("log-format", "", "text or json")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Load the env file first so it can name the config file. Variables that
+	// are already set in the environment win over the file.
+	if err := godotenv.Load(*envFile); err == nil {
+		log.Printf("Loaded environment from %s", *envFile)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error loading %s file: %v", *envFile, err)
+	}
+
+	cfg := Default()
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		data, err := os.ReadFile(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %v", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %v", *configFile, err)
+		}
+		log.Printf("Loaded configuration from %s", *configFile)
 	}
 
+	var errs []error
+	env := envReader{errs: &errs}
+
+	// Server configuration
+	env.str("SERVER_ADDRESS", &cfg.Server.Address)
+	env.list("CORS_ORIGINS", &cfg.Server.CORSOrigins)
+
 	// Database configuration
-	SQLPass = os.Getenv("SQL_PASS")
-	SQLIP = os.Getenv("SQL_IP")
-	SQLDBName = os.Getenv("SQL_DB_NAME")
-	SQLUser = os.Getenv("SQL_USER")
-	SQLPort = os.Getenv("SQL_PORT")
-	UseSSL = os.Getenv("USE_SSL")
+	env.str("SQL_IP", &cfg.Database.Host)
+	env.str("SQL_PORT", &cfg.Database.Port)
+	env.str("SQL_DB_NAME", &cfg.Database.Name)
+	env.str("SQL_USER", &cfg.Database.User)
+	env.str("SQL_PASS", &cfg.Database.Password)
+	env.str("USE_SSL", &cfg.Database.SSLMode)
 
 	// Authentication
-	RequireAuth = (os.Getenv("REQUIRE_AUTH") == "True")
-
-	// BunnyNet configuration
-	BunnyNetReadAPIKey = os.Getenv("BUNNYNET_READ_API_KEY")
-	BunnyNetWriteAPIKey = os.Getenv("BUNNYNET_WRITE_API_KEY")
-	BunnyNetStorageZone = os.Getenv("BUNNYNET_STORAGE_ZONE")
+	env.boolean("REQUIRE_AUTH", &cfg.Auth.Required)
+	env.list("ADMIN_USERS", &cfg.Auth.Admins)
 
-	// CDN configuration
-	CDNURL = os.Getenv("CDN_URL")
+	// BunnyNet and CDN configuration
+	env.str("BUNNYNET_READ_API_KEY", &cfg.BunnyNet.ReadAPIKey)
+	env.str("BUNNYNET_WRITE_API_KEY", &cfg.BunnyNet.WriteAPIKey)
+	env.str("BUNNYNET_STORAGE_ZONE", &cfg.BunnyNet.StorageZone)
+	env.str("CDN_URL", &cfg.BunnyNet.CDNURL)
 
 	// Email configuration
-	EmailUsername = os.Getenv("EMAIL_USERNAME")
-	EmailPassword = os.Getenv("EMAIL_PASSWORD")
+	env.str("EMAIL_USERNAME", &cfg.Email.Username)
+	env.str("EMAIL_PASSWORD", &cfg.Email.Password)
+	env.str("SMTP_HOST", &cfg.Email.SMTPHost)
+	env.str("SMTP_PORT", &cfg.Email.SMTPPort)
+
+	// Draw rules
+	env.integer("MAX_DAILY_CLEARS", &cfg.Draw.MaxDailyClears)
+	env.str("DRAW_TIMEZONE", &cfg.Draw.Timezone)
+
+	// Write queue configuration
+	env.integer("WRITE_QUEUE_MAX_DEPTH", &cfg.WriteQueue.MaxDepth)
+
+	// Logging configuration
+	env.str("LOG_LEVEL", &cfg.Log.Level)
+	env.str("LOG_FORMAT", &cfg.Log.Format)
+
+	// Flags win over everything else
+	if *address != "" {
+		cfg.Server.Address = *address
+	}
+	if *requireAuth != "" {
+		required, err := parseBool(*requireAuth)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("-require-auth: %v", err))
+		}
+		cfg.Auth.Required = required
+	}
+	if *logLevel != "" {
+		cfg.Log.Level = *logLevel
+	}
+	if *logFormat != "" {
+		cfg.Log.Format = *logFormat
+	}
+
+	errs = append(errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return cfg, nil
+}
+
+// Validate returns one error per missing or invalid setting
+func (c *Config) Validate() []error {
+	var errs []error
+	missing := func(key string) {
+		errs = append(errs, fmt.Errorf("%s is required", key))
+	}
+
+	if c.Server.Address == "" {
+		missing("SERVER_ADDRESS")
+	}
+
+	if c.Database.Host == "" {
+		missing("SQL_IP")
+	}
+	if c.Database.Name == "" {
+		missing("SQL_DB_NAME")
+	}
+	if c.Database.User == "" {
+		missing("SQL_USER")
+	}
+	if port, err := strconv.Atoi(c.Database.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Errorf("SQL_PORT must be a port number, got %q", c.Database.Port))
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("USE_SSL must be a postgres sslmode such as disable or require, got %q", c.Database.SSLMode))
+	}
+
+	if c.Auth.Required && len(c.Auth.Admins) == 0 {
+		errs = append(errs, errors.New("ADMIN_USERS must list at least one admin when REQUIRE_AUTH is on"))
+	}
+
+	if (c.BunnyNet.WriteAPIKey == "") != (c.BunnyNet.StorageZone == "") {
+		errs = append(errs, errors.New("BUNNYNET_WRITE_API_KEY and BUNNYNET_STORAGE_ZONE must be set together"))
+	}
+	if c.BunnyNet.WriteAPIKey != "" && c.BunnyNet.CDNURL == "" {
+		missing("CDN_URL")
+	}
+
+	if (c.Email.Username == "") != (c.Email.Password == "") {
+		errs = append(errs, errors.New("EMAIL_USERNAME and EMAIL_PASSWORD must be set together"))
+	}
+	if c.Email.SMTPHost == "" {
+		missing("SMTP_HOST")
+	}
+	if _, err := strconv.Atoi(c.Email.SMTPPort); err != nil {
+		errs = append(errs, fmt.Errorf("SMTP_PORT must be a port number, got %q", c.Email.SMTPPort))
+	}
+
+	if c.Draw.MaxDailyClears <= 0 {
+		errs = append(errs, fmt.Errorf("MAX_DAILY_CLEARS must be positive, got %d", c.Draw.MaxDailyClears))
+	}
+	if _, err := time.LoadLocation(c.Draw.Timezone); err != nil || c.Draw.Timezone == "" {
+		errs = append(errs, fmt.Errorf("DRAW_TIMEZONE must be an IANA timezone, got %q", c.Draw.Timezone))
+	}
+
+	if c.WriteQueue.MaxDepth < 0 {
+		errs = append(errs, fmt.Errorf("WRITE_QUEUE_MAX_DEPTH must not be negative, got %d", c.WriteQueue.MaxDepth))
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", c.Log.Level))
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT must be text or json, got %q", c.Log.Format))
+	}
+
+	return errs
+}
+
+// envReader copies set environment variables over the current values and
+// collects parse errors instead of stopping at the first one
+type envReader struct {
+	errs *[]error
+}
+
+func (e envReader) str(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+func (e envReader) list(key string, dst *[]string) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e envReader) integer(key string, dst *int) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		*e.errs = append(*e.errs, fmt.Errorf("%s must be a whole number, got %q", key, v))
+		return
+	}
+	*dst = n
+}
 
-	// Log the environment being used
-	log.Printf("Loaded configuration from %s", envFile)
+func (e envReader) boolean(key string, dst *bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	b, err := parseBool(v)
+	if err != nil {
+		*e.errs = append(*e.errs, fmt.Errorf("%s: %v", key, err))
+		return
+	}
+	*dst = b
+}
 
-	return nil
+func parseBool(v string) (bool, error) {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("must be True or False, got %q", v)
+	}
+	return b, nil
 }
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"strings"
 	"time"
@@ -14,17 +15,17 @@ import (
 
 var DB *sql.DB
 
-func InitDB() error {
+func InitDB(db config.DatabaseConfig) error {
 	// replace every space with %20
-	encodedPass := url.QueryEscape(config.SQLPass)
+	encodedPass := url.QueryEscape(db.Password)
 	// replace + with %20
 	encodedPass = strings.Replace(encodedPass, "+", "%20", -1)
 
 	// Construct the connection string
 	connStr := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=%s",
-		config.SQLUser, encodedPass, config.SQLIP, config.SQLPort, config.SQLDBName, config.UseSSL)
+		db.User, encodedPass, db.Host, db.Port, db.Name, db.SSLMode)
 
-	fmt.Println("Connection string:", connStr)
+	slog.Info("Connecting to database", "host", db.Host, "port", db.Port, "db", db.Name, "user", db.User, "sslmode", db.SSLMode)
 
 	// Open the database connection
 	var err error
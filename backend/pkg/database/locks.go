@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+)
+
+// writeLockNamespace is the first half of every advisory lock a write takes, so
+// they can never collide with migrationLockID or locks taken by other software
+// sharing the database
+const writeLockNamespace = 7390413
+
+// drawLockKey is held shared by every write and exclusively by draw-wide ones
+const drawLockKey = "draw"
+
+// WriteLocks names what a write request touches: the suites (or other keys) it
+// locks, or the whole draw
+type WriteLocks struct {
+	DrawWide bool
+	Keys     []string
+}
+
+type writeLocksContextKey struct{}
+
+// WithWriteLocks records the locks a request needs so that the transaction it
+// begins through BeginTx takes them
+func WithWriteLocks(ctx context.Context, locks WriteLocks) context.Context {
+	return context.WithValue(ctx, writeLocksContextKey{}, locks)
+}
+
+// WriteLocksFromContext returns the locks recorded by WithWriteLocks
+func WriteLocksFromContext(ctx context.Context) (WriteLocks, bool) {
+	locks, ok := ctx.Value(writeLocksContextKey{}).(WriteLocks)
+	return locks, ok
+}
+
+// BeginTx begins a write transaction holding the postgres advisory locks
+// recorded in ctx. The locks are released when the transaction ends, and since
+// they live in the database they also serialize writes from other server
+// instances and from roomdrawctl.
+func BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if locks, ok := WriteLocksFromContext(ctx); ok {
+		if err := LockForWrite(ctx, tx, locks); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return tx, nil
+}
+
+// LockForWrite takes the advisory locks for locks inside tx. The draw lock
+// always comes first and the rest in sorted order, so two writes can never
+// wait on each other in a cycle.
+func LockForWrite(ctx context.Context, tx *sql.Tx, locks WriteLocks) error {
+	if locks.DrawWide {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", writeLockNamespace, drawLockKey)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock_shared($1, hashtext($2))", writeLockNamespace, drawLockKey); err != nil {
+		return err
+	}
+
+	return LockKeys(ctx, tx, locks.Keys...)
+}
+
+// LockKeys takes exclusive advisory locks on keys inside tx. A handler that
+// only learns which suite it touches once it is running (e.g. from the
+// caller's room) locks it with this after BeginTx.
+func LockKeys(ctx context.Context, tx *sql.Tx, keys ...string) error {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", writeLockNamespace, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TryLockKeys is LockKeys without waiting: it reports false as soon as one of
+// keys is held by another transaction. Locks it did take stay held until tx ends.
+func TryLockKeys(ctx context.Context, tx *sql.Tx, keys ...string) (bool, error) {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		var locked bool
+		if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1, hashtext($2))", writeLockNamespace, key).Scan(&locked); err != nil {
+			return false, err
+		}
+		if !locked {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
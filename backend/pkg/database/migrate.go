@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the postgres advisory lock held while migrating so two
+// deploys can never migrate the same database at once
+const migrationLockID = 7390412
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// LoadMigrations returns the embedded migrations in version order. Versions
+// must start at 1 with no gaps and every migration needs an up and a down.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+
+		contents, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, found %d at position %d", m.Version, i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// LatestSchemaVersion is the version this build expects the database to be at
+func LatestSchemaVersion() (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	return len(migrations), nil
+}
+
+// SchemaVersion returns the version recorded in schema_migrations, or 0 if
+// the database has never been migrated
+func SchemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	err := DB.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version int
+	err = DB.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// CheckSchemaVersion refuses to run against a database whose schema does not
+// match this build
+func CheckSchemaVersion(ctx context.Context) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading schema version: %v", err)
+	}
+
+	switch {
+	case current < latest:
+		return fmt.Errorf("database schema is at version %d but this build needs %d, run the migrate up command first", current, latest)
+	case current > latest:
+		return fmt.Errorf("database schema is at version %d which is newer than this build (%d), deploy a newer build or migrate down", current, latest)
+	}
+	return nil
+}
+
+// MigrateUp applies every pending migration, each in its own transaction, and
+// returns the ones it applied
+func MigrateUp(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		current, err := SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if current > len(migrations) {
+			return fmt.Errorf("database schema is at version %d which is newer than this build (%d), deploy a newer build", current, len(migrations))
+		}
+
+		for _, m := range migrations[current:] {
+			log.Printf("Applying migration %d_%s", m.Version, m.Name)
+			err := runMigration(ctx, conn, m.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %v", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the last steps migrations and returns the ones it reverted
+func MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		current, err := SchemaVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if current > len(migrations) {
+			return fmt.Errorf("database schema is at version %d, this build only knows up to %d", current, len(migrations))
+		}
+
+		for i := 0; i < steps && current > 0; i++ {
+			m := migrations[current-1]
+			log.Printf("Reverting migration %d_%s", m.Version, m.Name)
+			err := runMigration(ctx, conn, m.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", m.Version)
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %v", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+			current--
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock,
+// creating the schema_migrations table first if needed
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("error taking migration lock: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version int PRIMARY KEY,
+            name varchar NOT NULL,
+            applied_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations table: %v", err)
+	}
+
+	return fn(conn)
+}
+
+// runMigration runs one migration script and records it in the same transaction
+func runMigration(ctx context.Context, conn *sql.Conn, script string, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS rooms;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS suitegroups;
+DROP TABLE IF EXISTS suites;
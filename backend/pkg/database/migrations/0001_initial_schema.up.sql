@@ -0,0 +1,93 @@
+-- Core draw tables. Later columns are added by the migrations that follow.
+-- Every statement is idempotent so databases created before migrations
+-- existed are adopted without changes.
+CREATE TABLE IF NOT EXISTS suites (
+    suite_uuid uuid NOT NULL,
+    dorm int NOT NULL,
+    dorm_name varchar NOT NULL,
+    floor int NOT NULL,
+    room_count int NOT NULL,
+    rooms uuid array,
+    alternative_pull bool NOT NULL,
+    suite_design varchar NOT NULL DEFAULT '',
+    can_lock_pull bool NOT NULL DEFAULT false,
+    lock_pulled_room uuid,
+    reslife_room uuid,
+    PRIMARY KEY (suite_uuid)
+);
+
+-- If group leader leaves a room voluntarily, new groupleader will be derived from the array. However, if the group leader leaving leaves the group to become 1 person, the group is disbanded.
+-- If group leader is bumped, group is disbanded and everyone is bumped.
+CREATE TABLE IF NOT EXISTS suitegroups (
+    sgroup_uuid uuid NOT NULL,
+    sgroup_size int NOT NULL,
+    sgroup_name varchar NOT NULL,
+    sgroup_suite uuid NOT NULL,
+    pull_priority jsonb NOT NULL DEFAULT '{
+        "valid": false,
+        "isPreplaced": false,
+        "hasInDorm": false,
+        "drawNumber": 0,
+        "year": 0,
+        "pullType": 0,
+        "inherited": {
+            "valid": false,
+            "hasInDorm": false,
+            "drawNumber": 0,
+            "year": 0
+        }
+    }'::jsonb,
+    disbanded boolean NOT NULL DEFAULT false,
+    rooms uuid array,
+    PRIMARY KEY (sgroup_uuid),
+    FOREIGN KEY (sgroup_suite) REFERENCES suites(suite_uuid)
+);
+
+-- in_dorm holds the dorm number the senior has in dorm for (0: None, 1: East etc.)
+CREATE TABLE IF NOT EXISTS users (
+    id serial,
+    year varchar NOT NULL,
+    first_name varchar NOT NULL,
+    last_name varchar NOT NULL,
+    email varchar,
+    draw_number decimal NOT NULL,
+    preplaced boolean NOT NULL,
+    in_dorm int NOT NULL,
+    sgroup_uuid uuid,
+    participated boolean NOT NULL DEFAULT false,
+    participation_time timestamp,
+    room_uuid uuid,
+    reslife_role varchar NOT NULL DEFAULT 'none',
+    PRIMARY KEY (id),
+    FOREIGN KEY (sgroup_uuid) REFERENCES suitegroups(sgroup_uuid)
+);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    room_uuid uuid NOT NULL,
+    dorm int NOT NULL,
+    dorm_name varchar NOT NULL,
+    room_id varchar NOT NULL,
+    suite_uuid uuid NOT NULL,
+    max_occupancy int NOT NULL,
+    current_occupancy int NOT NULL,
+    occupants int array,
+    pull_priority jsonb NOT NULL DEFAULT '{
+        "valid": false,
+        "isPreplaced": false,
+        "hasInDorm": false,
+        "drawNumber": 0,
+        "year": 0,
+        "pullType": 0,
+        "inherited": {
+            "valid": false,
+            "hasInDorm": false,
+            "drawNumber": 0,
+            "year": 0
+        }
+    }'::jsonb,
+    sgroup_uuid uuid,
+    has_frosh bool NOT NULL DEFAULT false,
+    frosh_room_type INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (room_uuid),
+    FOREIGN KEY (suite_uuid) REFERENCES suites(suite_uuid)
+);
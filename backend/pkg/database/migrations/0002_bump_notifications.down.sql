@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS notification_updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS notifications_enabled;
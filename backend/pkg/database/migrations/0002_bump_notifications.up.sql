@@ -0,0 +1,4 @@
+-- Opt-in email notifications when a user is bumped
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notifications_enabled boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_created_at timestamp WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_updated_at timestamp WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
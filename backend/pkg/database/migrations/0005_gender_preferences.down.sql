@@ -0,0 +1,3 @@
+ALTER TABLE suites DROP COLUMN IF EXISTS can_be_gender_preferenced;
+ALTER TABLE suites DROP COLUMN IF EXISTS gender_preferences;
+ALTER TABLE users DROP COLUMN IF EXISTS gender_preferences;
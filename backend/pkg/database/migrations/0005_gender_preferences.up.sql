@@ -0,0 +1,4 @@
+-- Gender preferences for users and the suites they pull into
+ALTER TABLE users ADD COLUMN IF NOT EXISTS gender_preferences varchar[] NOT NULL DEFAULT '{}';
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS gender_preferences varchar[] NOT NULL DEFAULT '{}';
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS can_be_gender_preferenced bool NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+ALTER TABLE suites DROP COLUMN IF EXISTS suite_notes;
+ALTER TABLE suites DROP COLUMN IF EXISTS legacy_suite;
+ALTER TABLE suites DROP COLUMN IF EXISTS animal_in_suite;
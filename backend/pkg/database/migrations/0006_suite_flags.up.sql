@@ -0,0 +1,4 @@
+-- Flags and notes shown on suites, set through /suites/flags
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS animal_in_suite bool NOT NULL DEFAULT false;
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS legacy_suite bool NOT NULL DEFAULT false;
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS suite_notes varchar NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transaction_logs_term_id;
+ALTER TABLE transaction_logs DROP COLUMN IF EXISTS term_id;
+DROP TABLE IF EXISTS term_snapshots;
+DROP TABLE IF EXISTS draw_terms;
@@ -0,0 +1,35 @@
+-- A draw term is one year's draw. The live tables always hold the newest
+-- term; closing a term copies its rows into term_snapshots so it stays
+-- queryable after the next term starts from an empty draw.
+CREATE TABLE IF NOT EXISTS draw_terms (
+    term_id serial PRIMARY KEY,
+    name varchar NOT NULL UNIQUE,
+    status varchar NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    opened_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at timestamp WITH TIME ZONE,
+    opened_by varchar NOT NULL DEFAULT '',
+    closed_by varchar NOT NULL DEFAULT ''
+);
+
+-- At most one term can be open at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_draw_terms_one_open ON draw_terms(status) WHERE status = 'open';
+
+-- Rows of users, rooms, suites, suitegroups and user_rate_limits as they were
+-- when their term closed
+CREATE TABLE IF NOT EXISTS term_snapshots (
+    term_id int NOT NULL REFERENCES draw_terms(term_id),
+    entity_type varchar NOT NULL, -- e.g., "ROOM", "USER", "SUITE", "SUITEGROUP", "RATE_LIMIT"
+    entity_id varchar NOT NULL,
+    data jsonb NOT NULL,
+    PRIMARY KEY (term_id, entity_type, entity_id)
+);
+
+-- The draw already in the live tables becomes the first term
+INSERT INTO draw_terms (name, status)
+SELECT to_char(CURRENT_DATE, 'YYYY'), 'open'
+WHERE NOT EXISTS (SELECT 1 FROM draw_terms);
+
+-- Logs are never cleared, so they are tagged with their term instead
+ALTER TABLE transaction_logs ADD COLUMN IF NOT EXISTS term_id int REFERENCES draw_terms(term_id);
+UPDATE transaction_logs SET term_id = (SELECT MIN(term_id) FROM draw_terms) WHERE term_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_transaction_logs_term_id ON transaction_logs(term_id);
@@ -0,0 +1,35 @@
+-- Rooms and suites users saved, with their own notes and ranking. last_state
+-- and reachable hold what the user was last alerted about, so an alert only
+-- goes out when a favorite actually changes.
+CREATE TABLE IF NOT EXISTS user_favorites (
+    favorite_id serial PRIMARY KEY,
+    user_id int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    room_uuid uuid REFERENCES rooms(room_uuid) ON DELETE CASCADE,
+    suite_uuid uuid REFERENCES suites(suite_uuid) ON DELETE CASCADE,
+    rank int NOT NULL DEFAULT 0,
+    notes text NOT NULL DEFAULT '',
+    last_state jsonb NOT NULL DEFAULT '{}',
+    reachable boolean NOT NULL DEFAULT false,
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((room_uuid IS NULL) <> (suite_uuid IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_favorites_room ON user_favorites(user_id, room_uuid) WHERE room_uuid IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_favorites_suite ON user_favorites(user_id, suite_uuid) WHERE suite_uuid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_user_favorites_room_uuid ON user_favorites(room_uuid);
+CREATE INDEX IF NOT EXISTS idx_user_favorites_suite_uuid ON user_favorites(suite_uuid);
+
+-- Alerts raised when a favorite changes occupancy or priority, or becomes
+-- reachable for its user
+CREATE TABLE IF NOT EXISTS user_alerts (
+    alert_id serial PRIMARY KEY,
+    user_id int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    favorite_id int REFERENCES user_favorites(favorite_id) ON DELETE SET NULL,
+    kind varchar NOT NULL CHECK (kind IN ('occupancy', 'priority', 'reachable')),
+    message text NOT NULL,
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at timestamp WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_alerts_user_id ON user_alerts(user_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS draw_group_members;
+DROP TABLE IF EXISTS draw_groups;
@@ -0,0 +1,23 @@
+-- Groups students form before the draw so they can pull together without
+-- knowing each other's user ids
+CREATE TABLE IF NOT EXISTS draw_groups (
+    group_id serial PRIMARY KEY,
+    name varchar NOT NULL DEFAULT '',
+    leader_id int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status varchar NOT NULL DEFAULT 'forming' CHECK (status IN ('forming', 'confirmed', 'pulled')),
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    confirmed_at timestamp WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS draw_group_members (
+    group_id int NOT NULL REFERENCES draw_groups(group_id) ON DELETE CASCADE,
+    user_id int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status varchar NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'accepted', 'declined')),
+    invited_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    responded_at timestamp WITH TIME ZONE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- A student can only be in one group at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_draw_group_members_one_group ON draw_group_members(user_id) WHERE status = 'accepted';
+CREATE INDEX IF NOT EXISTS idx_draw_group_members_user_id ON draw_group_members(user_id);
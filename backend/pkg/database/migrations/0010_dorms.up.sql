@@ -0,0 +1,36 @@
+-- The dorms the draw knows about. rooms.dorm and suites.dorm hold a dorm_id,
+-- and the handlers look up each dorm's rules here instead of comparing ids.
+--   frosh_policy: whole_suite (frosh fill a whole suite), one_per_suite or room
+--   suite_pull_policy: what a pull leader in a suite group can add to it.
+--     none, three_room_suite (South) or single_and_triple (Drinkward)
+--   capabilities: in_dorm (seniors can hold in dorm here) and frosh (frosh
+--     rooms can be placed here)
+--   normal_pull_triples: triples a single with in dorm can normal pull three
+--     people into
+CREATE TABLE IF NOT EXISTS dorms (
+    dorm_id int PRIMARY KEY,
+    name varchar NOT NULL UNIQUE,
+    description varchar NOT NULL DEFAULT '',
+    frosh_policy varchar NOT NULL DEFAULT 'room' CHECK (frosh_policy IN ('whole_suite', 'one_per_suite', 'room')),
+    suite_pull_policy varchar NOT NULL DEFAULT 'none' CHECK (suite_pull_policy IN ('none', 'three_room_suite', 'single_and_triple')),
+    capabilities text[] NOT NULL DEFAULT '{}',
+    normal_pull_triples text[] NOT NULL DEFAULT '{}'
+);
+
+INSERT INTO dorms (dorm_id, name, description, frosh_policy, suite_pull_policy, capabilities, normal_pull_triples) VALUES
+    (1, 'East', 'One of the four inner dorms. Frosh are placed a whole suite at a time.', 'whole_suite', 'none', '{in_dorm,frosh}', '{}'),
+    (2, 'North', 'One of the four inner dorms. Frosh are placed a whole suite at a time.', 'whole_suite', 'none', '{in_dorm,frosh}', '{}'),
+    (3, 'South', 'One of the four inner dorms. Frosh are placed a whole suite at a time, and a pull leader can pull two suitemates into a three room suite.', 'whole_suite', 'three_room_suite', '{in_dorm,frosh}', '{}'),
+    (4, 'West', 'One of the four inner dorms. Frosh are placed a whole suite at a time.', 'whole_suite', 'none', '{in_dorm,frosh}', '{}'),
+    (5, 'Atwood', 'Frosh are placed room by room.', 'room', 'none', '{in_dorm,frosh}', '{}'),
+    (6, 'Sontag', 'Frosh are placed room by room.', 'room', 'none', '{in_dorm,frosh}', '{}'),
+    (7, 'Case', 'Frosh are placed room by room.', 'room', 'none', '{in_dorm,frosh}', '{}'),
+    (8, 'Drinkward', 'Frosh are placed room by room. A pull leader with in dorm can pull a single and a triple on the suite side together.', 'room', 'single_and_triple', '{in_dorm,frosh}',
+        '{123C,124C,221C,222C,223C,224C,321C,322C,323C,324C}'),
+    (9, 'Linde', 'Frosh are placed room by room, at most one frosh room per suite.', 'one_per_suite', 'none', '{in_dorm,frosh}', '{}')
+ON CONFLICT (dorm_id) DO NOTHING;
+
+-- Any other dorm already in the data keeps its name and gets the defaults
+INSERT INTO dorms (dorm_id, name)
+SELECT DISTINCT ON (dorm) dorm, dorm_name FROM suites ORDER BY dorm, dorm_name
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,11 @@
+ALTER TABLE suites DROP COLUMN IF EXISTS suite_pull_policy;
+
+ALTER TABLE dorms ADD COLUMN IF NOT EXISTS normal_pull_triples text[] NOT NULL DEFAULT '{}';
+UPDATE dorms d SET normal_pull_triples = p.normal_pull_triples
+FROM suite_pull_policies p WHERE d.suite_pull_policy = p.name;
+
+ALTER TABLE dorms DROP CONSTRAINT IF EXISTS dorms_suite_pull_policy_fkey;
+UPDATE dorms SET suite_pull_policy = 'none' WHERE suite_pull_policy NOT IN ('none', 'three_room_suite', 'single_and_triple');
+ALTER TABLE dorms ADD CONSTRAINT dorms_suite_pull_policy_check CHECK (suite_pull_policy IN ('none', 'three_room_suite', 'single_and_triple'));
+
+DROP TABLE IF EXISTS suite_pull_policies;
@@ -0,0 +1,29 @@
+-- Named rules for normal pulls and suite groups, see models.SuitePullPolicy.
+-- A dorm names the policy its suites follow and a suite can override it, so a
+-- new dorm or a renovated suite is configured here instead of in the handlers.
+CREATE TABLE IF NOT EXISTS suite_pull_policies (
+    name varchar PRIMARY KEY,
+    description varchar NOT NULL DEFAULT '',
+    max_rooms int NOT NULL DEFAULT 2 CHECK (max_rooms >= 2),
+    suite_room_counts int[] NOT NULL DEFAULT '{}',
+    room_combinations jsonb NOT NULL DEFAULT '[]', -- e.g. [[1, 1, 3]]
+    in_dorm_leader_past int NOT NULL DEFAULT 0,
+    in_dorm_singles bool NOT NULL DEFAULT false,
+    normal_pull_triples text[] NOT NULL DEFAULT '{}'
+);
+
+INSERT INTO suite_pull_policies (name, description, max_rooms, suite_room_counts, room_combinations, in_dorm_leader_past, in_dorm_singles, normal_pull_triples) VALUES
+    ('none', 'A pull leader can pull one other room of their suite.', 2, '{}', '[]', 0, false, '{}'),
+    ('three_room_suite', 'A pull leader can pull two other rooms, in a suite with three rooms.', 3, '{3}', '[]', 0, false, '{}'),
+    ('single_and_triple', 'A pull leader with in dorm can pull a single and a triple. The single needs in dorm, the triple does not.', 3, '{}', '[[1, 1, 3]]', 2, true, '{}')
+ON CONFLICT (name) DO NOTHING;
+
+-- The Drinkward suite triples move from the dorm to its policy
+UPDATE suite_pull_policies p SET normal_pull_triples = d.normal_pull_triples
+FROM dorms d WHERE d.suite_pull_policy = p.name AND cardinality(d.normal_pull_triples) > 0;
+ALTER TABLE dorms DROP COLUMN IF EXISTS normal_pull_triples;
+
+ALTER TABLE dorms DROP CONSTRAINT IF EXISTS dorms_suite_pull_policy_check;
+ALTER TABLE dorms ADD CONSTRAINT dorms_suite_pull_policy_fkey FOREIGN KEY (suite_pull_policy) REFERENCES suite_pull_policies(name);
+
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS suite_pull_policy varchar REFERENCES suite_pull_policies(name);
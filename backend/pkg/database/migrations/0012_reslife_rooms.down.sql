@@ -0,0 +1 @@
+ALTER TABLE suites DROP COLUMN IF EXISTS reslife_room_role;
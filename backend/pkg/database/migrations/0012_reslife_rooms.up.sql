@@ -0,0 +1,12 @@
+-- A suite's ResLife room is designated for a mentor or a proctor, and pulls
+-- treat it as locked whether or not anyone lives there yet.
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS reslife_room_role varchar
+    CHECK (reslife_room_role IN ('mentor', 'proctor'));
+
+-- Rooms marked by preplacing a ResLife student take that student's role
+UPDATE suites s SET reslife_room_role = COALESCE(
+    (SELECT u.reslife_role FROM users u
+     WHERE u.room_uuid = s.reslife_room AND u.reslife_role IN ('mentor', 'proctor')
+     ORDER BY u.reslife_role LIMIT 1),
+    'mentor')
+WHERE s.reslife_room IS NOT NULL AND s.reslife_room_role IS NULL;
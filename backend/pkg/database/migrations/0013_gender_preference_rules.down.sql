@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS suite_gender_preference_approvals;
+DROP TABLE IF EXISTS suite_gender_preference_proposals;
+ALTER TABLE suites DROP COLUMN IF EXISTS gender_preference_decided_by;
+ALTER TABLE suites DROP COLUMN IF EXISTS gender_preference_rule;
@@ -0,0 +1,28 @@
+-- The rule that decided a suite's gender preference and the occupants whose
+-- preferences it used. Filled in the next time the preference is worked out.
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS gender_preference_rule varchar NOT NULL DEFAULT '';
+ALTER TABLE suites ADD COLUMN IF NOT EXISTS gender_preference_decided_by int[] NOT NULL DEFAULT '{}';
+
+-- Gender preferences occupants ask for. A proposal takes over from the rules
+-- once every occupant has approved it, until someone who did not approve it
+-- moves in.
+CREATE TABLE IF NOT EXISTS suite_gender_preference_proposals (
+    proposal_id serial PRIMARY KEY,
+    suite_uuid uuid NOT NULL REFERENCES suites(suite_uuid) ON DELETE CASCADE,
+    proposed_by int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    gender_preferences varchar[] NOT NULL DEFAULT '{}',
+    status varchar NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'approved', 'rejected', 'superseded')),
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at timestamp WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS suite_gender_preference_approvals (
+    proposal_id int NOT NULL REFERENCES suite_gender_preference_proposals(proposal_id) ON DELETE CASCADE,
+    user_id int NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    approved_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (proposal_id, user_id)
+);
+
+-- A suite has at most one open proposal
+CREATE UNIQUE INDEX IF NOT EXISTS idx_suite_gender_preference_proposals_open ON suite_gender_preference_proposals(suite_uuid) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_suite_gender_preference_proposals_suite_uuid ON suite_gender_preference_proposals(suite_uuid);
@@ -0,0 +1 @@
+ALTER TABLE dorms DROP COLUMN IF EXISTS gender_preference_mode;
@@ -0,0 +1,7 @@
+-- How a dorm treats a suite's gender preference on pulls:
+--   advisory: shown to students only
+--   enforced: a pull into a suite with a preference is refused if one of the
+--     proposed occupants' preferences has nothing in common with it, unless the
+--     pull outranks everyone already living in the suite
+ALTER TABLE dorms ADD COLUMN IF NOT EXISTS gender_preference_mode varchar NOT NULL DEFAULT 'advisory'
+    CHECK (gender_preference_mode IN ('advisory', 'enforced'));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS profile_edit_windows;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_channels;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
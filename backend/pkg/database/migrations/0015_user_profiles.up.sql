@@ -0,0 +1,17 @@
+-- What students can change about themselves through /users/me
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name varchar NOT NULL DEFAULT '';
+-- The emails a student gets while notifications_enabled is on
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_channels varchar[] NOT NULL DEFAULT '{bumps,frosh,favorites}';
+
+-- Students can only change a field while an admin has opened a window for it
+CREATE TABLE IF NOT EXISTS profile_edit_windows (
+    window_id serial PRIMARY KEY,
+    field varchar NOT NULL CHECK (field IN ('gender_preferences', 'notifications', 'display_name')),
+    opens_at timestamp WITH TIME ZONE NOT NULL,
+    closes_at timestamp WITH TIME ZONE NOT NULL,
+    created_by varchar NOT NULL DEFAULT '',
+    created_at timestamp WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (closes_at > opens_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_profile_edit_windows_field ON profile_edit_windows(field, closes_at);
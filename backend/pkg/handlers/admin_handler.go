@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"database/sql"
-	"log"
 	"net/http"
+	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
 	"time"
 
 	"roomdraw/backend/pkg/models"
@@ -23,14 +25,14 @@ type BlocklistedUser struct {
 
 // GetBlocklistedUsers returns a list of all blocklisted users
 func GetBlocklistedUsers(c *gin.Context) {
-	rows, err := database.DB.Query(`
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
 		SELECT email, clear_room_count, clear_room_date, blocklisted_at, blocklisted_reason
 		FROM user_rate_limits
 		WHERE is_blocklisted = true
 		ORDER BY blocklisted_at DESC
 	`)
 	if err != nil {
-		log.Printf("Error querying blocklisted users: %v", err)
+		logging.FromContext(c).Error("Error querying blocklisted users", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blocklisted users"})
 		return
 	}
@@ -47,7 +49,7 @@ func GetBlocklistedUsers(c *gin.Context) {
 			&blocklistedAt,
 			&user.BlocklistedReason,
 		); err != nil {
-			log.Printf("Error scanning blocklisted user: %v", err)
+			logging.FromContext(c).Error("Error scanning blocklisted user", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan blocklisted users"})
 			return
 		}
@@ -68,12 +70,13 @@ func RemoveUserBlocklist(c *gin.Context) {
 	email := c.Param("email")
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
+		logging.FromContext(c).Error("Error starting transaction", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -92,7 +95,7 @@ func RemoveUserBlocklist(c *gin.Context) {
 	`, email)
 
 	if err != nil {
-		log.Printf("Error removing user from blocklist: %v", err)
+		logging.FromContext(c).Error("Error removing user from blocklist", "error", err)
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user from blocklist"})
 		return
@@ -101,7 +104,7 @@ func RemoveUserBlocklist(c *gin.Context) {
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		logging.FromContext(c).Error("Error committing transaction", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
@@ -109,8 +112,14 @@ func RemoveUserBlocklist(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User removed from blocklist", "email": email})
 }
 
-// GetUserClearRoomStats gets a user's clear room usage stats
-func GetUserClearRoomStats(c *gin.Context) {
+// GetUserClearRoomStats reports how many clears the user has left today under the draw rules
+func GetUserClearRoomStats(draw config.DrawConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		getUserClearRoomStats(c, draw)
+	}
+}
+
+func getUserClearRoomStats(c *gin.Context, draw config.DrawConfig) {
 	email, exists := c.Get("email")
 	if !exists {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User email not found"})
@@ -118,24 +127,19 @@ func GetUserClearRoomStats(c *gin.Context) {
 	}
 	emailStr := email.(string)
 
-	// Get Pacific time
-	loc, err := time.LoadLocation("America/Los_Angeles")
-	if err != nil {
-		log.Printf("Error loading Pacific timezone: %v", err)
-		// Fall back to UTC if unable to load Pacific time
-		loc = time.UTC
-	}
+	// Get the time in the draw timezone (Pacific by default)
+	loc := draw.Location()
 	pacificNow := time.Now().In(loc)
 	today := pacificNow.Format("2006-01-02") // YYYY-MM-DD format
 	todayDate, _ := time.Parse("2006-01-02", today)
 
-	const MAX_DAILY_CLEARS = 10
+	maxDailyClears := draw.MaxDailyClears
 
 	// Define a UserRateLimit instance
 	var userLimit models.UserRateLimit
 
 	// Get the user's clear room stats
-	err = database.DB.QueryRow(`
+	err := database.DB.QueryRow(`
 		SELECT email, clear_room_count, clear_room_date, is_blocklisted, blocklisted_at, blocklisted_reason
 		FROM user_rate_limits
 		WHERE email = $1
@@ -157,7 +161,7 @@ func GetUserClearRoomStats(c *gin.Context) {
 			userLimit.ClearRoomDate.Time = todayDate
 			userLimit.IsBlocklisted = false
 		} else {
-			log.Printf("Error retrieving clear room stats: %v", err)
+			logging.FromContext(c).Error("Error retrieving clear room stats", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rate limit information"})
 			return
 		}
@@ -170,8 +174,7 @@ func GetUserClearRoomStats(c *gin.Context) {
 	}
 
 	// Log the values for debugging
-	log.Printf("For user %s: clearCount=%d, recordDate=%s, isBlocklisted=%v",
-		emailStr, userLimit.ClearRoomCount, recordDateStr, userLimit.IsBlocklisted)
+	logging.FromContext(c).Debug("Clear room stats", "email", emailStr, "clear_count", userLimit.ClearRoomCount, "record_date", recordDateStr, "blocklisted", userLimit.IsBlocklisted)
 
 	// Check if we need to reset based on date
 	if recordDateStr != today {
@@ -188,8 +191,8 @@ func GetUserClearRoomStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"clearRoomCount":  userLimit.ClearRoomCount,
-		"maxDailyClears":  MAX_DAILY_CLEARS,
-		"remainingClears": MAX_DAILY_CLEARS - userLimit.ClearRoomCount,
+		"maxDailyClears":  maxDailyClears,
+		"remainingClears": maxDailyClears - userLimit.ClearRoomCount,
 		"resetsInMinutes": minutesUntilReset,
 		"pacificDate":     today,
 		"isBlocklisted":   userLimit.IsBlocklisted,
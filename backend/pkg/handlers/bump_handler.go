@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// dryRunKey marks a pull as a preview so it is rolled back instead of committed
+	dryRunKey = "pull_dry_run"
+	// dryRunSuitesKey holds the suites whose rooms a preview compares
+	dryRunSuitesKey = "pull_dry_run_suites"
+	// dryRunResultKey holds what a previewed pull would have changed
+	dryRunResultKey = "pull_dry_run_result"
+)
+
+// BumpEdge is one student displacing another from a room
+type BumpEdge struct {
+	From      int       `json:"from"`
+	To        int       `json:"to"`
+	RoomUUID  string    `json:"roomUuid"`
+	RoomID    string    `json:"roomId"`
+	DormName  string    `json:"dormName"`
+	Operation string    `json:"operation"`
+	LogID     int       `json:"logId"`
+	At        time.Time `json:"at"`
+	// DisbandedGroup is the suite group the bumped room belonged to, which the pull broke up
+	DisbandedGroup *uuid.UUID `json:"disbandedGroup,omitempty"`
+}
+
+// BumpNode is a student who displaced or was displaced
+type BumpNode struct {
+	UserID int    `json:"userId"`
+	Name   string `json:"name"`
+}
+
+// BumpCascade is a pull and every later pull made by a student it displaced,
+// directly or further down the chain
+type BumpCascade struct {
+	RootLogID int   `json:"rootLogId"`
+	LogIDs    []int `json:"logIds"`
+	Displaced []int `json:"displaced"`
+	Depth     int   `json:"depth"`
+}
+
+// bumpEvent is one logged pull that displaced someone
+type bumpEvent struct {
+	logID      int
+	displacers []int
+	bumped     []int
+}
+
+// GetBumpGraph returns who displaced whom since ?since= (RFC 3339 or
+// YYYY-MM-DD, default the last 24 hours), built from the bumped occupants the
+// pull handlers record in transaction_logs
+func GetBumpGraph(c *gin.Context) {
+	since := time.Now().Add(-24 * time.Hour)
+	if sinceParam := c.Query("since"); sinceParam != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", sinceParam)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time or a YYYY-MM-DD date"})
+			return
+		}
+		since = parsed
+	}
+
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
+        SELECT l.log_id, l.operation_type, l.entity_id, l.created_at, l.details,
+               COALESCE(r.room_id, ''), COALESCE(r.dorm_name, '')
+        FROM transaction_logs l
+        LEFT JOIN rooms r ON r.room_uuid::text = l.entity_id
+        WHERE l.created_at >= $1
+          AND jsonb_array_length(COALESCE(l.details->'bumped_occupant_ids', '[]'::jsonb)) > 0
+        ORDER BY l.created_at, l.log_id`, since)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying bump logs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bumps"})
+		return
+	}
+	defer rows.Close()
+
+	edges := []BumpEdge{}
+	var events []bumpEvent
+	userIDs := map[int]bool{}
+	for rows.Next() {
+		var edge BumpEdge
+		var detailsJSON []byte
+		if err := rows.Scan(&edge.LogID, &edge.Operation, &edge.RoomUUID, &edge.At, &detailsJSON, &edge.RoomID, &edge.DormName); err != nil {
+			logging.FromContext(c).Error("Error scanning bump log", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan bumps"})
+			return
+		}
+
+		var details struct {
+			ProposedOccupants  []int      `json:"proposed_occupants"`
+			BumpedOccupantIDs  []int      `json:"bumped_occupant_ids"`
+			PreviousSGroupUUID *uuid.UUID `json:"previous_sgroup_uuid"`
+		}
+		if err := json.Unmarshal(detailsJSON, &details); err != nil {
+			logging.FromContext(c).Warn("Skipping transaction log with unreadable details", "log_id", edge.LogID, "error", err)
+			continue
+		}
+		if details.PreviousSGroupUUID != nil && *details.PreviousSGroupUUID != uuid.Nil {
+			edge.DisbandedGroup = details.PreviousSGroupUUID
+		}
+
+		events = append(events, bumpEvent{logID: edge.LogID, displacers: details.ProposedOccupants, bumped: details.BumpedOccupantIDs})
+		for _, from := range details.ProposedOccupants {
+			userIDs[from] = true
+			for _, to := range details.BumpedOccupantIDs {
+				userIDs[to] = true
+				e := edge
+				e.From, e.To = from, to
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	nodes, err := bumpNodes(c, userIDs)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying bumped users", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bumped users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":     since,
+		"nodes":     nodes,
+		"edges":     edges,
+		"cascades":  bumpCascades(events),
+		"pullCount": len(events),
+	})
+}
+
+func bumpNodes(c *gin.Context, userIDs map[int]bool) ([]BumpNode, error) {
+	ids := make([]int, 0, len(userIDs))
+	for id := range userIDs {
+		ids = append(ids, id)
+	}
+
+	rows, err := database.DB.QueryContext(c.Request.Context(),
+		"SELECT id, first_name || ' ' || last_name FROM users WHERE id = ANY($1) ORDER BY id", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []BumpNode{}
+	for rows.Next() {
+		var node BumpNode
+		if err := rows.Scan(&node.UserID, &node.Name); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// bumpCascades links each pull to the next pull made by each student it
+// displaced, and returns one cascade per pull that no earlier pull led to
+func bumpCascades(events []bumpEvent) []BumpCascade {
+	next := make(map[int][]int, len(events)) // event index -> following event indexes
+	hasParent := make([]bool, len(events))
+	for i, event := range events {
+		for _, bumped := range event.bumped {
+			for j := i + 1; j < len(events); j++ {
+				if slices.Contains(events[j].displacers, bumped) {
+					next[i] = append(next[i], j)
+					hasParent[j] = true
+					break
+				}
+			}
+		}
+	}
+
+	cascades := []BumpCascade{}
+	for i := range events {
+		if hasParent[i] {
+			continue
+		}
+
+		cascade := BumpCascade{RootLogID: events[i].logID}
+		displaced := map[int]bool{}
+		seen := map[int]bool{i: true}
+		level := []int{i}
+		for len(level) > 0 {
+			cascade.Depth++
+			var following []int
+			for _, e := range level {
+				cascade.LogIDs = append(cascade.LogIDs, events[e].logID)
+				for _, id := range events[e].bumped {
+					displaced[id] = true
+				}
+				for _, n := range next[e] {
+					if !seen[n] {
+						seen[n] = true
+						following = append(following, n)
+					}
+				}
+			}
+			level = following
+		}
+
+		for id := range displaced {
+			cascade.Displaced = append(cascade.Displaced, id)
+		}
+		sort.Ints(cascade.Displaced)
+		cascades = append(cascades, cascade)
+	}
+
+	return cascades
+}
+
+// previewRoom is the part of a room's state a pull can change
+type previewRoom struct {
+	RoomUUID     uuid.UUID
+	RoomID       string
+	DormName     string
+	Occupants    models.IntArray
+	SGroupUUID   uuid.NullUUID
+	PullPriority models.PullPriority
+}
+
+type dryRunResult struct {
+	after         map[uuid.UUID]previewRoom
+	notifications []models.BumpNotification
+	err           error
+}
+
+// RoomImpact is how a previewed pull would change one room
+type RoomImpact struct {
+	RoomUUID  uuid.UUID `json:"roomUuid"`
+	RoomID    string    `json:"roomId"`
+	DormName  string    `json:"dormName"`
+	Changes   []string  `json:"changes"`
+	Displaced []int     `json:"displaced"`
+}
+
+func isDryRun(c *gin.Context) bool {
+	return c.GetBool(dryRunKey)
+}
+
+// finishDryRun records the state a previewed pull left the compared rooms
+// in, then rolls the pull back
+func finishDryRun(c *gin.Context, tx *sql.Tx, notificationQueue *models.BumpNotificationQueue) {
+	defer tx.Rollback()
+
+	suites, _ := c.Get(dryRunSuitesKey)
+	after, err := previewRooms(tx, suites.([]uuid.UUID))
+	c.Set(dryRunResultKey, dryRunResult{after: after, notifications: notificationQueue.Notifications, err: err})
+}
+
+func previewRooms(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}, suites []uuid.UUID) (map[uuid.UUID]previewRoom, error) {
+	rows, err := q.Query(`
+        SELECT room_uuid, room_id, dorm_name, occupants, sgroup_uuid, pull_priority
+        FROM rooms WHERE suite_uuid = ANY($1)`, pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := map[uuid.UUID]previewRoom{}
+	for rows.Next() {
+		var room previewRoom
+		if err := rows.Scan(&room.RoomUUID, &room.RoomID, &room.DormName, &room.Occupants, &room.SGroupUUID, &room.PullPriority); err != nil {
+			return nil, err
+		}
+		rooms[room.RoomUUID] = room
+	}
+	return rooms, rows.Err()
+}
+
+// PreviewPull runs a proposed pull without committing it and reports every
+// student and room it would displace. Invalid pulls are rejected with the same
+// error the real pull would give.
+func PreviewPull(c *gin.Context) {
+	var request models.OccupantUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A pull only changes rooms in the target suite and the pull leader's
+	// suite, both of which the write queue holds for this request
+	var suites []uuid.UUID
+	err := database.DB.QueryRow(`
+        SELECT array_agg(DISTINCT suite_uuid) FROM rooms WHERE room_uuid::text = $1 OR room_uuid = $2`,
+		c.Param("roomuuid"), request.PullLeaderRoom).Scan(pq.Array(&suites))
+	if err != nil || len(suites) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
+
+	before, err := previewRooms(database.DB, suites)
+	if err != nil {
+		logging.FromContext(c).Error("Error reading rooms for pull preview", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read rooms"})
+		return
+	}
+
+	c.Set(dryRunKey, true)
+	c.Set(dryRunSuitesKey, suites)
+	runPull(c, request)
+	if c.Writer.Written() {
+		return // the pull was rejected and has already responded
+	}
+
+	value, ok := c.Get(dryRunResultKey)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Pull preview did not complete"})
+		return
+	}
+	result := value.(dryRunResult)
+	if result.err != nil {
+		logging.FromContext(c).Error("Error reading pull preview result", "error", result.err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute pull preview"})
+		return
+	}
+
+	impacts := []RoomImpact{}
+	displacedIDs := []int{}
+	for roomUUID, old := range before {
+		now := result.after[roomUUID]
+		impact := RoomImpact{RoomUUID: roomUUID, RoomID: old.RoomID, DormName: old.DormName, Displaced: []int{}}
+
+		for _, occupant := range old.Occupants {
+			if !slices.Contains(now.Occupants, occupant) && !slices.Contains(request.ProposedOccupants, occupant) {
+				impact.Displaced = append(impact.Displaced, occupant)
+			}
+		}
+		if len(impact.Displaced) > 0 {
+			impact.Changes = append(impact.Changes, "occupants_displaced")
+			displacedIDs = append(displacedIDs, impact.Displaced...)
+		}
+		if old.SGroupUUID.Valid && now.SGroupUUID != old.SGroupUUID {
+			impact.Changes = append(impact.Changes, "left_suite_group")
+		}
+		if old.PullPriority.PullType == 3 && now.PullPriority.PullType != 3 {
+			impact.Changes = append(impact.Changes, "lock_pull_removed")
+		}
+		if old.PullPriority.Inherited.Valid && !now.PullPriority.Inherited.Valid && len(now.Occupants) > 0 {
+			impact.Changes = append(impact.Changes, "lost_inherited_priority")
+		}
+
+		if len(impact.Changes) > 0 {
+			impacts = append(impacts, impact)
+		}
+	}
+	sort.Slice(impacts, func(i, j int) bool { return impacts[i].RoomID < impacts[j].RoomID })
+
+	userIDs := map[int]bool{}
+	for _, id := range displacedIDs {
+		userIDs[id] = true
+	}
+	displaced, err := bumpNodes(c, userIDs)
+	if err != nil {
+		logging.FromContext(c).Error("Error reading displaced users for pull preview", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read displaced users"})
+		return
+	}
+
+	notified := make([]int, 0, len(result.notifications))
+	for _, n := range result.notifications {
+		notified = append(notified, n.UserID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":     true,
+		"displaced": displaced,
+		"rooms":     impacts,
+		"notified":  notified,
+	})
+}
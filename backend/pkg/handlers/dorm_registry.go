@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/models"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// dormRegistry holds the dorms and suite_pull_policies tables, loaded once at
+// startup by LoadDorms. The handlers look dorm rules up here instead of
+// comparing dorm ids.
+var dormRegistry = struct {
+	sync.RWMutex
+	byID     map[int]models.Dorm
+	policies map[string]models.SuitePullPolicy
+}{byID: make(map[int]models.Dorm), policies: make(map[string]models.SuitePullPolicy)}
+
+// LoadDorms reads the dorms and their suite pull policies into the registry
+func LoadDorms(ctx context.Context) error {
+	rows, err := database.DB.QueryContext(ctx, "SELECT dorm_id, name, description, frosh_policy, suite_pull_policy, capabilities, gender_preference_mode FROM dorms")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int]models.Dorm)
+	for rows.Next() {
+		var d models.Dorm
+		if err := rows.Scan(&d.ID, &d.Name, &d.Description, &d.FroshPolicy, &d.SuitePullPolicy, pq.Array(&d.Capabilities), &d.GenderPreferenceMode); err != nil {
+			return err
+		}
+		byID[d.ID] = d
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	policies, err := loadSuitePullPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	dormRegistry.Lock()
+	dormRegistry.byID = byID
+	dormRegistry.policies = policies
+	dormRegistry.Unlock()
+
+	slog.Info("Loaded dorms and suite pull policies", "dorms", len(byID), "policies", len(policies))
+	return nil
+}
+
+func loadSuitePullPolicies(ctx context.Context) (map[string]models.SuitePullPolicy, error) {
+	rows, err := database.DB.QueryContext(ctx, `
+		SELECT name, description, max_rooms, suite_room_counts, room_combinations, in_dorm_leader_past, in_dorm_singles, normal_pull_triples
+		FROM suite_pull_policies`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make(map[string]models.SuitePullPolicy)
+	for rows.Next() {
+		var p models.SuitePullPolicy
+		var roomCounts pq.Int64Array
+		var combinations []byte
+		if err := rows.Scan(&p.Name, &p.Description, &p.MaxRooms, &roomCounts, &combinations, &p.InDormLeaderPast, &p.InDormSingles, pq.Array(&p.NormalPullTriples)); err != nil {
+			return nil, err
+		}
+		for _, n := range roomCounts {
+			p.SuiteRoomCounts = append(p.SuiteRoomCounts, int(n))
+		}
+		if err := json.Unmarshal(combinations, &p.RoomCombinations); err != nil {
+			return nil, fmt.Errorf("invalid room_combinations for suite pull policy %s: %v", p.Name, err)
+		}
+		policies[p.Name] = p
+	}
+	return policies, rows.Err()
+}
+
+// lookupDorm returns the registry entry of a dorm id
+func lookupDorm(id int) (models.Dorm, bool) {
+	dormRegistry.RLock()
+	defer dormRegistry.RUnlock()
+	d, ok := dormRegistry.byID[id]
+	return d, ok
+}
+
+// lookupDormByName returns the registry entry of a dorm by its name, ignoring case
+func lookupDormByName(name string) (models.Dorm, bool) {
+	dormRegistry.RLock()
+	defer dormRegistry.RUnlock()
+	for _, d := range dormRegistry.byID {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return models.Dorm{}, false
+}
+
+// suitePullPolicy returns the policy a suite follows: its own if it names one,
+// otherwise its dorm's
+func suitePullPolicy(dorm int, override string) models.SuitePullPolicy {
+	dormRegistry.RLock()
+	defer dormRegistry.RUnlock()
+	name := override
+	if name == "" {
+		name = dormRegistry.byID[dorm].SuitePullPolicy
+	}
+	if p, ok := dormRegistry.policies[name]; ok {
+		return p
+	}
+	return models.DefaultSuitePullPolicy
+}
+
+// loadSuitePullPolicy returns the policy of a suite read in a transaction
+func loadSuitePullPolicy(tx *sql.Tx, suiteUUID uuid.UUID) (models.SuitePullPolicy, error) {
+	var dorm int
+	var override string
+	err := tx.QueryRow("SELECT dorm, COALESCE(suite_pull_policy, '') FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&dorm, &override)
+	if err != nil {
+		return models.SuitePullPolicy{}, err
+	}
+	return suitePullPolicy(dorm, override), nil
+}
+
+// dormGrantsInDorm reports whether seniors can hold in dorm for a dorm
+func dormGrantsInDorm(id int) bool {
+	d, ok := lookupDorm(id)
+	return ok && d.Can(models.DormCapabilityInDorm)
+}
+
+// GetDorms lists every dorm with its rules
+func GetDorms(c *gin.Context) {
+	dormRegistry.RLock()
+	dorms := make([]models.Dorm, 0, len(dormRegistry.byID))
+	for _, d := range dormRegistry.byID {
+		dorms = append(dorms, d)
+	}
+	dormRegistry.RUnlock()
+
+	sort.Slice(dorms, func(i, j int) bool { return dorms[i].ID < dorms[j].ID })
+	c.JSON(http.StatusOK, dorms)
+}
+
+// GetSuitePullPolicies lists every suite pull policy
+func GetSuitePullPolicies(c *gin.Context) {
+	dormRegistry.RLock()
+	policies := make([]models.SuitePullPolicy, 0, len(dormRegistry.policies))
+	for _, p := range dormRegistry.policies {
+		policies = append(policies, p)
+	}
+	dormRegistry.RUnlock()
+
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	c.JSON(http.StatusOK, policies)
+}
+
+// SetSuitePullPolicy lets an admin make a suite follow a different policy than its
+// dorm, e.g. after a renovation. An empty policy goes back to the dorm's.
+func SetSuitePullPolicy(c *gin.Context) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	var body struct {
+		Policy string `json:"policy"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if body.Policy != "" {
+		dormRegistry.RLock()
+		_, ok := dormRegistry.policies[body.Policy]
+		dormRegistry.RUnlock()
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown suite pull policy " + body.Policy})
+			return
+		}
+	}
+
+	var previous string
+	err = database.DB.QueryRowContext(c.Request.Context(), `
+		UPDATE suites s SET suite_pull_policy = NULLIF($1, '')
+		FROM suites old WHERE s.suite_uuid = $2 AND old.suite_uuid = s.suite_uuid
+		RETURNING COALESCE(old.suite_pull_policy, '')`, body.Policy, suiteUUID).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Error updating suite pull policy", "suite", suiteUUID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update suite pull policy"})
+		return
+	}
+
+	if err := logging.LogOperation(c, "SET_SUITE_PULL_POLICY", models.EntityTypeSuite, suiteUUID.String(),
+		map[string]string{"suitePullPolicy": previous}, map[string]string{"suitePullPolicy": body.Policy}, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log SET_SUITE_PULL_POLICY operation", "suite", suiteUUID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suite pull policy updated"})
+}
+
+// SetDormGenderPreferenceMode lets an admin choose whether pulls in a dorm have to
+// respect suite gender preferences. It takes effect right away.
+func SetDormGenderPreferenceMode(c *gin.Context) {
+	dormID, err := strconv.Atoi(c.Param("dormid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm id"})
+		return
+	}
+
+	var body struct {
+		Mode string `json:"mode" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if body.Mode != models.GenderPreferenceModeAdvisory && body.Mode != models.GenderPreferenceModeEnforced {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be advisory or enforced"})
+		return
+	}
+
+	var previous string
+	err = database.DB.QueryRowContext(c.Request.Context(), `
+		UPDATE dorms d SET gender_preference_mode = $1
+		FROM dorms old WHERE d.dorm_id = $2 AND old.dorm_id = d.dorm_id
+		RETURNING old.gender_preference_mode`, body.Mode, dormID).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dorm not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Error updating gender preference mode", "dorm", dormID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gender preference mode"})
+		return
+	}
+
+	dormRegistry.Lock()
+	if d, ok := dormRegistry.byID[dormID]; ok {
+		d.GenderPreferenceMode = body.Mode
+		dormRegistry.byID[dormID] = d
+	}
+	dormRegistry.Unlock()
+
+	if err := logging.LogOperation(c, "SET_GENDER_PREFERENCE_MODE", models.EntityTypeDorm, strconv.Itoa(dormID),
+		map[string]string{"genderPreferenceMode": previous}, map[string]string{"genderPreferenceMode": body.Mode}, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log SET_GENDER_PREFERENCE_MODE operation", "dorm", dormID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Gender preference mode updated"})
+}
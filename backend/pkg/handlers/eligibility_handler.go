@@ -0,0 +1,557 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EligiblePull is one way a group could take a room right now
+type EligiblePull struct {
+	PullType int `json:"pullType"` // 1 = self, 2 = normal pull, 3 = lock pull, 4 = alternative pull
+	// PullLeaderRoom is the room whose occupants would have to lead a normal or alternative pull
+	PullLeaderRoom *uuid.UUID          `json:"pullLeaderRoom,omitempty"`
+	Priority       models.PullPriority `json:"priority"`
+}
+
+// EligibleRoom is a room a group could currently pull into, with every pull that would succeed
+type EligibleRoom struct {
+	RoomUUID          uuid.UUID           `json:"roomUuid"`
+	RoomID            string              `json:"roomId"`
+	Dorm              int                 `json:"dorm"`
+	DormName          string              `json:"dormName"`
+	SuiteUUID         uuid.UUID           `json:"suiteUuid"`
+	MaxOccupancy      int                 `json:"maxOccupancy"`
+	CurrentOccupancy  int                 `json:"currentOccupancy"`
+	Occupants         models.IntArray     `json:"occupants"`
+	PullPriority      models.PullPriority `json:"pullPriority"`
+	GenderPreferences []string            `json:"genderPreferences"`
+	InDorm            bool                `json:"inDorm"`
+	// FavoriteRank is the user's rank of the favorite covering the room, if any
+	FavoriteRank *int           `json:"favoriteRank,omitempty"`
+	Pulls        []EligiblePull `json:"pulls"`
+}
+
+// eligibility is a read-only snapshot of the draw that the pull rules are checked against
+type eligibility struct {
+	// users are the requested users in the order they were asked for, group the
+	// ones the pull rules are currently checked for
+	users        []models.UserRaw
+	group        []models.UserRaw
+	rooms        []models.RoomRaw
+	roomsBySuite map[uuid.UUID][]models.RoomRaw
+	suites       map[uuid.UUID]models.SuiteRaw
+	groupLeaders map[uuid.UUID]models.PullPriority
+	roomUsers    map[uuid.UUID][]models.UserRaw
+	// proposals are the approved gender preference proposals by suite
+	proposals map[uuid.UUID]*approvedGenderPreferenceProposal
+}
+
+// GetMyEligibleRooms lists the rooms the signed in user could take right now, see
+// eligibleRooms
+func GetMyEligibleRooms(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	eligibleRooms(c, userID)
+}
+
+// GetEligibleRooms lists the rooms any user could take right now, for admins, see
+// eligibleRooms
+func GetEligibleRooms(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	eligibleRooms(c, userID)
+}
+
+// eligibleRooms lists every room a user, optionally with the proposed roommates in
+// ?with=, could take right now by self, normal, lock or alternative pull. The pull
+// handlers' rules are checked against one read-only snapshot of the draw, so a room
+// can still be lost to a concurrent pull afterwards. PreviewPull runs the pull itself
+// for a room the user picks.
+func eligibleRooms(c *gin.Context, userID int) {
+	groupIDs := models.IntArray{userID}
+	if with := c.Query("with"); with != "" {
+		for _, s := range strings.Split(with, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id in with: " + s})
+				return
+			}
+			if slices.Contains(groupIDs, id) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate user was specified in the occupants list"})
+				return
+			}
+			groupIDs = append(groupIDs, id)
+		}
+	}
+
+	pullType := 0
+	if s := c.Query("pull_type"); s != "" {
+		var err error
+		pullType, err = strconv.Atoi(s)
+		if err != nil || pullType < 1 || pullType > 4 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pull_type must be 1, 2, 3 or 4"})
+			return
+		}
+	}
+
+	var dormOrder []int
+	if s := c.Query("dorms"); s != "" {
+		for _, d := range strings.Split(s, ",") {
+			dorm, err := strconv.Atoi(strings.TrimSpace(d))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm in dorms: " + d})
+				return
+			}
+			dormOrder = append(dormOrder, dorm)
+		}
+	}
+
+	// read everything from one snapshot so rooms and users agree with each other
+	tx, err := database.DB.BeginTx(c.Request.Context(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	e, err := loadEligibility(tx, groupIDs, nil)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load the draw state", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the draw state"})
+		return
+	}
+
+	favorites, err := loadFavoriteRanks(tx, userID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load favorites", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load favorites"})
+		return
+	}
+	if dormOrder == nil {
+		dormOrder = favorites.dormOrder(e)
+	}
+
+	e.group = e.users
+	if len(e.group) != len(groupIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var placed models.IntArray
+	for _, u := range e.group {
+		if u.Preplaced {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull with a preplaced user", "occupants": models.IntArray{u.Id}})
+			return
+		}
+		if u.RoomUUID != uuid.Nil {
+			placed = append(placed, u.Id)
+		}
+	}
+	if len(placed) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": placed})
+		return
+	}
+
+	eligibleRooms := []EligibleRoom{}
+	for _, room := range e.rooms {
+		pulls := slices.DeleteFunc(e.pullsInto(room), func(p EligiblePull) bool {
+			return pullType != 0 && p.PullType != pullType
+		})
+		if len(pulls) == 0 {
+			continue
+		}
+
+		eligibleRooms = append(eligibleRooms, EligibleRoom{
+			RoomUUID:          room.RoomUUID,
+			RoomID:            room.RoomID,
+			Dorm:              room.Dorm,
+			DormName:          room.DormName,
+			SuiteUUID:         room.SuiteUUID,
+			MaxOccupancy:      room.MaxOccupancy,
+			CurrentOccupancy:  room.CurrentOccupancy,
+			Occupants:         room.Occupants,
+			PullPriority:      room.PullPriority,
+			GenderPreferences: e.suites[room.SuiteUUID].GenderPreferences,
+			InDorm:            e.group[0].InDorm == room.Dorm,
+			FavoriteRank:      favorites.rankOf(room),
+			Pulls:             pulls,
+		})
+	}
+
+	rankEligibleRooms(eligibleRooms, len(groupIDs), dormOrder)
+
+	c.JSON(http.StatusOK, gin.H{
+		"userId":    userID,
+		"occupants": groupIDs,
+		"count":     len(eligibleRooms),
+		"rooms":     eligibleRooms,
+	})
+}
+
+// rankEligibleRooms puts the user's favorites first in their ranking, then rooms in
+// the user's in-dorm, then rooms that fit the group exactly, then dorms in dormOrder,
+// then by dorm and room number
+func rankEligibleRooms(rooms []EligibleRoom, groupSize int, dormOrder []int) {
+	dormRank := func(dorm int) int {
+		if i := slices.Index(dormOrder, dorm); i >= 0 {
+			return i
+		}
+		return len(dormOrder)
+	}
+
+	sort.SliceStable(rooms, func(i, j int) bool {
+		a, b := rooms[i], rooms[j]
+		if (a.FavoriteRank == nil) != (b.FavoriteRank == nil) {
+			return a.FavoriteRank != nil
+		}
+		if a.FavoriteRank != nil && *a.FavoriteRank != *b.FavoriteRank {
+			return *a.FavoriteRank < *b.FavoriteRank
+		}
+		if a.InDorm != b.InDorm {
+			return a.InDorm
+		}
+		if fitA, fitB := a.MaxOccupancy-groupSize, b.MaxOccupancy-groupSize; fitA != fitB {
+			return fitA < fitB
+		}
+		if rankA, rankB := dormRank(a.Dorm), dormRank(b.Dorm); rankA != rankB {
+			return rankA < rankB
+		}
+		if a.Dorm != b.Dorm {
+			return a.Dorm < b.Dorm
+		}
+		return a.RoomID < b.RoomID
+	})
+}
+
+// favoriteRanks are the rooms and suites a user saved, by their rank
+type favoriteRanks struct {
+	rooms  map[uuid.UUID]int
+	suites map[uuid.UUID]int
+	// order is every favorited room or suite, best ranked first
+	order []uuid.UUID
+}
+
+// loadFavoriteRanks reads a user's favorites in their ranking
+func loadFavoriteRanks(tx *sql.Tx, userID int) (favoriteRanks, error) {
+	f := favoriteRanks{rooms: make(map[uuid.UUID]int), suites: make(map[uuid.UUID]int)}
+	rows, err := tx.Query("SELECT room_uuid, suite_uuid, rank FROM user_favorites WHERE user_id = $1 ORDER BY rank, favorite_id", userID)
+	if err != nil {
+		return f, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roomUUID, suiteUUID uuid.NullUUID
+		var rank int
+		if err := rows.Scan(&roomUUID, &suiteUUID, &rank); err != nil {
+			return f, err
+		}
+		if roomUUID.Valid {
+			f.rooms[roomUUID.UUID] = rank
+			f.order = append(f.order, roomUUID.UUID)
+		}
+		if suiteUUID.Valid {
+			f.suites[suiteUUID.UUID] = rank
+			f.order = append(f.order, suiteUUID.UUID)
+		}
+	}
+	return f, rows.Err()
+}
+
+// rankOf returns the best rank of a favorite covering room, the room itself or its suite
+func (f favoriteRanks) rankOf(room models.RoomRaw) *int {
+	rank, ok := f.rooms[room.RoomUUID]
+	if suiteRank, suiteOk := f.suites[room.SuiteUUID]; suiteOk && (!ok || suiteRank < rank) {
+		rank, ok = suiteRank, true
+	}
+	if !ok {
+		return nil
+	}
+	return &rank
+}
+
+// dormOrder returns the dorms of the favorites, best ranked first
+func (f favoriteRanks) dormOrder(e *eligibility) []int {
+	var dorms []int
+	for _, id := range f.order {
+		dorm := -1
+		if suite, ok := e.suites[id]; ok {
+			dorm = suite.Dorm
+		}
+		for _, room := range e.rooms {
+			if room.RoomUUID == id {
+				dorm = room.Dorm
+				break
+			}
+		}
+		if dorm >= 0 && !slices.Contains(dorms, dorm) {
+			dorms = append(dorms, dorm)
+		}
+	}
+	return dorms
+}
+
+// loadEligibility reads the users asked for and the draw the pull rules are checked
+// against. suites limits the draw to those suites, nil reads all of it.
+func loadEligibility(tx *sql.Tx, userIDs models.IntArray, suites []uuid.UUID) (*eligibility, error) {
+	e := &eligibility{
+		roomsBySuite: make(map[uuid.UUID][]models.RoomRaw),
+		suites:       make(map[uuid.UUID]models.SuiteRaw),
+		groupLeaders: make(map[uuid.UUID]models.PullPriority),
+		roomUsers:    make(map[uuid.UUID][]models.UserRaw),
+		proposals:    make(map[uuid.UUID]*approvedGenderPreferenceProposal),
+	}
+
+	rows, err := tx.Query(`
+        SELECT id, year, draw_number, in_dorm, preplaced, room_uuid, gender_preferences FROM users
+        WHERE id = ANY($1) OR room_uuid IN (SELECT room_uuid FROM rooms WHERE $2::uuid[] IS NULL OR suite_uuid = ANY($2))`,
+		pq.Array(userIDs), pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u models.UserRaw
+		if err := rows.Scan(&u.Id, &u.Year, &u.DrawNumber, &u.InDorm, &u.Preplaced, &u.RoomUUID, &u.GenderPreferences); err != nil {
+			return nil, err
+		}
+		if u.RoomUUID != uuid.Nil {
+			e.roomUsers[u.RoomUUID] = append(e.roomUsers[u.RoomUUID], u)
+		}
+		if slices.Contains(userIDs, u.Id) {
+			e.users = append(e.users, u)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// keep the requested user first so the in-dorm ranking is theirs
+	sort.SliceStable(e.users, func(i, j int) bool {
+		return slices.Index(userIDs, e.users[i].Id) < slices.Index(userIDs, e.users[j].Id)
+	})
+
+	rows, err = tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh FROM rooms WHERE $1::uuid[] IS NULL OR suite_uuid = ANY($1) ORDER BY dorm, room_id", pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.RoomRaw
+		if err := rows.Scan(&r.RoomUUID, &r.Dorm, &r.DormName, &r.RoomID, &r.SuiteUUID, &r.MaxOccupancy, &r.CurrentOccupancy, &r.Occupants, &r.PullPriority, &r.SGroupUUID, &r.HasFrosh); err != nil {
+			return nil, err
+		}
+		e.rooms = append(e.rooms, r)
+		e.roomsBySuite[r.SuiteUUID] = append(e.roomsBySuite[r.SuiteUUID], r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query("SELECT suite_uuid, dorm, dorm_name, floor, room_count, alternative_pull, can_lock_pull, gender_preferences, can_be_gender_preferenced, COALESCE(suite_pull_policy, ''), reslife_room FROM suites WHERE $1::uuid[] IS NULL OR suite_uuid = ANY($1)", pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s models.SuiteRaw
+		if err := rows.Scan(&s.SuiteUUID, &s.Dorm, &s.DormName, &s.Floor, &s.RoomCount, &s.AlternativePull, &s.CanLockPull, &s.GenderPreferences, &s.CanBeGenderPreferenced, &s.SuitePullPolicy, &s.ReslifeRoom); err != nil {
+			return nil, err
+		}
+		e.suites[s.SuiteUUID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query("SELECT sgroup_uuid, pull_priority FROM suitegroups WHERE disbanded = false AND ($1::uuid[] IS NULL OR sgroup_suite = ANY($1))", pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sgroupUUID uuid.UUID
+		var priority models.PullPriority
+		if err := rows.Scan(&sgroupUUID, &priority); err != nil {
+			return nil, err
+		}
+		e.groupLeaders[sgroupUUID] = priority
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the approved proposal of each suite, as loadApprovedGenderPreferenceProposal reads it
+	rows, err = tx.Query(`
+        SELECT DISTINCT ON (p.suite_uuid) p.suite_uuid, p.proposal_id, p.gender_preferences,
+               ARRAY(SELECT a.user_id FROM suite_gender_preference_approvals a WHERE a.proposal_id = p.proposal_id ORDER BY a.user_id)
+        FROM suite_gender_preference_proposals p
+        WHERE p.status = $1 AND ($2::uuid[] IS NULL OR p.suite_uuid = ANY($2))
+        ORDER BY p.suite_uuid, p.decided_at DESC`, models.GenderPreferenceProposalApproved, pq.Array(suites))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var suiteUUID uuid.UUID
+		var proposal approvedGenderPreferenceProposal
+		var preferences pq.StringArray
+		var approvedBy models.IntArray
+		if err := rows.Scan(&suiteUUID, &proposal.proposalID, &preferences, &approvedBy); err != nil {
+			return nil, err
+		}
+		proposal.genderPreferences = preferences
+		proposal.approvedBy = approvedBy
+		e.proposals[suiteUUID] = &proposal
+	}
+	return e, rows.Err()
+}
+
+// candidatePulls returns every pull of the right shape for the group to try on a
+// room, the ones the pull handlers turn away before looking at anyone's priority
+func (e *eligibility) candidatePulls(room models.RoomRaw) []EligiblePull {
+	if room.HasFrosh || room.PullPriority.IsPreplaced || len(e.group) > room.MaxOccupancy {
+		return nil
+	}
+	if e.suites[room.SuiteUUID].ReslifeRoom == room.RoomUUID {
+		return nil
+	}
+
+	var pulls []EligiblePull
+	fills := len(e.group) == room.MaxOccupancy
+	if fills {
+		pulls = append(pulls, EligiblePull{PullType: 1})
+	}
+	if fills && room.CurrentOccupancy == 0 {
+		pulls = append(pulls, EligiblePull{PullType: 3})
+	}
+	for _, leader := range e.roomsBySuite[room.SuiteUUID] {
+		if leader.RoomUUID == room.RoomUUID {
+			continue
+		}
+		leaderRoom := leader.RoomUUID
+		pulls = append(pulls, EligiblePull{PullType: 2, PullLeaderRoom: &leaderRoom})
+		if fills {
+			pulls = append(pulls, EligiblePull{PullType: 4, PullLeaderRoom: &leaderRoom})
+		}
+	}
+	return pulls
+}
+
+// pullsInto returns every pull the group could make into a room right now, with the
+// priority it would give the room
+func (e *eligibility) pullsInto(room models.RoomRaw) []EligiblePull {
+	var pulls []EligiblePull
+	for _, pull := range e.candidatePulls(room) {
+		priority, ok := e.pullPriority(room, pull)
+		if !ok || !e.genderAllows(room, priority) {
+			continue
+		}
+		pull.Priority = priority
+		pulls = append(pulls, pull)
+	}
+	return pulls
+}
+
+// pullPriority runs the pull handlers' rule for the pull's type against the snapshot,
+// and returns the priority the pull would give room if the rules let it through
+func (e *eligibility) pullPriority(room models.RoomRaw, pull EligiblePull) (models.PullPriority, bool) {
+	suite := e.suites[room.SuiteUUID]
+	var leader models.RoomRaw
+	if pull.PullLeaderRoom != nil {
+		i := slices.IndexFunc(e.roomsBySuite[room.SuiteUUID], func(r models.RoomRaw) bool { return r.RoomUUID == *pull.PullLeaderRoom })
+		if i < 0 {
+			return models.PullPriority{}, false
+		}
+		leader = e.roomsBySuite[room.SuiteUUID][i]
+	}
+
+	switch pull.PullType {
+	case 1:
+		priority, err := selfPullPriority(e.group, room)
+		return priority, err == nil
+	case 2:
+		priority, err := normalPullPriority(e.group, room, leader, e.pullPolicy(room))
+		if err != nil {
+			return priority, false
+		}
+		return priority, leader.SGroupUUID == uuid.Nil || e.canAddToSuiteGroup(room, leader, priority)
+	case 3:
+		priority, err := lockPullPriority(e.group, room, suite, e.roomsBySuite[room.SuiteUUID])
+		return priority, err == nil
+	case 4:
+		priority, _, err := alternativePullPriority(e.group, e.roomUsers[leader.RoomUUID], room, leader, suite)
+		return priority, err == nil
+	}
+	return models.PullPriority{}, false
+}
+
+// genderAllows reports whether a pull with priority gets past the suite's gender
+// preference the way enforceSuiteGenderPreference decides it: from the occupants who
+// would stay in the suite and its approved proposal
+func (e *eligibility) genderAllows(room models.RoomRaw, priority models.PullPriority) bool {
+	if dorm, ok := lookupDorm(room.Dorm); !ok || dorm.GenderPreferenceMode != models.GenderPreferenceModeEnforced {
+		return true
+	}
+	if !e.suites[room.SuiteUUID].CanBeGenderPreferenced {
+		return true
+	}
+
+	suiteRooms := e.roomsBySuite[room.SuiteUUID]
+	var staying []models.UserRaw
+	for _, r := range suiteRooms {
+		if r.RoomUUID != room.RoomUUID {
+			staying = append(staying, e.roomUsers[r.RoomUUID]...)
+		}
+	}
+	decision, _ := decideSuiteGenderPreference(true, staying, room.Dorm, e.proposals[room.SuiteUUID])
+	return len(genderPreferenceBlocks(decision.GenderPreferences, priority, room, suiteRooms, e.group)) == 0
+}
+
+// pullPolicy returns the suite pull policy of a room's suite
+func (e *eligibility) pullPolicy(room models.RoomRaw) models.SuitePullPolicy {
+	suite := e.suites[room.SuiteUUID]
+	return suitePullPolicy(room.Dorm, suite.SuitePullPolicy)
+}
+
+// canAddToSuiteGroup applies the suite's pull policy for a leader pulling another
+// room into their suite group
+func (e *eligibility) canAddToSuiteGroup(room, leader models.RoomRaw, proposed models.PullPriority) bool {
+	suite := e.suites[room.SuiteUUID]
+	var groupRooms []int
+	for _, r := range e.roomsBySuite[room.SuiteUUID] {
+		if r.SGroupUUID == leader.SGroupUUID && r.RoomUUID != room.RoomUUID {
+			groupRooms = append(groupRooms, r.MaxOccupancy)
+		}
+	}
+
+	err := e.pullPolicy(room).CheckAddRoom(models.SuiteGroupPull{
+		SuiteRoomCount:      suite.RoomCount,
+		GroupRooms:          groupRooms,
+		RoomSize:            room.MaxOccupancy,
+		Occupants:           len(e.group),
+		LeaderHasInDorm:     leader.PullPriority.HasInDorm,
+		OccupantsHaveInDorm: proposed.HasInDorm,
+	})
+	if err != nil {
+		return false
+	}
+
+	groupPriority, ok := e.groupLeaders[leader.SGroupUUID]
+	return ok && groupPriority == leader.PullPriority
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"roomdraw/backend/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// eligibilityRoom is a room of a suite in dorm 0, which is in no registry so no gender
+// preference or dorm policy applies
+func eligibilityRoom(id string, suite uuid.UUID, size int, occupants ...models.UserRaw) models.RoomRaw {
+	room := models.RoomRaw{RoomUUID: uuid.New(), RoomID: id, SuiteUUID: suite, MaxOccupancy: size, CurrentOccupancy: len(occupants)}
+	if len(occupants) > 0 {
+		room.Occupants = []int{occupants[0].Id}
+		room.PullPriority = generateUserPriority(occupants[0], 0)
+		room.PullPriority.Valid = true
+		room.PullPriority.PullType = 1
+	}
+	return room
+}
+
+// snapshotOf builds the eligibility snapshot of one suite for group. The room
+// checked is always the first.
+func snapshotOf(suite models.SuiteRaw, group []models.UserRaw, rooms []models.RoomRaw, occupants map[uuid.UUID][]models.UserRaw) *eligibility {
+	return &eligibility{
+		group:        group,
+		rooms:        rooms,
+		roomsBySuite: map[uuid.UUID][]models.RoomRaw{suite.SuiteUUID: rooms},
+		suites:       map[uuid.UUID]models.SuiteRaw{suite.SuiteUUID: suite},
+		groupLeaders: map[uuid.UUID]models.PullPriority{},
+		roomUsers:    occupants,
+	}
+}
+
+func pullTypes(pulls []EligiblePull) []int {
+	var types []int
+	for _, p := range pulls {
+		types = append(types, p.PullType)
+	}
+	slices.Sort(types)
+	return types
+}
+
+func TestPullsInto(t *testing.T) {
+	suiteUUID := uuid.New()
+	senior := models.UserRaw{Id: 1, Year: "senior", DrawNumber: 10}
+	junior := models.UserRaw{Id: 2, Year: "junior", DrawNumber: 5}
+	sophomore := models.UserRaw{Id: 3, Year: "sophomore", DrawNumber: 50}
+	sophomore2 := models.UserRaw{Id: 4, Year: "sophomore", DrawNumber: 60}
+
+	tests := []struct {
+		name  string
+		suite models.SuiteRaw
+		group []models.UserRaw
+		rooms func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw)
+		want  []int
+	}{
+		{
+			name:  "an empty single can be self pulled or normal pulled by a better single",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID},
+			group: []models.UserRaw{junior},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				leader := eligibilityRoom("101", suiteUUID, 1, senior)
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 1), leader}, map[uuid.UUID][]models.UserRaw{leader.RoomUUID: {senior}}
+			},
+			want: []int{1, 2},
+		},
+		{
+			name:  "a leader who does not outrank the group cannot normal pull it",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID},
+			group: []models.UserRaw{senior},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				leader := eligibilityRoom("101", suiteUUID, 1, junior)
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 1), leader}, map[uuid.UUID][]models.UserRaw{leader.RoomUUID: {junior}}
+			},
+			want: []int{1},
+		},
+		{
+			name:  "a room held by a better pull cannot be taken",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID},
+			group: []models.UserRaw{sophomore},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 1, junior)}, nil
+			},
+		},
+		{
+			name:  "a lock pulled room cannot be bumped",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID},
+			group: []models.UserRaw{senior},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				room := eligibilityRoom("102", suiteUUID, 1, sophomore)
+				room.PullPriority.PullType = 3
+				return []models.RoomRaw{room}, nil
+			},
+		},
+		{
+			name:  "the last empty room of a full suite can be lock pulled",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID, CanLockPull: true},
+			group: []models.UserRaw{sophomore},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				full := eligibilityRoom("101", suiteUUID, 1, sophomore2)
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 1), full}, map[uuid.UUID][]models.UserRaw{full.RoomUUID: {sophomore2}}
+			},
+			want: []int{1, 3},
+		},
+		{
+			name:  "a room next to a full room can be alternative pulled",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID, AlternativePull: true},
+			group: []models.UserRaw{sophomore, sophomore2},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				full := eligibilityRoom("101", suiteUUID, 1, senior)
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 2), full}, map[uuid.UUID][]models.UserRaw{full.RoomUUID: {senior}}
+			},
+			want: []int{1, 4},
+		},
+		{
+			name:  "a leader already in a suite group cannot alternative pull",
+			suite: models.SuiteRaw{SuiteUUID: suiteUUID, AlternativePull: true},
+			group: []models.UserRaw{sophomore, sophomore2},
+			rooms: func() ([]models.RoomRaw, map[uuid.UUID][]models.UserRaw) {
+				full := eligibilityRoom("101", suiteUUID, 1, senior)
+				full.SGroupUUID = uuid.New()
+				return []models.RoomRaw{eligibilityRoom("102", suiteUUID, 2), full}, map[uuid.UUID][]models.UserRaw{full.RoomUUID: {senior}}
+			},
+			want: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rooms, occupants := tt.rooms()
+			e := snapshotOf(tt.suite, tt.group, rooms, occupants)
+			if got := pullTypes(e.pullsInto(rooms[0])); !slices.Equal(got, tt.want) {
+				t.Errorf("pullsInto() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
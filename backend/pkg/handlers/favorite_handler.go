@@ -0,0 +1,705 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// pullTypeNames are the pull types as they are written in alerts
+var pullTypeNames = map[int]string{
+	1: "self",
+	2: "normal",
+	3: "lock",
+	4: "alternative",
+}
+
+// Favorite is a room or suite a user saved, with their own notes and ranking
+type Favorite struct {
+	FavoriteID int        `json:"favoriteId"`
+	RoomUUID   *uuid.UUID `json:"roomUuid,omitempty"`
+	SuiteUUID  *uuid.UUID `json:"suiteUuid,omitempty"`
+	DormName   string     `json:"dormName"`
+	Floor      int        `json:"floor"`
+	RoomID     string     `json:"roomId,omitempty"`
+	Rank       int        `json:"rank"`
+	Notes      string     `json:"notes"`
+	Reachable  bool       `json:"reachable"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// FavoriteAlert tells a user that one of their favorites changed
+type FavoriteAlert struct {
+	AlertID    int        `json:"alertId"`
+	FavoriteID *int       `json:"favoriteId"`
+	Kind       string     `json:"kind"` // occupancy, priority or reachable
+	Message    string     `json:"message"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReadAt     *time.Time `json:"readAt"`
+}
+
+type favoriteRequest struct {
+	RoomUUID  *uuid.UUID `json:"roomUuid"`
+	SuiteUUID *uuid.UUID `json:"suiteUuid"`
+	Rank      *int       `json:"rank"`
+	Notes     *string    `json:"notes"`
+}
+
+// favoriteRoomState is what the alert check compares for each room a favorite covers
+type favoriteRoomState struct {
+	Occupancy int                 `json:"occupancy"`
+	Priority  models.PullPriority `json:"priority"`
+}
+
+// favoriteWatch is a favorite as the alert check sees it
+type favoriteWatch struct {
+	favoriteID int
+	userID     int
+	roomUUID   uuid.NullUUID
+	suiteUUID  uuid.NullUUID
+	lastState  map[uuid.UUID]favoriteRoomState
+	reachable  bool
+}
+
+// currentUserID looks up the signed in user by the email JWTAuthMiddleware put in the
+// context, and writes the error response if there is none
+func currentUserID(c *gin.Context) (int, bool) {
+	email, _ := c.Get("email")
+	userEmail, _ := email.(string)
+	if userEmail == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return 0, false
+	}
+
+	var userID int
+	err := database.DB.QueryRow("SELECT id FROM users WHERE email = $1", userEmail).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return 0, false
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// GetFavorites lists the signed in user's favorites in their ranking
+func GetFavorites(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	favorites, err := loadFavorites(userID, 0)
+	if err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, favorites)
+}
+
+// AddFavorite saves a room or a suite for the signed in user. It is added last in their
+// ranking unless a rank is given.
+func AddFavorite(c *gin.Context) {
+	var request favoriteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (request.RoomUUID == nil) == (request.SuiteUUID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of roomUuid and suiteUuid is required"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	watch := favoriteWatch{userID: userID}
+	var suiteUUID uuid.UUID
+	if request.RoomUUID != nil {
+		watch.roomUUID = uuid.NullUUID{UUID: *request.RoomUUID, Valid: true}
+		err = tx.QueryRow("SELECT suite_uuid FROM rooms WHERE room_uuid = $1", *request.RoomUUID).Scan(&suiteUUID)
+	} else {
+		watch.suiteUUID = uuid.NullUUID{UUID: *request.SuiteUUID, Valid: true}
+		err = tx.QueryRow("SELECT suite_uuid FROM suites WHERE suite_uuid = $1", *request.SuiteUUID).Scan(&suiteUUID)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room or suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+
+	rank := 0
+	if request.Rank != nil {
+		rank = *request.Rank
+	} else if err := tx.QueryRow("SELECT COALESCE(MAX(rank), 0) + 1 FROM user_favorites WHERE user_id = $1", userID).Scan(&rank); err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+	notes := ""
+	if request.Notes != nil {
+		notes = *request.Notes
+	}
+
+	// start from the current state so the user is only alerted about later changes
+	e, groups, err := loadFavoriteSnapshot(tx, models.IntArray{userID}, []uuid.UUID{suiteUUID})
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load the draw state", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the draw state"})
+		return
+	}
+	state, reachablePull := e.favoriteState(e.groupOf(groups[userID]), watch)
+	stateJSON, err := json.Marshal(state)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal favorite state"})
+		return
+	}
+
+	var favoriteID int
+	err = tx.QueryRow(`
+		INSERT INTO user_favorites (user_id, room_uuid, suite_uuid, rank, notes, last_state, reachable)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING
+		RETURNING favorite_id`,
+		userID, watch.roomUUID, watch.suiteUUID, rank, notes, stateJSON, reachablePull != nil,
+	).Scan(&favoriteID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a favorite"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to save favorite", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save favorite"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+
+	favorites, err := loadFavorites(userID, favoriteID)
+	if err != nil || len(favorites) == 0 {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+	c.JSON(http.StatusCreated, favorites[0])
+}
+
+// UpdateFavorite changes the notes or rank of one of the signed in user's favorites
+func UpdateFavorite(c *gin.Context) {
+	favoriteID, err := strconv.Atoi(c.Param("favoriteid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid favorite id"})
+		return
+	}
+
+	var request favoriteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.RoomUUID != nil || request.SuiteUUID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A favorite's room or suite cannot be changed"})
+		return
+	}
+	if request.Rank == nil && request.Notes == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE user_favorites
+		SET rank = COALESCE($1, rank), notes = COALESCE($2, notes), updated_at = NOW()
+		WHERE favorite_id = $3 AND user_id = $4`,
+		request.Rank, request.Notes, favoriteID, userID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to update favorite", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update favorite"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+		return
+	}
+
+	favorites, err := loadFavorites(userID, favoriteID)
+	if err != nil || len(favorites) == 0 {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+	c.JSON(http.StatusOK, favorites[0])
+}
+
+// DeleteFavorite removes one of the signed in user's favorites
+func DeleteFavorite(c *gin.Context) {
+	favoriteID, err := strconv.Atoi(c.Param("favoriteid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid favorite id"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM user_favorites WHERE favorite_id = $1 AND user_id = $2", favoriteID, userID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to delete favorite", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete favorite"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Favorite removed"})
+}
+
+// GetFavoriteAlerts lists the signed in user's alerts, newest first. ?unread=true leaves
+// out the ones already read.
+func GetFavoriteAlerts(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT alert_id, favorite_id, kind, message, created_at, read_at
+		FROM user_alerts
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, alert_id DESC
+		LIMIT $3`, userID, unreadOnly, limit)
+	if err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+	defer rows.Close()
+
+	alerts := []FavoriteAlert{}
+	for rows.Next() {
+		var a FavoriteAlert
+		if err := rows.Scan(&a.AlertID, &a.FavoriteID, &a.Kind, &a.Message, &a.CreatedAt, &a.ReadAt); err != nil {
+			logging.FromContext(c).Error("Database scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
+			return
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(c).Error("Database scan failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// MarkFavoriteAlertsRead marks the given alerts, or all of them if none are given, as read
+func MarkFavoriteAlertsRead(c *gin.Context) {
+	var request struct {
+		AlertIDs []int `json:"alertIds"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE user_alerts SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL AND (COALESCE(cardinality($2::int[]), 0) = 0 OR alert_id = ANY($2))`,
+		userID, pq.Array(request.AlertIDs))
+	if err != nil {
+		logging.FromContext(c).Error("Failed to mark alerts as read", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark alerts as read"})
+		return
+	}
+	updated, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// loadFavorites returns a user's favorites, or only favoriteID if it is not 0
+func loadFavorites(userID int, favoriteID int) ([]Favorite, error) {
+	rows, err := database.DB.Query(`
+		SELECT f.favorite_id, f.room_uuid, f.suite_uuid, s.dorm_name, s.floor, COALESCE(r.room_id, ''),
+			f.rank, f.notes, f.reachable, f.created_at, f.updated_at
+		FROM user_favorites f
+		LEFT JOIN rooms r ON r.room_uuid = f.room_uuid
+		JOIN suites s ON s.suite_uuid = COALESCE(f.suite_uuid, r.suite_uuid)
+		WHERE f.user_id = $1 AND ($2 = 0 OR f.favorite_id = $2)
+		ORDER BY f.rank, f.favorite_id`, userID, favoriteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := []Favorite{}
+	for rows.Next() {
+		var f Favorite
+		var roomUUID, suiteUUID uuid.NullUUID
+		if err := rows.Scan(&f.FavoriteID, &roomUUID, &suiteUUID, &f.DormName, &f.Floor, &f.RoomID,
+			&f.Rank, &f.Notes, &f.Reachable, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if roomUUID.Valid {
+			f.RoomUUID = &roomUUID.UUID
+		}
+		if suiteUUID.Valid {
+			f.SuiteUUID = &suiteUUID.UUID
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, rows.Err()
+}
+
+// FavoriteAlertMiddleware checks the favorites in every suite a successful write
+// logged a change to, and alerts their users in the background once the request is done
+func FavoriteAlertMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Status() >= 300 || isDryRun(c) {
+			return
+		}
+		value, _ := c.Get("request_id")
+		requestID, ok := value.(uuid.UUID)
+		if !ok {
+			return
+		}
+
+		pendingNotifications.Add(1)
+		go func() {
+			defer pendingNotifications.Done()
+			if err := alertFavorites(context.Background(), requestID); err != nil {
+				slog.Error("Failed to check favorites", "request_id", requestID, "error", err)
+			}
+		}()
+	}
+}
+
+// alertFavorites compares the favorites in every suite a request logged a change to
+// with what their users were last alerted about, raises an alert for each difference
+// and emails the users who opted in. Only those suites are read. A favorite another
+// request checked in the meantime is left to that request, so no alert is raised twice.
+func alertFavorites(ctx context.Context, requestID uuid.UUID) error {
+	tx, err := database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var suites []uuid.UUID
+	rows, err := tx.Query(`
+		SELECT r.suite_uuid FROM transaction_logs l JOIN rooms r ON r.room_uuid::text = l.entity_id
+		WHERE l.request_id = $1 AND l.entity_type = $2
+		UNION
+		SELECT s.suite_uuid FROM transaction_logs l JOIN suites s ON s.suite_uuid::text = l.entity_id
+		WHERE l.request_id = $1 AND l.entity_type = $3`, requestID, models.EntityTypeRoom, models.EntityTypeSuite)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var suiteUUID uuid.UUID
+		if err := rows.Scan(&suiteUUID); err != nil {
+			return err
+		}
+		suites = append(suites, suiteUUID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(suites) == 0 {
+		return nil
+	}
+
+	rows, err = tx.Query(`
+		SELECT f.favorite_id, f.user_id, f.room_uuid, f.suite_uuid, f.last_state, f.reachable
+		FROM user_favorites f
+		LEFT JOIN rooms fr ON fr.room_uuid = f.room_uuid
+		WHERE COALESCE(f.suite_uuid, fr.suite_uuid) = ANY($1)
+		ORDER BY f.favorite_id`, pq.Array(suites))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var watches []favoriteWatch
+	var lastStates [][]byte
+	var userIDs models.IntArray
+	for rows.Next() {
+		var w favoriteWatch
+		var lastState []byte
+		if err := rows.Scan(&w.favoriteID, &w.userID, &w.roomUUID, &w.suiteUUID, &lastState, &w.reachable); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(lastState, &w.lastState); err != nil {
+			return err
+		}
+		watches = append(watches, w)
+		lastStates = append(lastStates, lastState)
+		if !slices.Contains(userIDs, w.userID) {
+			userIDs = append(userIDs, w.userID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(watches) == 0 {
+		return nil
+	}
+
+	e, groups, err := loadFavoriteSnapshot(tx, userIDs, suites)
+	if err != nil {
+		return err
+	}
+
+	type sentAlert struct {
+		userID  int
+		message string
+	}
+	var sent []sentAlert
+
+	for i, w := range watches {
+		group := e.groupOf(groups[w.userID])
+		if len(group) == 0 {
+			continue
+		}
+		state, reachablePull := e.favoriteState(group, w)
+		label := e.favoriteLabel(w)
+
+		stateJSON, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		// only the request that moves the favorite on from what it read alerts about it
+		result, err := tx.Exec("UPDATE user_favorites SET last_state = $1, reachable = $2 WHERE favorite_id = $3 AND last_state = $4::jsonb AND reachable = $5",
+			stateJSON, reachablePull != nil, w.favoriteID, lastStates[i], w.reachable)
+		if err != nil {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+			continue
+		}
+
+		var alerts [][2]string
+		if len(w.lastState) > 0 {
+			before, after := 0, 0
+			priorityChanged := false
+			for roomUUID, now := range state {
+				was, ok := w.lastState[roomUUID]
+				if !ok {
+					continue
+				}
+				before += was.Occupancy
+				after += now.Occupancy
+				if was.Priority != now.Priority {
+					priorityChanged = true
+				}
+			}
+			if before != after {
+				alerts = append(alerts, [2]string{"occupancy", fmt.Sprintf("%s now has %d occupants (was %d).", label, after, before)})
+			} else if priorityChanged {
+				alerts = append(alerts, [2]string{"priority", fmt.Sprintf("The pull priority in %s changed.", label)})
+			}
+		}
+		if reachablePull != nil && !w.reachable {
+			alerts = append(alerts, [2]string{"reachable", fmt.Sprintf("%s is now reachable for you by a %s pull.", label, pullTypeNames[reachablePull.PullType])})
+		}
+
+		for _, alert := range alerts {
+			_, err = tx.Exec("INSERT INTO user_alerts (user_id, favorite_id, kind, message) VALUES ($1, $2, $3, $4)", w.userID, w.favoriteID, alert[0], alert[1])
+			if err != nil {
+				return err
+			}
+			sent = append(sent, sentAlert{w.userID, alert[1]})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, alert := range sent {
+		sendFavoriteAlert(alert.userID, alert.message)
+	}
+	return nil
+}
+
+// loadFavoriteSnapshot reads the suites favorites are checked in, with their users and
+// everyone those users would pull with: the accepted members of their draw group if
+// it has not pulled yet. groups holds each user's group, the user first.
+func loadFavoriteSnapshot(tx *sql.Tx, userIDs models.IntArray, suites []uuid.UUID) (*eligibility, map[int]models.IntArray, error) {
+	groups := make(map[int]models.IntArray, len(userIDs))
+	allIDs := slices.Clone(userIDs)
+	for _, id := range userIDs {
+		groups[id] = models.IntArray{id}
+	}
+
+	rows, err := tx.Query(`
+		SELECT m.user_id, mate.user_id
+		FROM draw_group_members m
+		JOIN draw_groups g ON g.group_id = m.group_id AND g.status <> $2
+		JOIN draw_group_members mate ON mate.group_id = m.group_id AND mate.status = $1 AND mate.user_id <> m.user_id
+		WHERE m.user_id = ANY($3) AND m.status = $1
+		ORDER BY m.user_id, mate.user_id`,
+		models.DrawGroupMemberAccepted, models.DrawGroupStatusPulled, pq.Array(userIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, mateID int
+		if err := rows.Scan(&userID, &mateID); err != nil {
+			return nil, nil, err
+		}
+		groups[userID] = append(groups[userID], mateID)
+		if !slices.Contains(allIDs, mateID) {
+			allIDs = append(allIDs, mateID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	e, err := loadEligibility(tx, allIDs, suites)
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, groups, nil
+}
+
+// groupOf returns the loaded users with ids in the order given, or nil if one of them
+// was not found
+func (e *eligibility) groupOf(ids models.IntArray) []models.UserRaw {
+	group := make([]models.UserRaw, 0, len(ids))
+	for _, id := range ids {
+		i := slices.IndexFunc(e.users, func(u models.UserRaw) bool { return u.Id == id })
+		if i < 0 {
+			return nil
+		}
+		group = append(group, e.users[i])
+	}
+	return group
+}
+
+// favoriteRooms returns the rooms a favorite covers
+func (e *eligibility) favoriteRooms(w favoriteWatch) []models.RoomRaw {
+	if w.suiteUUID.Valid {
+		return e.roomsBySuite[w.suiteUUID.UUID]
+	}
+	for _, room := range e.rooms {
+		if room.RoomUUID == w.roomUUID.UUID {
+			return []models.RoomRaw{room}
+		}
+	}
+	return nil
+}
+
+// favoriteState returns the occupancy and priority of every room a favorite covers, and
+// a pull that would win one of them for group, the user with their draw group, under
+// the same rules the pull handlers apply
+func (e *eligibility) favoriteState(group []models.UserRaw, w favoriteWatch) (map[uuid.UUID]favoriteRoomState, *EligiblePull) {
+	state := make(map[uuid.UUID]favoriteRoomState)
+	var reachablePull *EligiblePull
+
+	e.group = group
+	placed := slices.ContainsFunc(group, func(u models.UserRaw) bool { return u.Preplaced || u.RoomUUID != uuid.Nil })
+	for _, room := range e.favoriteRooms(w) {
+		state[room.RoomUUID] = favoriteRoomState{Occupancy: room.CurrentOccupancy, Priority: room.PullPriority}
+
+		if reachablePull != nil || placed {
+			continue
+		}
+		if pulls := e.pullsInto(room); len(pulls) > 0 {
+			reachablePull = &pulls[0]
+		}
+	}
+
+	return state, reachablePull
+}
+
+// favoriteLabel names a favorite in alerts, e.g. "Drinkward 123A" or "the Drinkward suite 221A/222A"
+func (e *eligibility) favoriteLabel(w favoriteWatch) string {
+	rooms := e.favoriteRooms(w)
+	if len(rooms) == 0 {
+		return "Your favorite"
+	}
+	if !w.suiteUUID.Valid {
+		return rooms[0].DormName + " " + rooms[0].RoomID
+	}
+
+	roomIDs := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.RoomID)
+	}
+	return fmt.Sprintf("The %s suite %s", rooms[0].DormName, strings.Join(roomIDs, "/"))
+}
+
+// sendFavoriteAlert emails an alert to a user who opted in to notifications
+func sendFavoriteAlert(userID int, alert string) {
+	notifyUser(userID, models.NotificationChannelFavorites, func(user models.UserRaw) error {
+		return emailService.SendFavoriteAlert(user, alert)
+	})
+}
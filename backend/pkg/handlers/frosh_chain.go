@@ -0,0 +1,471 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxFroshChainMoves is the longest chain of frosh bumps the solver looks for
+	maxFroshChainMoves = 4
+	// maxFroshChainStates bounds how many frosh layouts the solver visits
+	maxFroshChainStates = 20000
+	// maxFroshChainSearches bounds how many searches run at once
+	maxFroshChainSearches = 2
+)
+
+// errFroshChainBusy is returned when maxFroshChainSearches are already running
+var errFroshChainBusy = errors.New("too many frosh chain searches running")
+
+// errFroshChainRoomNotFound is never cached, so unknown rooms cannot fill the cache
+var errFroshChainRoomNotFound = froshRuleError("Room not found")
+
+// froshChainSearches holds a slot for each running search
+var froshChainSearches = make(chan struct{}, maxFroshChainSearches)
+
+// froshChainCache holds the last answer for each room together with the newest
+// transaction log it saw. Every write is logged, so the answer stays valid until
+// the next log entry.
+var froshChainCache = struct {
+	sync.Mutex
+	byRoom map[string]cachedFroshChain
+}{byRoom: make(map[string]cachedFroshChain)}
+
+type cachedFroshChain struct {
+	lastLogID int
+	chain     FroshChain
+	err       error
+}
+
+// FroshChainMove is one frosh bump in a chain
+type FroshChainMove struct {
+	FromRoomUUID  uuid.UUID `json:"fromRoomUuid"`
+	FromRoomID    string    `json:"fromRoomId"`
+	ToRoomUUID    uuid.UUID `json:"toRoomUuid"`
+	ToRoomID      string    `json:"toRoomId"`
+	FroshRoomType int       `json:"froshRoomType"`
+	// SuiteWide is set when the frosh of the whole suite move, as in the inner dorms
+	SuiteWide bool `json:"suiteWide"`
+}
+
+// FroshChain is a sequence of frosh bumps that ends with a room free of frosh and
+// every frosh in a legal room of its frosh room type
+type FroshChain struct {
+	RoomUUID uuid.UUID        `json:"roomUuid"`
+	RoomID   string           `json:"roomId"`
+	DormName string           `json:"dormName"`
+	Moves    []FroshChainMove `json:"moves"`
+}
+
+type froshChainRequest struct {
+	Moves []FroshChainMove `json:"moves" binding:"required"`
+}
+
+// froshLayout is which rooms of a dorm hold frosh, one entry per room
+type froshLayout []bool
+
+// froshChainNode is a layout the solver reached and the moves that led to it
+type froshChainNode struct {
+	layout froshLayout
+	moves  []FroshChainMove
+}
+
+// PreviewFroshChain finds the shortest chain of frosh bumps that frees a room
+// without changing anything
+func PreviewFroshChain(c *gin.Context) {
+	chain, err := previewFroshChain(c.Request.Context(), c.Param("roomuuid"))
+	if errors.Is(err, errFroshChainBusy) {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many frosh chain searches are running, please try again shortly"})
+		return
+	}
+	if err != nil {
+		if !errors.As(err, new(froshRuleError)) {
+			logging.FromContext(c).Error("Failed to find a chain of frosh bumps", "room", c.Param("roomuuid"), "error", err)
+		}
+		respondFroshError(c, err, "Failed to find a chain of frosh bumps")
+		return
+	}
+
+	c.JSON(http.StatusOK, chain)
+}
+
+// ExecuteFroshChain carries out a previewed chain of frosh bumps in one
+// transaction. The chain is solved again under the locks of the suites its moves
+// name, and if the draw changed so that the answer is no longer the reviewed chain
+// nothing is changed and the server answers with the new chain to review. Each
+// bump is then checked like a single bump.
+func ExecuteFroshChain(c *gin.Context) {
+	roomUUID, err := uuid.Parse(c.Param("roomuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room UUID"})
+		return
+	}
+
+	var request froshChainRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Moves) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the reviewed moves of the chain"})
+		return
+	}
+	if len(request.Moves) > maxFroshChainMoves {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A chain has at most %d frosh bumps", maxFroshChainMoves)})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	select {
+	case froshChainSearches <- struct{}{}:
+		defer func() { <-froshChainSearches }()
+	default:
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many frosh chain searches are running, please try again shortly"})
+		return
+	}
+
+	// only the chain the server would suggest is carried out, which keeps every move
+	// in the room's dorm and within the suites that were locked
+	setup, err := loadFroshChainSetup(tx, roomUUID.String())
+	if err == nil {
+		var chain FroshChain
+		chain, err = solveFroshChain(setup, roomUUID)
+		if err == nil && !sameFroshChain(chain.Moves, request.Moves) {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "The draw changed since the chain was reviewed", "chain": chain})
+			return
+		}
+	}
+	if errors.As(err, new(froshRuleError)) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "The chain can no longer be carried out", "chainError": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to find a chain of frosh bumps", "room", roomUUID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find a chain of frosh bumps"})
+		return
+	}
+
+	moves := make([]*froshMove, 0, len(request.Moves))
+	for i, m := range request.Moves {
+		move, err := executeFroshChainMove(tx, m)
+		var ruleErr froshRuleError
+		if errors.As(err, &ruleErr) {
+			tx.Rollback()
+			respondFroshChainConflict(c, roomUUID, fmt.Sprintf("Move %d of the chain is no longer allowed: %s", i+1, ruleErr))
+			return
+		}
+		if err != nil {
+			logging.FromContext(c).Error("Failed to bump frosh", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bump frosh"})
+			return
+		}
+		moves = append(moves, move)
+	}
+
+	var hasFrosh bool
+	if err := tx.QueryRow("SELECT has_frosh FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&hasFrosh); err != nil {
+		logging.FromContext(c).Error("Failed to get room from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+		return
+	}
+	if hasFrosh {
+		tx.Rollback()
+		respondFroshChainConflict(c, roomUUID, "The chain does not free the room of frosh")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	for i, move := range moves {
+		move.finish(c, map[string]interface{}{
+			"chain_room_uuid": roomUUID,
+			"chain_move":      i + 1,
+			"chain_length":    len(moves),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Frosh moved in %d bumps", len(moves)), "moves": request.Moves})
+}
+
+// executeFroshChainMove makes one bump of a chain in tx
+func executeFroshChainMove(tx *sql.Tx, m FroshChainMove) (*froshMove, error) {
+	from, err := loadFroshRoom(tx, m.FromRoomUUID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, froshRuleError("Room " + m.FromRoomID + " no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	target, err := loadFroshRoom(tx, m.ToRoomUUID.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, froshRuleError("Room " + m.ToRoomID + " no longer exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return moveFrosh(tx, from, target)
+}
+
+// respondFroshChainConflict answers a chain that can no longer be carried out with
+// a 409 and, if there is one, a new chain for the room
+func respondFroshChainConflict(c *gin.Context, roomUUID uuid.UUID, message string) {
+	chain, err := previewFroshChain(c.Request.Context(), roomUUID.String())
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": message, "chainError": err.Error()})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": message, "chain": chain})
+}
+
+// previewFroshChain solves for a room in a read-only transaction. Answers are
+// cached until the next write, and only maxFroshChainSearches searches run at
+// once, the rest get errFroshChainBusy.
+func previewFroshChain(ctx context.Context, roomUUID string) (FroshChain, error) {
+	id, err := uuid.Parse(roomUUID)
+	if err != nil {
+		return FroshChain{}, froshRuleError("Invalid room UUID")
+	}
+
+	tx, err := database.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return FroshChain{}, err
+	}
+	defer tx.Rollback()
+
+	// read in the same snapshot as the search, so the answer matches this log id
+	var lastLogID int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(log_id), 0) FROM transaction_logs").Scan(&lastLogID); err != nil {
+		return FroshChain{}, err
+	}
+
+	froshChainCache.Lock()
+	cached, ok := froshChainCache.byRoom[roomUUID]
+	froshChainCache.Unlock()
+	if ok && cached.lastLogID == lastLogID {
+		return cached.chain, cached.err
+	}
+
+	select {
+	case froshChainSearches <- struct{}{}:
+		defer func() { <-froshChainSearches }()
+	default:
+		return FroshChain{}, errFroshChainBusy
+	}
+
+	setup, err := loadFroshChainSetup(tx, roomUUID)
+	if err != nil {
+		return FroshChain{}, err
+	}
+	chain, err := solveFroshChain(setup, id)
+	if err == nil || (errors.As(err, new(froshRuleError)) && !errors.Is(err, errFroshChainRoomNotFound)) {
+		froshChainCache.Lock()
+		froshChainCache.byRoom[roomUUID] = cachedFroshChain{lastLogID: lastLogID, chain: chain, err: err}
+		froshChainCache.Unlock()
+	}
+	return chain, err
+}
+
+// froshChainSetup is what the solver searches: the rooms of a dorm, its frosh
+// policy and the suites of the dorm that have a reslife room
+type froshChainSetup struct {
+	policy        froshPolicy
+	rooms         []models.RoomRaw
+	reslifeSuites map[uuid.UUID]bool
+}
+
+// loadFroshChainSetup reads the dorm of a room for solveFroshChain
+func loadFroshChainSetup(tx *sql.Tx, roomUUID string) (froshChainSetup, error) {
+	var dormID int
+	err := tx.QueryRow("SELECT dorm FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&dormID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return froshChainSetup{}, errFroshChainRoomNotFound
+	}
+	if err != nil {
+		return froshChainSetup{}, err
+	}
+
+	setup := froshChainSetup{reslifeSuites: make(map[uuid.UUID]bool)}
+	if setup.policy, err = froshPolicyFor(dormID); err != nil {
+		return froshChainSetup{}, err
+	}
+	if setup.rooms, err = loadDormRooms(tx, dormID); err != nil {
+		return froshChainSetup{}, err
+	}
+
+	rows, err := tx.Query("SELECT suite_uuid FROM suites WHERE dorm = $1 AND reslife_room IS NOT NULL", dormID)
+	if err != nil {
+		return froshChainSetup{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var suiteUUID uuid.UUID
+		if err := rows.Scan(&suiteUUID); err != nil {
+			return froshChainSetup{}, err
+		}
+		setup.reslifeSuites[suiteUUID] = true
+	}
+	return setup, rows.Err()
+}
+
+// solveFroshChain searches breadth first for the fewest frosh bumps that free a
+// room, trying every legal bump from each layout the way BumpFroshHandler checks
+// it. In the inner dorms a bump moves a whole suite. The room and, in the inner
+// dorms, its suite are never a target, and frosh in a suite with a reslife room
+// only move within it.
+func solveFroshChain(setup froshChainSetup, roomUUID uuid.UUID) (FroshChain, error) {
+	policy, rooms, reslifeSuites := setup.policy, setup.rooms, setup.reslifeSuites
+
+	freed := -1
+	suites := make(map[uuid.UUID][]int)
+	byType := make(map[int][]int)
+	start := make(froshLayout, len(rooms))
+	for i, r := range rooms {
+		if r.RoomUUID == roomUUID {
+			freed = i
+		}
+		suites[r.SuiteUUID] = append(suites[r.SuiteUUID], i)
+		if r.FroshRoomType != 0 {
+			byType[r.FroshRoomType] = append(byType[r.FroshRoomType], i)
+		}
+		start[i] = r.HasFrosh
+	}
+	if freed < 0 {
+		return FroshChain{}, errFroshChainRoomNotFound
+	}
+
+	if !start[freed] {
+		return FroshChain{}, froshRuleError("Room does not have a frosh")
+	}
+	chain := FroshChain{RoomUUID: rooms[freed].RoomUUID, RoomID: rooms[freed].RoomID, DormName: rooms[freed].DormName}
+
+	// suiteRooms returns the rooms of a suite as they are in a layout
+	suiteRooms := func(layout froshLayout, suiteUUID uuid.UUID) []models.RoomRaw {
+		suite := make([]models.RoomRaw, 0, len(suites[suiteUUID]))
+		for _, i := range suites[suiteUUID] {
+			r := rooms[i]
+			r.HasFrosh = layout[i]
+			suite = append(suite, r)
+		}
+		return suite
+	}
+
+	seen := map[string]bool{string(start.key()): true}
+	queue := []froshChainNode{{layout: start}}
+	for len(queue) > 0 && len(seen) < maxFroshChainStates {
+		node := queue[0]
+		queue = queue[1:]
+		if len(node.moves) == maxFroshChainMoves {
+			continue
+		}
+
+		for i, from := range rooms {
+			if !node.layout[i] || from.FroshRoomType == 0 {
+				continue
+			}
+			from.HasFrosh = true
+
+			for _, j := range byType[from.FroshRoomType] {
+				target := rooms[j]
+				if node.layout[j] || j == freed {
+					continue
+				}
+				if reslifeSuites[from.SuiteUUID] && target.SuiteUUID != from.SuiteUUID {
+					continue
+				}
+				if policy.suiteWide() && (target.SuiteUUID == from.SuiteUUID || target.SuiteUUID == rooms[freed].SuiteUUID) {
+					continue
+				}
+				target.HasFrosh = false
+				if checkFroshPlacement(policy, target, suiteRooms(node.layout, target.SuiteUUID), &from) != nil {
+					continue
+				}
+
+				layout := node.layout.moved(policy, suites, from, target, i, j)
+				key := string(layout.key())
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				moves := append(append([]FroshChainMove(nil), node.moves...), FroshChainMove{
+					FromRoomUUID:  from.RoomUUID,
+					FromRoomID:    from.RoomID,
+					ToRoomUUID:    target.RoomUUID,
+					ToRoomID:      target.RoomID,
+					FroshRoomType: from.FroshRoomType,
+					SuiteWide:     policy.suiteWide(),
+				})
+				if !layout[freed] {
+					chain.Moves = moves
+					return chain, nil
+				}
+				queue = append(queue, froshChainNode{layout: layout, moves: moves})
+			}
+		}
+	}
+
+	return FroshChain{}, froshRuleError(fmt.Sprintf("No chain of up to %d frosh bumps frees room %s", maxFroshChainMoves, rooms[freed].RoomID))
+}
+
+// sameFroshChain reports whether two chains make the same bumps in the same order
+func sameFroshChain(a, b []FroshChainMove) bool {
+	return slices.EqualFunc(a, b, func(x, y FroshChainMove) bool {
+		return x.FromRoomUUID == y.FromRoomUUID && x.ToRoomUUID == y.ToRoomUUID
+	})
+}
+
+// moved returns the layout after the frosh in rooms[i] are bumped to rooms[j], a
+// whole suite at a time if the policy fills whole suites
+func (l froshLayout) moved(policy froshPolicy, suites map[uuid.UUID][]int, from, target models.RoomRaw, i, j int) froshLayout {
+	layout := append(froshLayout(nil), l...)
+	if !policy.suiteWide() {
+		layout[i] = false
+		layout[j] = true
+		return layout
+	}
+	for _, k := range suites[from.SuiteUUID] {
+		layout[k] = false
+	}
+	for _, k := range suites[target.SuiteUUID] {
+		layout[k] = true
+	}
+	return layout
+}
+
+// key identifies a layout so the solver visits it once
+func (l froshLayout) key() []byte {
+	key := make([]byte, len(l))
+	for i, hasFrosh := range l {
+		if hasFrosh {
+			key[i] = 1
+		}
+	}
+	return key
+}
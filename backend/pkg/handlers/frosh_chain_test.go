@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"roomdraw/backend/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// withFroshDorm registers dorm 1, the dorm of plannerRoom, as a dorm that takes
+// frosh for the length of a test
+func withFroshDorm(t *testing.T) {
+	dormRegistry.Lock()
+	previous := dormRegistry.byID
+	dormRegistry.byID = map[int]models.Dorm{1: {ID: 1, Name: "Test", Capabilities: []string{models.DormCapabilityFrosh}}}
+	dormRegistry.Unlock()
+
+	t.Cleanup(func() {
+		dormRegistry.Lock()
+		dormRegistry.byID = previous
+		dormRegistry.Unlock()
+	})
+}
+
+func chainMoveIDs(moves []FroshChainMove) []string {
+	ids := make([]string, 0, len(moves))
+	for _, m := range moves {
+		ids = append(ids, m.FromRoomID+">"+m.ToRoomID)
+	}
+	return ids
+}
+
+func TestSolveFroshChain(t *testing.T) {
+	withFroshDorm(t)
+	suiteA, suiteB, suiteC := uuid.New(), uuid.New(), uuid.New()
+
+	typed := func(room models.RoomRaw, froshRoomType int) models.RoomRaw {
+		room.FroshRoomType = froshRoomType
+		return room
+	}
+
+	tests := []struct {
+		name          string
+		policy        froshPolicy
+		rooms         []models.RoomRaw
+		reslifeSuites map[uuid.UUID]bool
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name:   "one per suite moves a frosh out of a suite so another can move in",
+			policy: onePerSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true),
+				typed(plannerRoom("201", suiteB, true), 2), plannerRoom("202", suiteB, false),
+				typed(plannerRoom("301", suiteC, false), 2),
+			},
+			want: []string{"201>301", "101>202"},
+		},
+		{
+			name:   "whole suite moves the suite to an empty suite other than its own",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("102", suiteA, true),
+				plannerRoom("201", suiteB, false, 5), plannerRoom("202", suiteB, false),
+				plannerRoom("301", suiteC, false), plannerRoom("302", suiteC, false),
+			},
+			want: []string{"101>301"},
+		},
+		{
+			name:   "whole suite finds no chain without an empty suite",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("102", suiteA, true),
+				plannerRoom("201", suiteB, false, 5), plannerRoom("202", suiteB, false),
+			},
+			wantErr: true,
+		},
+		{
+			name:   "frosh in a suite with a reslife room only move within it",
+			policy: roomFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("201", suiteB, false), plannerRoom("102", suiteA, false),
+			},
+			reslifeSuites: map[uuid.UUID]bool{suiteA: true},
+			want:          []string{"101>102"},
+		},
+		{
+			name:   "frosh in a full suite with a reslife room cannot move",
+			policy: roomFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("201", suiteB, false), plannerRoom("102", suiteA, false, 5),
+			},
+			reslifeSuites: map[uuid.UUID]bool{suiteA: true},
+			wantErr:       true,
+		},
+		{
+			name:    "a room without frosh has nothing to free",
+			policy:  roomFroshPolicy{},
+			rooms:   []models.RoomRaw{plannerRoom("101", suiteA, false), plannerRoom("201", suiteB, false)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup := froshChainSetup{policy: tt.policy, rooms: tt.rooms, reslifeSuites: tt.reslifeSuites}
+			chain, err := solveFroshChain(setup, tt.rooms[0].RoomUUID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("solveFroshChain() = %v, want an error", chainMoveIDs(chain.Moves))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("solveFroshChain() error = %v", err)
+			}
+			if got := chainMoveIDs(chain.Moves); !slices.Equal(got, tt.want) {
+				t.Errorf("solveFroshChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameFroshChain(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	chain := []FroshChainMove{{FromRoomUUID: a, ToRoomUUID: b}, {FromRoomUUID: c, ToRoomUUID: a}}
+
+	if !sameFroshChain(chain, slices.Clone(chain)) {
+		t.Error("a chain should match itself")
+	}
+	if sameFroshChain(chain, []FroshChainMove{chain[1], chain[0]}) {
+		t.Error("the same moves in another order should not match")
+	}
+	if sameFroshChain(chain, chain[:1]) {
+		t.Error("a shorter chain should not match")
+	}
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"database/sql"
-	"errors"
-	"log"
+	"fmt"
 	"net/http"
 	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
 	"roomdraw/backend/pkg/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,80 +19,70 @@ func AddFroshHandler(c *gin.Context) {
 	roomUUID := c.Param("roomuuid")
 
 	// start the transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
-
-	// ensure the transaction is either committed or rolled back
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if err != nil {
-			tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
 
 	// get the room from the database
-	var room models.RoomRaw
-	err = tx.QueryRow("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&room.RoomUUID, &room.Dorm, &room.DormName, &room.RoomID, &room.SuiteUUID, &room.MaxOccupancy, &room.CurrentOccupancy, &room.Occupants, &room.PullPriority, &room.SGroupUUID, &room.HasFrosh, &room.FroshRoomType)
+	room, err := loadFroshRoom(tx, roomUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
 		return
 	}
 
-	// ensure that frosh_room_type is not 0
-	if room.FroshRoomType == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is not a frosh room"})
+	policy, err := froshPolicyFor(room.Dorm)
+	if err != nil {
+		respondFroshError(c, err, "Failed to get frosh policy")
 		return
 	}
 
-	// make sure room is empty
-	if room.CurrentOccupancy != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is not empty"})
+	suite, err := loadSuiteRooms(tx, room.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check other rooms in the suite"})
 		return
 	}
 
-	// add the frosh to the room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", roomUUID)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add frosh to room"})
+	// the same rules apply as when a frosh is bumped into the room
+	if err := checkFroshPlacement(policy, room, suite, nil); err != nil {
+		respondFroshError(c, err, "Failed to check frosh placement")
 		return
 	}
 
-	// if room.Dorm between 1 and 4
-	if room.Dorm >= 1 && room.Dorm <= 4 {
-		// check that all the other rooms in the suite are empty
-		var count int
-		err = tx.QueryRow("SELECT COUNT(*) FROM rooms WHERE suite_uuid = $1 AND room_uuid != $2 AND current_occupancy != 0", room.SuiteUUID, roomUUID).Scan(&count)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check other rooms in the suite"})
-			return
-		}
+	rooms := froshChangedRooms(policy, room, suite)
+	previousStates := froshRoomStates(tx, "ADD_FROSH", rooms)
 
-		if count > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Other rooms in the suite are not empty"})
-			return
-		}
-
-		// add frosh to all the rooms with the same suite_uuid
-		_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE suite_uuid = $1", room.SuiteUUID)
+	// add the frosh to the room, or to its whole suite in the inner dorms
+	if err := setFrosh(tx, policy, room, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add frosh to room"})
+		return
+	}
+	if policy.suiteWide() {
+		logging.FromContext(c).Info("Frosh added to all rooms in suite because frosh fill whole suites in this dorm")
+	}
 
-		log.Println("Frosh added to all rooms in suite because frosh was placed in inner dorm")
+	suitemates, err := froshSuitemates(tx, room.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suitemates"})
+		return
 	}
 
 	// commit the transaction
-	err = tx.Commit()
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
+	logFroshChange(c, "ADD_FROSH", room.RoomUUID, rooms, previousStates, map[string]interface{}{
+		"room_id":    room.RoomID,
+		"suite_uuid": room.SuiteUUID,
+		"suitemates": suitemates,
+	})
+	notifyFroshSuitemates(suitemates, fmt.Sprintf("Frosh have been placed in %s %s, in your suite.", room.DormName, room.RoomID))
+
 	c.JSON(http.StatusOK, gin.H{"message": "Frosh added to room"})
 }
 
@@ -99,46 +91,48 @@ func RemoveFroshHandler(c *gin.Context) { // should be a secured route
 	roomUUID := c.Param("roomuuid")
 
 	// start the transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
-
-	// ensure the transaction is either committed or rolled back
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if err != nil {
-			tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
 
 	// get the room from the database
-	var room models.RoomRaw
-	err = tx.QueryRow("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&room.RoomUUID, &room.Dorm, &room.DormName, &room.RoomID, &room.SuiteUUID, &room.MaxOccupancy, &room.CurrentOccupancy, &room.Occupants, &room.PullPriority, &room.SGroupUUID, &room.HasFrosh, &room.FroshRoomType)
+	room, err := loadFroshRoom(tx, roomUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
 		return
 	}
 
-	// remove the frosh from the room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", roomUUID)
+	if !room.HasFrosh {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room does not have a frosh"})
+		return
+	}
 
+	policy, err := froshPolicyFor(room.Dorm)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove frosh from room"})
+		respondFroshError(c, err, "Failed to get frosh policy")
+		return
+	}
+
+	suite, err := loadSuiteRooms(tx, room.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get other rooms in the suite"})
 		return
 	}
+	rooms := froshChangedRooms(policy, room, suite)
+	previousStates := froshRoomStates(tx, "REMOVE_FROSH", rooms)
 
-	// if room.Dorm between 1 and 4
-	if room.Dorm >= 1 && room.Dorm <= 4 {
-		// remove frosh from all the rooms with the same suite_uuid
-		_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE suite_uuid = $1", room.SuiteUUID)
+	// remove the frosh from the room, or from its whole suite in the inner dorms
+	if err := setFrosh(tx, policy, room, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove frosh from room"})
+		return
+	}
 
-		log.Println("Frosh removed from suite because frosh was removed from inner dorm")
+	if policy.suiteWide() {
+		logging.FromContext(c).Info("Frosh removed from suite because frosh fill whole suites in this dorm")
 	} else {
 		err = RemoveLockPull(room.RoomUUID, tx) // runs buggy if you remove a suite of frosh
 		if err != nil {
@@ -147,13 +141,25 @@ func RemoveFroshHandler(c *gin.Context) { // should be a secured route
 		}
 	}
 
-	// commit the transaction
-	err = tx.Commit()
+	suitemates, err := froshSuitemates(tx, room.SuiteUUID)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suitemates"})
+		return
+	}
+
+	// commit the transaction
+	if err := tx.Commit(); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
+	logFroshChange(c, "REMOVE_FROSH", room.RoomUUID, rooms, previousStates, map[string]interface{}{
+		"room_id":    room.RoomID,
+		"suite_uuid": room.SuiteUUID,
+		"suitemates": suitemates,
+	})
+	notifyFroshSuitemates(suitemates, fmt.Sprintf("Frosh are no longer placed in %s %s, in your suite.", room.DormName, room.RoomID))
+
 	c.JSON(http.StatusOK, gin.H{"message": "Frosh removed from room"})
 }
 
@@ -167,265 +173,140 @@ func BumpFroshHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// start the transaction
 
-	tx, err := database.DB.Begin()
+	// start the transaction
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
 
-	// ensure the transaction is either committed or rolled back
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		} else if err != nil {
-			tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
-
-	var originalRoom models.RoomRaw
-	err = tx.QueryRow("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&originalRoom.RoomUUID, &originalRoom.Dorm, &originalRoom.DormName, &originalRoom.RoomID, &originalRoom.SuiteUUID, &originalRoom.MaxOccupancy, &originalRoom.CurrentOccupancy, &originalRoom.Occupants, &originalRoom.PullPriority, &originalRoom.SGroupUUID, &originalRoom.HasFrosh, &originalRoom.FroshRoomType)
+	originalRoom, err := loadFroshRoom(tx, roomUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
 		return
 	}
 
-	if !originalRoom.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room does not have a frosh"})
-		return
-	}
-
 	// get the target room info
-	var targetRoom models.RoomRaw
-	err = tx.QueryRow("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms WHERE room_uuid = $1", bumpFroshReq.TargetRoomUUID).Scan(&targetRoom.RoomUUID, &targetRoom.Dorm, &targetRoom.DormName, &targetRoom.RoomID, &targetRoom.SuiteUUID, &targetRoom.MaxOccupancy, &targetRoom.CurrentOccupancy, &targetRoom.Occupants, &targetRoom.PullPriority, &targetRoom.SGroupUUID, &targetRoom.HasFrosh, &targetRoom.FroshRoomType)
+	targetRoom, err := loadFroshRoom(tx, bumpFroshReq.TargetRoomUUID.String())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get target room from database"})
 		return
 	}
 
-	// check that the reslife_room column in the suite table is null meaning a frosh is not being bumped out of a reslife suite
-	var reslifeRoomUUID uuid.NullUUID
-	err = tx.QueryRow("SELECT reslife_room FROM suites WHERE suite_uuid = $1", originalRoom.SuiteUUID).Scan(&reslifeRoomUUID)
+	move, err := moveFrosh(tx, originalRoom, targetRoom)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suite from database"})
-		log.Println(err)
-		return
-	}
-
-	if reslifeRoomUUID.Valid && targetRoom.SuiteUUID != originalRoom.SuiteUUID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Frosh is in a reslife suite and cannot be bumped out of that suite"})
+		logging.FromContext(c).Error("Failed to bump frosh", "error", err)
+		respondFroshError(c, err, "Failed to bump frosh")
 		return
 	}
 
-	// verify that the target room is in the same dorm
-	if targetRoom.Dorm != originalRoom.Dorm {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Target room is not in the same dorm as the original room"})
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
 		return
 	}
 
-	if targetRoom.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Target room already has a frosh"})
-		return
-	}
-
-	if targetRoom.FroshRoomType != originalRoom.FroshRoomType {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Target room is not the same type as the original room"})
-		return
-	}
-
-	if originalRoom.Dorm >= 1 && originalRoom.Dorm <= 4 {
-		err = BumpFroshInnerDormHelper(tx, originalRoom, targetRoom)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		switch originalRoom.Dorm {
-		case 5:
-			err = BumpFroshAtwoodHelper(tx, originalRoom, targetRoom)
-		case 6:
-			err = BumpFroshSontagHelper(tx, originalRoom, targetRoom)
-		case 7:
-			err = BumpFroshCaseHelper(tx, originalRoom, targetRoom)
-		case 8:
-			err = BumpFroshDrinkwardHelper(tx, originalRoom, targetRoom)
-		case 9:
-			err = BumpFroshLindeHelper(tx, originalRoom, targetRoom)
-		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm"})
-			err = errors.New("invalid dorm")
-		}
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-	}
-
-	err = RemoveLockPull(originalRoom.RoomUUID, tx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove lock pull"})
-		return
-	}
+	move.finish(c, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Frosh bumped from room" + originalRoom.RoomID + " to room " + targetRoom.RoomID})
 }
 
-func BumpFroshAtwoodHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-	// in atwood, just verify that the target room is empty
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
-	}
-
-	// set the has_frosh field to false for the original room and true for the target room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", originalRoom.RoomUUID)
-
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", targetRoom.RoomUUID)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+// froshMove is a frosh bump made in a transaction, logged and announced once it commits
+type froshMove struct {
+	from, target   models.RoomRaw
+	rooms          []uuid.UUID
+	previousStates []*models.RoomRaw
+	// students in the suite the frosh leave and in the suite they move into
+	sourceSuitemates []int
+	targetSuitemates []int
 }
 
-func BumpFroshSontagHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-	// in sontag, just verify that the target room is empty
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
+// moveFrosh bumps the frosh in from to target, a whole suite at a time in the
+// inner dorms. A move the rules do not allow returns a froshRuleError.
+func moveFrosh(tx *sql.Tx, from, target models.RoomRaw) (*froshMove, error) {
+	if !from.HasFrosh {
+		return nil, froshRuleError("Room does not have a frosh")
 	}
 
-	// set the has_frosh field to false for the original room and true for the target room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", originalRoom.RoomUUID)
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", targetRoom.RoomUUID)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func BumpFroshLindeHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-	// in linde, verify that the target room is empty and also that the target suite doesn't already have frosh
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
-	}
-
-	var count int
-	err = tx.QueryRow("SELECT COUNT(*) FROM rooms WHERE suite_uuid = $1 AND has_frosh = true", targetRoom.SuiteUUID).Scan(&count)
+	// check that the reslife_room column in the suite table is null meaning a frosh is not being bumped out of a reslife suite
+	var reslifeRoomUUID uuid.NullUUID
+	err := tx.QueryRow("SELECT reslife_room FROM suites WHERE suite_uuid = $1", from.SuiteUUID).Scan(&reslifeRoomUUID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to get suite %s: %w", from.SuiteUUID, err)
 	}
 
-	if count > 0 {
-		if originalRoom.SuiteUUID != targetRoom.SuiteUUID {
-			return errors.New("target suite already has frosh")
-		} else {
-			if count > 1 {
-				return errors.New("target suite already has more than one frosh room")
-			}
-		}
+	if reslifeRoomUUID.Valid && target.SuiteUUID != from.SuiteUUID {
+		return nil, froshRuleError("Frosh is in a reslife suite and cannot be bumped out of that suite")
 	}
 
-	// set the has_frosh field to false for the original room and true for the target room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", originalRoom.RoomUUID)
+	policy, err := froshPolicyFor(from.Dorm)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", targetRoom.RoomUUID)
+	targetSuite, err := loadSuiteRooms(tx, target.SuiteUUID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to check other rooms in the target suite: %w", err)
 	}
 
-	return nil
-}
-
-func BumpFroshCaseHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-	// in case, just verify that the target room is empty
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
+	if err := checkFroshPlacement(policy, target, targetSuite, &from); err != nil {
+		return nil, err
 	}
 
-	// set the has_frosh field to false for the original room and true for the target room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", originalRoom.RoomUUID)
+	originalSuite, err := loadSuiteRooms(tx, from.SuiteUUID)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to get other rooms in the original suite: %w", err)
 	}
 
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", targetRoom.RoomUUID)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
+	move := &froshMove{from: from, target: target}
+	move.rooms = append(froshChangedRooms(policy, from, originalSuite), froshChangedRooms(policy, target, targetSuite)...)
+	move.previousStates = froshRoomStates(tx, "BUMP_FROSH", move.rooms)
 
-func BumpFroshDrinkwardHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-	// in case, just verify that the target room is empty
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
+	// move the frosh, a whole suite at a time in the inner dorms
+	if err := setFrosh(tx, policy, from, false); err != nil {
+		return nil, fmt.Errorf("failed to remove frosh from room %s: %w", from.RoomUUID, err)
 	}
-
-	// set the has_frosh field to false for the original room and true for the target room
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE room_uuid = $1", originalRoom.RoomUUID)
-	if err != nil {
-		return err
+	if err := setFrosh(tx, policy, target, true); err != nil {
+		return nil, fmt.Errorf("failed to add frosh to target room %s: %w", target.RoomUUID, err)
 	}
 
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE room_uuid = $1", targetRoom.RoomUUID)
-	if err != nil {
-		return err
+	if err := RemoveLockPull(from.RoomUUID, tx); err != nil {
+		return nil, fmt.Errorf("failed to remove lock pull: %w", err)
 	}
 
-	return nil
-}
-
-func BumpFroshInnerDormHelper(tx *sql.Tx, originalRoom models.RoomRaw, targetRoom models.RoomRaw) error {
-	var err error
-
-	if targetRoom.CurrentOccupancy != 0 {
-		return errors.New("target room is not empty")
-	}
-
-	// in the inner dorms, the entire suite of frosh must be moved
-	// check that all the other rooms in the target suite are empty
-	var count int
-	err = tx.QueryRow("SELECT COUNT(*) FROM rooms WHERE suite_uuid = $1 AND room_uuid != $2 AND current_occupancy != 0", targetRoom.SuiteUUID, targetRoom.RoomUUID).Scan(&count)
+	move.sourceSuitemates, err = froshSuitemates(tx, from.SuiteUUID)
 	if err != nil {
-		return err
-	}
-
-	if count > 0 {
-		return errors.New("other rooms in the target suite are not empty")
+		return nil, fmt.Errorf("failed to get suitemates: %w", err)
 	}
-
-	// set the has_frosh field to false for all rooms in the original suite and true for all rooms in the target suite
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = false WHERE suite_uuid = $1", originalRoom.SuiteUUID)
-	if err != nil {
-		return err
+	if target.SuiteUUID != from.SuiteUUID {
+		move.targetSuitemates, err = froshSuitemates(tx, target.SuiteUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get suitemates: %w", err)
+		}
 	}
 
-	_, err = tx.Exec("UPDATE rooms SET has_frosh = true WHERE suite_uuid = $1", targetRoom.SuiteUUID)
-	if err != nil {
-		return err
-	}
+	return move, nil
+}
 
-	return nil
+// finish logs a committed move and notifies the students in both suites. details
+// are added to the transaction log.
+func (m *froshMove) finish(c *gin.Context, details map[string]interface{}) {
+	logDetails := map[string]interface{}{
+		"room_id":           m.from.RoomID,
+		"suite_uuid":        m.from.SuiteUUID,
+		"target_room_uuid":  m.target.RoomUUID,
+		"target_room_id":    m.target.RoomID,
+		"target_suite_uuid": m.target.SuiteUUID,
+		"source_suitemates": m.sourceSuitemates,
+		"target_suitemates": m.targetSuitemates,
+	}
+	for k, v := range details {
+		logDetails[k] = v
+	}
+	logFroshChange(c, "BUMP_FROSH", m.from.RoomUUID, m.rooms, m.previousStates, logDetails)
+
+	notifyFroshSuitemates(m.sourceSuitemates, fmt.Sprintf("The frosh in %s %s, in your suite, have moved to %s.", m.from.DormName, m.from.RoomID, m.target.RoomID))
+	notifyFroshSuitemates(m.targetSuitemates, fmt.Sprintf("Frosh have moved from %s %s into %s, in your suite.", m.from.DormName, m.from.RoomID, m.target.RoomID))
 }
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// froshOperations are the transaction log operations that change where frosh live
+var froshOperations = []string{"ADD_FROSH", "REMOVE_FROSH", "BUMP_FROSH", "APPLY_FROSH_PLAN"}
+
+// froshChangedRooms returns the rooms a frosh change in room touches: the room
+// itself, or every room of its suite if the policy fills whole suites
+func froshChangedRooms(policy froshPolicy, room models.RoomRaw, suite []models.RoomRaw) []uuid.UUID {
+	if !policy.suiteWide() {
+		return []uuid.UUID{room.RoomUUID}
+	}
+	rooms := make([]uuid.UUID, 0, len(suite))
+	for _, r := range suite {
+		rooms = append(rooms, r.RoomUUID)
+	}
+	return rooms
+}
+
+// froshRoomStates reads the state of rooms for a frosh change's transaction log,
+// through the change's transaction before it commits and the database after
+func froshRoomStates(q drawGroupQuerier, operation string, roomUUIDs []uuid.UUID) []*models.RoomRaw {
+	states := make([]*models.RoomRaw, 0, len(roomUUIDs))
+	for _, roomUUID := range roomUUIDs {
+		var room models.RoomRaw
+		err := q.QueryRow(`
+            SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy,
+                   current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type
+            FROM rooms
+            WHERE room_uuid = $1`, roomUUID).Scan(
+			&room.RoomUUID, &room.Dorm, &room.DormName, &room.RoomID, &room.SuiteUUID,
+			&room.MaxOccupancy, &room.CurrentOccupancy, &room.Occupants, &room.PullPriority,
+			&room.SGroupUUID, &room.HasFrosh, &room.FroshRoomType,
+		)
+		if err != nil {
+			slog.Error("Error fetching room state for "+operation, "room", roomUUID, "error", err)
+			states = append(states, nil)
+			continue
+		}
+		states = append(states, &room)
+	}
+	return states
+}
+
+// froshSuitemates returns the students living in any of the suites
+func froshSuitemates(tx *sql.Tx, suiteUUIDs ...uuid.UUID) ([]int, error) {
+	ids := make([]string, len(suiteUUIDs))
+	for i, suiteUUID := range suiteUUIDs {
+		ids[i] = suiteUUID.String()
+	}
+
+	rows, err := tx.Query("SELECT DISTINCT unnest(occupants) FROM rooms WHERE suite_uuid = ANY($1::uuid[])", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// notifyFroshSuitemates emails each student about a frosh change in their suite
+func notifyFroshSuitemates(userIDs []int, change string) {
+	for _, userID := range userIDs {
+		SendFroshNotificationAsync(userID, change)
+	}
+}
+
+// logFroshChange records a committed frosh change with the state of every room it
+// touched before and after
+func logFroshChange(c *gin.Context, operation string, roomUUID uuid.UUID, rooms []uuid.UUID, previous []*models.RoomRaw, details map[string]interface{}) {
+	logging.FromContext(c).Info("Committed "+operation, "room", roomUUID, "rooms", len(rooms))
+
+	if err := logging.LogOperation(c, operation, models.EntityTypeRoom, roomUUID.String(), previous, froshRoomStates(database.DB, operation, rooms), details); err != nil {
+		logging.FromContext(c).Warn("Failed to log "+operation+" operation", "room", roomUUID, "error", err)
+	}
+}
+
+// GetFroshHistory returns every logged frosh add, remove, bump and applied plan,
+// newest first. ?dorm= and ?room= narrow it to a dorm or a room (as either end of
+// a bump), ?since= (RFC 3339 or YYYY-MM-DD) to recent changes, and ?limit= and
+// ?offset= page through it.
+func GetFroshHistory(c *gin.Context) {
+	dorm, err := strconv.Atoi(c.DefaultQuery("dorm", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm"})
+		return
+	}
+
+	room := c.Query("room")
+	if room != "" {
+		if _, err := uuid.Parse(room); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room UUID"})
+			return
+		}
+	}
+
+	var since time.Time
+	if sinceParam := c.Query("since"); sinceParam != "" {
+		since, err = time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			since, err = time.Parse("2006-01-02", sinceParam)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time or a YYYY-MM-DD date"})
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "200"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
+        SELECT to_jsonb(l) || jsonb_build_object('room_id', r.room_id, 'dorm', r.dorm, 'dorm_name', r.dorm_name)
+        FROM transaction_logs l
+        LEFT JOIN rooms r ON r.room_uuid::text = l.entity_id
+        WHERE l.operation_type = ANY($1)
+          AND ($2 = 0 OR r.dorm = $2)
+          AND ($3 = '' OR l.entity_id = $3 OR l.details->>'target_room_uuid' = $3)
+          AND l.created_at >= $4
+        ORDER BY l.created_at DESC, l.log_id DESC
+        LIMIT $5 OFFSET $6`, pq.Array(froshOperations), dorm, room, since, limit, offset)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying frosh history", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve frosh history"})
+		return
+	}
+	defer rows.Close()
+
+	history := []json.RawMessage{}
+	for rows.Next() {
+		var entry json.RawMessage
+		if err := rows.Scan(&entry); err != nil {
+			logging.FromContext(c).Error("Error scanning frosh history", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan frosh history"})
+			return
+		}
+		history = append(history, entry)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FroshTarget is how many frosh rooms of one frosh_room_type a dorm should have
+type FroshTarget struct {
+	Dorm          int `json:"dorm"`
+	FroshRoomType int `json:"froshRoomType"`
+	Count         int `json:"count"`
+}
+
+// FroshPlanRoom is a room that gains or loses frosh under a plan
+type FroshPlanRoom struct {
+	RoomUUID      uuid.UUID `json:"roomUuid"`
+	RoomID        string    `json:"roomId"`
+	Dorm          int       `json:"dorm"`
+	DormName      string    `json:"dormName"`
+	SuiteUUID     uuid.UUID `json:"suiteUuid"`
+	FroshRoomType int       `json:"froshRoomType"`
+	// Displaced are the students who would have to leave the room
+	Displaced []int `json:"displaced"`
+}
+
+// FroshPlanCount compares a dorm's frosh rooms of one type now, as asked for and under the plan
+type FroshPlanCount struct {
+	Dorm          int `json:"dorm"`
+	FroshRoomType int `json:"froshRoomType"`
+	Current       int `json:"current"`
+	Target        int `json:"target"`
+	Planned       int `json:"planned"`
+}
+
+// FroshPlan is the diff between the frosh rooms placed now and a set of targets
+type FroshPlan struct {
+	Add       []FroshPlanRoom  `json:"add"`
+	Remove    []FroshPlanRoom  `json:"remove"`
+	Displaced []int            `json:"displaced"`
+	Counts    []FroshPlanCount `json:"counts"`
+	// Unmet lists the targets the plan could not reach without breaking a frosh policy
+	Unmet []string `json:"unmet"`
+}
+
+type froshPlanRequest struct {
+	Targets []FroshTarget `json:"targets" binding:"required"`
+	// Plan is the plan the admin reviewed. Apply refuses if the draw changed since.
+	Plan *FroshPlan `json:"plan"`
+}
+
+// froshUnit is a set of rooms that gain or lose frosh together: one room, or a
+// whole suite in dorms where frosh fill whole suites
+type froshUnit struct {
+	rooms []models.RoomRaw
+}
+
+func (u froshUnit) contribution() map[int]int {
+	counts := make(map[int]int)
+	for _, r := range u.rooms {
+		if r.FroshRoomType != 0 {
+			counts[r.FroshRoomType]++
+		}
+	}
+	return counts
+}
+
+func (u froshUnit) displaced() []int {
+	var users []int
+	for _, r := range u.rooms {
+		users = append(users, r.Occupants...)
+	}
+	return users
+}
+
+func (u froshUnit) preplaced() bool {
+	for _, r := range u.rooms {
+		if r.PullPriority.IsPreplaced {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanFrosh proposes which rooms should gain or lose frosh to reach the target
+// counts, following each dorm's frosh policy and displacing as few placed students
+// as it can. Nothing is changed; review the plan and send it to ApplyFroshPlan.
+func PlanFrosh(c *gin.Context) {
+	var request froshPlanRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := database.DB.BeginTx(c.Request.Context(), &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer tx.Rollback()
+
+	plan, err := makeFroshPlan(tx, request.Targets)
+	if err != nil {
+		respondFroshError(c, err, "Failed to plan frosh rooms")
+		if !errors.As(err, new(froshRuleError)) {
+			logging.FromContext(c).Error("Failed to plan frosh rooms", "error", err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// ApplyFroshPlan carries out a reviewed frosh plan in one transaction. The plan is
+// made again first and must match the one sent, so what is applied is what was reviewed.
+func ApplyFroshPlan(c *gin.Context) {
+	var request froshPlanRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Plan == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the reviewed plan along with the targets"})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	plan, err := makeFroshPlan(tx, request.Targets)
+	if err != nil {
+		respondFroshError(c, err, "Failed to plan frosh rooms")
+		if !errors.As(err, new(froshRuleError)) {
+			logging.FromContext(c).Error("Failed to plan frosh rooms", "error", err)
+		}
+		return
+	}
+
+	if !sameFroshRooms(plan.Add, request.Plan.Add) || !sameFroshRooms(plan.Remove, request.Plan.Remove) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The draw changed since the plan was made, review the new plan", "plan": plan})
+		return
+	}
+
+	changed := make(map[uuid.UUID]bool)
+	for _, r := range plan.Remove {
+		changed[r.RoomUUID] = true
+	}
+	for _, r := range plan.Add {
+		changed[r.RoomUUID] = true
+	}
+	previousStates := make(map[uuid.UUID]*models.RoomRaw)
+	for roomUUID := range changed {
+		state, err := getRoomStateRaw(roomUUID.String())
+		if err != nil {
+			logging.FromContext(c).Error("Error fetching room state for APPLY_FROSH_PLAN", "room", roomUUID, "error", err)
+		}
+		previousStates[roomUUID] = state
+	}
+
+	notificationQueue := models.NewBumpNotificationQueue()
+	email := c.GetString("email")
+	suites := make(map[uuid.UUID]bool)
+
+	for _, r := range plan.Remove {
+		room, err := loadFroshRoom(tx, r.RoomUUID.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+			return
+		}
+		policy, err := froshPolicyFor(room.Dorm)
+		if err != nil {
+			respondFroshError(c, err, "Failed to get frosh policy")
+			return
+		}
+		if err := setFrosh(tx, policy, room, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove frosh from room"})
+			return
+		}
+		if !policy.suiteWide() {
+			if err := RemoveLockPull(room.RoomUUID, tx); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove lock pull"})
+				return
+			}
+		}
+	}
+
+	for _, r := range plan.Add {
+		if len(r.Displaced) > 0 {
+			if err := clearRoom(r.RoomUUID, tx, notificationQueue, email); err != nil {
+				logging.FromContext(c).Error("Failed to clear room", "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear room " + r.RoomID})
+				return
+			}
+			suites[r.SuiteUUID] = true
+		}
+	}
+
+	for _, r := range plan.Add {
+		room, err := loadFroshRoom(tx, r.RoomUUID.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+			return
+		}
+		if room.HasFrosh || room.FroshRoomType == 0 {
+			continue // another room of its suite brings frosh to the whole suite
+		}
+		policy, err := froshPolicyFor(room.Dorm)
+		if err != nil {
+			respondFroshError(c, err, "Failed to get frosh policy")
+			return
+		}
+		suite, err := loadSuiteRooms(tx, room.SuiteUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check other rooms in the suite"})
+			return
+		}
+		// the same rules as adding one frosh room by hand
+		if err := checkFroshPlacement(policy, room, suite, nil); err != nil {
+			respondFroshError(c, err, "Failed to check frosh placement")
+			return
+		}
+		if err := setFrosh(tx, policy, room, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add frosh to room"})
+			return
+		}
+	}
+
+	for suiteUUID := range suites {
+		if err := UpdateSuiteGenderPreferencesBySuiteUUID(tx, suiteUUID); err != nil {
+			logging.FromContext(c).Warn("Failed to update gender preferences", "suite", suiteUUID, "error", err)
+		}
+	}
+
+	// the students left in each suite frosh moved into or out of, and what changed there
+	suitemateChanges := make(map[int][]string)
+	for _, change := range []struct {
+		rooms  []FroshPlanRoom
+		format string
+	}{
+		{plan.Remove, "Frosh are no longer placed in %s %s, in your suite."},
+		{plan.Add, "Frosh have been placed in %s %s, in your suite."},
+	} {
+		for _, r := range change.rooms {
+			suitemates, err := froshSuitemates(tx, r.SuiteUUID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suitemates"})
+				return
+			}
+			for _, userID := range suitemates {
+				suitemateChanges[userID] = append(suitemateChanges[userID], fmt.Sprintf(change.format, r.DormName, r.RoomID))
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit APPLY_FROSH_PLAN", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+	logging.FromContext(c).Info("Committed APPLY_FROSH_PLAN", "added", len(plan.Add), "removed", len(plan.Remove), "by", email)
+
+	for _, notification := range notificationQueue.Notifications {
+		SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
+	}
+	for userID, changes := range suitemateChanges {
+		SendFroshNotificationAsync(userID, strings.Join(changes, "\n"))
+	}
+
+	details := map[string]interface{}{
+		"targets":     request.Targets,
+		"added":       len(plan.Add),
+		"removed":     len(plan.Remove),
+		"displaced":   plan.Displaced,
+		"plan_counts": plan.Counts,
+	}
+	for roomUUID := range changed {
+		newState, err := getRoomStateRaw(roomUUID.String())
+		if err != nil {
+			logging.FromContext(c).Error("Error fetching new room state for APPLY_FROSH_PLAN after commit", "room", roomUUID, "error", err)
+		}
+		if err := logging.LogOperation(c, "APPLY_FROSH_PLAN", models.EntityTypeRoom, roomUUID.String(), previousStates[roomUUID], newState, details); err != nil {
+			logging.FromContext(c).Warn("Failed to log APPLY_FROSH_PLAN operation", "room", roomUUID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Frosh plan applied", "plan": plan})
+}
+
+// makeFroshPlan reads every room of the targeted dorms and plans them
+func makeFroshPlan(tx *sql.Tx, targets []FroshTarget) (FroshPlan, error) {
+	byDorm := make(map[int]map[int]int)
+	for _, t := range targets {
+		if t.Count < 0 || t.FroshRoomType <= 0 {
+			return FroshPlan{}, froshRuleError("Targets need a frosh room type and a count of at least 0")
+		}
+		if dorm, ok := lookupDorm(t.Dorm); !ok || !dorm.Can(models.DormCapabilityFrosh) {
+			return FroshPlan{}, froshRuleError(fmt.Sprintf("Frosh cannot be placed in dorm %d", t.Dorm))
+		}
+		if byDorm[t.Dorm] == nil {
+			byDorm[t.Dorm] = make(map[int]int)
+		}
+		if _, ok := byDorm[t.Dorm][t.FroshRoomType]; ok {
+			return FroshPlan{}, froshRuleError(fmt.Sprintf("Dorm %d has more than one target for frosh room type %d", t.Dorm, t.FroshRoomType))
+		}
+		byDorm[t.Dorm][t.FroshRoomType] = t.Count
+	}
+
+	dormIDs := make([]int, 0, len(byDorm))
+	for id := range byDorm {
+		dormIDs = append(dormIDs, id)
+	}
+	sort.Ints(dormIDs)
+
+	plan := FroshPlan{Add: []FroshPlanRoom{}, Remove: []FroshPlanRoom{}, Displaced: []int{}, Counts: []FroshPlanCount{}, Unmet: []string{}}
+	for _, dormID := range dormIDs {
+		rooms, err := loadDormRooms(tx, dormID)
+		if err != nil {
+			return FroshPlan{}, err
+		}
+
+		if len(rooms) == 0 {
+			plan.Unmet = append(plan.Unmet, fmt.Sprintf("Dorm %d has no rooms", dormID))
+			continue
+		}
+
+		policy, err := froshPolicyFor(dormID)
+		if err != nil {
+			return FroshPlan{}, err
+		}
+		planDormFrosh(&plan, policy, rooms, byDorm[dormID])
+	}
+
+	return plan, nil
+}
+
+// planDormFrosh adds a dorm's part of the plan. Frosh rooms beyond a target are
+// removed first, then rooms are added from the ones displacing the fewest students.
+// A room or suite is only picked if it does not overshoot any type, and types
+// without a target keep the count they have.
+func planDormFrosh(plan *FroshPlan, policy froshPolicy, rooms []models.RoomRaw, targets map[int]int) {
+	current := make(map[int]int)
+	for _, r := range rooms {
+		if r.HasFrosh && r.FroshRoomType != 0 {
+			current[r.FroshRoomType]++
+		}
+	}
+
+	need := make(map[int]int)
+	for froshType := range current {
+		need[froshType] = 0
+	}
+	for froshType, count := range targets {
+		need[froshType] = count - current[froshType]
+	}
+
+	// group the rooms into the units that gain or lose frosh together
+	var units []froshUnit
+	if policy.suiteWide() {
+		bySuite := make(map[uuid.UUID]int)
+		for _, r := range rooms {
+			i, ok := bySuite[r.SuiteUUID]
+			if !ok {
+				i = len(units)
+				bySuite[r.SuiteUUID] = i
+				units = append(units, froshUnit{})
+			}
+			units[i].rooms = append(units[i].rooms, r)
+		}
+	} else {
+		for _, r := range rooms {
+			units = append(units, froshUnit{rooms: []models.RoomRaw{r}})
+		}
+	}
+
+	fits := func(u froshUnit, sign int) bool {
+		helps := false
+		for froshType, n := range u.contribution() {
+			remaining := sign * need[froshType]
+			if n > remaining {
+				return false
+			}
+			helps = true
+		}
+		return helps
+	}
+	apply := func(u froshUnit, sign int) {
+		for froshType, n := range u.contribution() {
+			need[froshType] -= sign * n
+		}
+	}
+
+	// remove frosh rooms beyond the targets, last rooms first
+	frosh := make(map[uuid.UUID]int) // frosh rooms per suite once the plan is done
+	var removed []froshUnit
+	for i := len(units) - 1; i >= 0; i-- {
+		u := units[i]
+		if !slices.ContainsFunc(u.rooms, func(r models.RoomRaw) bool { return r.HasFrosh }) {
+			continue
+		}
+		if fits(u, -1) {
+			apply(u, -1)
+			removed = append(removed, u)
+			continue
+		}
+		for _, r := range u.rooms {
+			if r.HasFrosh {
+				frosh[r.SuiteUUID]++
+			}
+		}
+	}
+
+	// add rooms that displace the fewest students first
+	candidates := slices.Clone(units)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].displaced()) < len(candidates[j].displaced())
+	})
+	_, onePerSuite := policy.(onePerSuiteFroshPolicy)
+	var added []froshUnit
+	for _, u := range candidates {
+		if u.preplaced() || slices.ContainsFunc(u.rooms, func(r models.RoomRaw) bool { return r.HasFrosh }) {
+			continue
+		}
+		if onePerSuite && frosh[u.rooms[0].SuiteUUID] > 0 {
+			continue
+		}
+		if !fits(u, 1) {
+			continue
+		}
+		apply(u, 1)
+		added = append(added, u)
+		for _, r := range u.rooms {
+			frosh[r.SuiteUUID]++
+		}
+	}
+
+	for _, u := range removed {
+		for _, r := range u.rooms {
+			if r.HasFrosh {
+				plan.Remove = append(plan.Remove, froshPlanRoom(r))
+			}
+		}
+	}
+	for _, u := range added {
+		for _, r := range u.rooms {
+			plan.Add = append(plan.Add, froshPlanRoom(r))
+			plan.Displaced = append(plan.Displaced, r.Occupants...)
+		}
+	}
+
+	froshTypes := make([]int, 0, len(need))
+	for froshType := range need {
+		froshTypes = append(froshTypes, froshType)
+	}
+	sort.Ints(froshTypes)
+	for _, froshType := range froshTypes {
+		target, ok := targets[froshType]
+		if !ok {
+			target = current[froshType]
+		}
+		plan.Counts = append(plan.Counts, FroshPlanCount{
+			Dorm:          rooms[0].Dorm,
+			FroshRoomType: froshType,
+			Current:       current[froshType],
+			Target:        target,
+			Planned:       target - need[froshType],
+		})
+		if need[froshType] != 0 {
+			plan.Unmet = append(plan.Unmet, fmt.Sprintf("%s frosh room type %d: planned %d of %d", rooms[0].DormName, froshType, target-need[froshType], target))
+		}
+	}
+}
+
+func froshPlanRoom(r models.RoomRaw) FroshPlanRoom {
+	displaced := []int(r.Occupants)
+	if displaced == nil {
+		displaced = []int{}
+	}
+	return FroshPlanRoom{
+		RoomUUID:      r.RoomUUID,
+		RoomID:        r.RoomID,
+		Dorm:          r.Dorm,
+		DormName:      r.DormName,
+		SuiteUUID:     r.SuiteUUID,
+		FroshRoomType: r.FroshRoomType,
+		Displaced:     displaced,
+	}
+}
+
+// sameFroshRooms reports whether two lists hold the same rooms, each displacing the
+// same students. A student who pulled into a planned room since it was reviewed
+// would be displaced without the admin having seen it.
+func sameFroshRooms(a, b []FroshPlanRoom) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	rooms := make(map[uuid.UUID][]int, len(a))
+	for _, r := range a {
+		rooms[r.RoomUUID] = slices.Sorted(slices.Values(r.Displaced))
+	}
+	for _, r := range b {
+		displaced, ok := rooms[r.RoomUUID]
+		if !ok || !slices.Equal(displaced, slices.Sorted(slices.Values(r.Displaced))) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"roomdraw/backend/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+// plannerRoom is a room of dorm 1 for the planner tests
+func plannerRoom(id string, suite uuid.UUID, hasFrosh bool, occupants ...int) models.RoomRaw {
+	return models.RoomRaw{
+		RoomUUID:         uuid.New(),
+		Dorm:             1,
+		DormName:         "Test",
+		RoomID:           id,
+		SuiteUUID:        suite,
+		CurrentOccupancy: len(occupants),
+		Occupants:        occupants,
+		HasFrosh:         hasFrosh,
+		FroshRoomType:    1,
+	}
+}
+
+func planRoomIDs(rooms []FroshPlanRoom) []string {
+	ids := make([]string, 0, len(rooms))
+	for _, r := range rooms {
+		ids = append(ids, r.RoomID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestPlanDormFrosh(t *testing.T) {
+	suiteA, suiteB := uuid.New(), uuid.New()
+
+	preplaced := plannerRoom("101", suiteA, false, 9)
+	preplaced.PullPriority.IsPreplaced = true
+
+	tests := []struct {
+		name          string
+		policy        froshPolicy
+		rooms         []models.RoomRaw
+		target        int
+		wantAdd       []string
+		wantRemove    []string
+		wantDisplaced []int
+		wantUnmet     int
+	}{
+		{
+			name:   "one per suite spreads frosh across suites",
+			policy: onePerSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, false), plannerRoom("102", suiteA, false),
+				plannerRoom("201", suiteB, false, 5), plannerRoom("202", suiteB, false),
+			},
+			target:  2,
+			wantAdd: []string{"101", "202"},
+		},
+		{
+			name:   "one per suite skips suites that keep a frosh room",
+			policy: onePerSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("102", suiteA, false),
+				plannerRoom("201", suiteB, false, 5),
+			},
+			target:        2,
+			wantAdd:       []string{"201"},
+			wantDisplaced: []int{5},
+		},
+		{
+			name:   "one per suite leaves a target unmet rather than doubling up",
+			policy: onePerSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, false), plannerRoom("102", suiteA, false),
+			},
+			target:    2,
+			wantAdd:   []string{"101"},
+			wantUnmet: 1,
+		},
+		{
+			name:   "whole suite adds the suite displacing the fewest students",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, false, 5), plannerRoom("102", suiteA, false),
+				plannerRoom("201", suiteB, false), plannerRoom("202", suiteB, false),
+			},
+			target:  2,
+			wantAdd: []string{"201", "202"},
+		},
+		{
+			name:   "whole suite displaces students when it has to",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, false, 5), plannerRoom("102", suiteA, false),
+				plannerRoom("201", suiteB, false), plannerRoom("202", suiteB, false),
+			},
+			target:        4,
+			wantAdd:       []string{"101", "102", "201", "202"},
+			wantDisplaced: []int{5},
+		},
+		{
+			name:   "whole suite never overshoots a target",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, false), plannerRoom("102", suiteA, false), plannerRoom("103", suiteA, false),
+			},
+			target:    2,
+			wantUnmet: 1,
+		},
+		{
+			name:   "whole suite removes a suite beyond the target",
+			policy: wholeSuiteFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("102", suiteA, true),
+				plannerRoom("201", suiteB, true), plannerRoom("202", suiteB, true),
+			},
+			target:     2,
+			wantRemove: []string{"201", "202"},
+		},
+		{
+			name:   "rooms beyond the target are removed last first",
+			policy: roomFroshPolicy{},
+			rooms: []models.RoomRaw{
+				plannerRoom("101", suiteA, true), plannerRoom("102", suiteA, true), plannerRoom("103", suiteA, true),
+			},
+			target:     1,
+			wantRemove: []string{"102", "103"},
+		},
+		{
+			name:   "preplaced rooms are never given frosh",
+			policy: roomFroshPolicy{},
+			rooms: []models.RoomRaw{
+				preplaced, plannerRoom("102", suiteA, false, 6),
+			},
+			target:        2,
+			wantAdd:       []string{"102"},
+			wantDisplaced: []int{6},
+			wantUnmet:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := FroshPlan{}
+			planDormFrosh(&plan, tt.policy, tt.rooms, map[int]int{1: tt.target})
+
+			if got := planRoomIDs(plan.Add); !slices.Equal(got, tt.wantAdd) && len(got)+len(tt.wantAdd) > 0 {
+				t.Errorf("added %v, want %v", got, tt.wantAdd)
+			}
+			if got := planRoomIDs(plan.Remove); !slices.Equal(got, tt.wantRemove) && len(got)+len(tt.wantRemove) > 0 {
+				t.Errorf("removed %v, want %v", got, tt.wantRemove)
+			}
+			if !slices.Equal(plan.Displaced, tt.wantDisplaced) && len(plan.Displaced)+len(tt.wantDisplaced) > 0 {
+				t.Errorf("displaced %v, want %v", plan.Displaced, tt.wantDisplaced)
+			}
+			if len(plan.Unmet) != tt.wantUnmet {
+				t.Errorf("unmet %v, want %d", plan.Unmet, tt.wantUnmet)
+			}
+		})
+	}
+}
+
+func TestSameFroshRooms(t *testing.T) {
+	room := FroshPlanRoom{RoomUUID: uuid.New(), Displaced: []int{}}
+	pulledInto := room
+	pulledInto.Displaced = []int{7}
+
+	if !sameFroshRooms([]FroshPlanRoom{room}, []FroshPlanRoom{room}) {
+		t.Error("a plan should match itself")
+	}
+	if sameFroshRooms([]FroshPlanRoom{pulledInto}, []FroshPlanRoom{room}) {
+		t.Error("a room that would now displace a student should not match the reviewed plan")
+	}
+	if sameFroshRooms([]FroshPlanRoom{room}, []FroshPlanRoom{{RoomUUID: uuid.New()}}) {
+		t.Error("different rooms should not match")
+	}
+}
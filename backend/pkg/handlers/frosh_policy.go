@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"roomdraw/backend/pkg/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// froshPolicy is how frosh rooms are placed in a dorm, chosen by dorms.frosh_policy.
+// Adding, removing and bumping frosh all go through the same policy.
+type froshPolicy interface {
+	// suiteWide reports whether frosh fill a whole suite, so a suite gains or loses
+	// frosh all at once
+	suiteWide() bool
+	// check returns why a frosh cannot go into target, nil if it can. suite holds the
+	// rooms of target's suite and from is the room a bumped frosh leaves, or nil when
+	// a frosh is added.
+	check(target models.RoomRaw, suite []models.RoomRaw, from *models.RoomRaw) error
+}
+
+// roomFroshPolicy places frosh room by room
+type roomFroshPolicy struct{}
+
+func (roomFroshPolicy) suiteWide() bool { return false }
+
+func (roomFroshPolicy) check(target models.RoomRaw, suite []models.RoomRaw, from *models.RoomRaw) error {
+	return nil
+}
+
+// onePerSuiteFroshPolicy places frosh room by room, with at most one frosh room in a suite
+type onePerSuiteFroshPolicy struct{}
+
+func (onePerSuiteFroshPolicy) suiteWide() bool { return false }
+
+func (onePerSuiteFroshPolicy) check(target models.RoomRaw, suite []models.RoomRaw, from *models.RoomRaw) error {
+	for _, r := range suite {
+		if r.HasFrosh && r.RoomUUID != target.RoomUUID && (from == nil || r.RoomUUID != from.RoomUUID) {
+			return froshRuleError("Target suite already has a frosh room")
+		}
+	}
+	return nil
+}
+
+// wholeSuiteFroshPolicy fills a whole suite with frosh, as in the inner dorms
+type wholeSuiteFroshPolicy struct{}
+
+func (wholeSuiteFroshPolicy) suiteWide() bool { return true }
+
+func (wholeSuiteFroshPolicy) check(target models.RoomRaw, suite []models.RoomRaw, from *models.RoomRaw) error {
+	for _, r := range suite {
+		if r.RoomUUID != target.RoomUUID && r.CurrentOccupancy != 0 {
+			return froshRuleError("Other rooms in the target suite are not empty")
+		}
+	}
+	return nil
+}
+
+// froshRuleError is a frosh placement the dorm's policy does not allow
+type froshRuleError string
+
+func (e froshRuleError) Error() string {
+	return string(e)
+}
+
+// froshPolicyFor returns the frosh policy of a dorm
+func froshPolicyFor(dormID int) (froshPolicy, error) {
+	dorm, ok := lookupDorm(dormID)
+	if !ok {
+		return nil, froshRuleError("Invalid dorm")
+	}
+	switch dorm.FroshPolicy {
+	case models.FroshPolicyWholeSuite:
+		return wholeSuiteFroshPolicy{}, nil
+	case models.FroshPolicyOnePerSuite:
+		return onePerSuiteFroshPolicy{}, nil
+	default:
+		return roomFroshPolicy{}, nil
+	}
+}
+
+// checkFroshPlacement applies the rules every dorm shares and then the dorm's own
+// policy to a frosh going into target. from is the room a bumped frosh leaves, or
+// nil when a frosh is added.
+func checkFroshPlacement(policy froshPolicy, target models.RoomRaw, suite []models.RoomRaw, from *models.RoomRaw) error {
+	if dorm, ok := lookupDorm(target.Dorm); !ok || !dorm.Can(models.DormCapabilityFrosh) {
+		return froshRuleError("Frosh cannot be placed in this dorm")
+	}
+	if target.FroshRoomType == 0 {
+		return froshRuleError("Room is not a frosh room")
+	}
+	if from != nil {
+		if target.Dorm != from.Dorm {
+			return froshRuleError("Target room is not in the same dorm as the original room")
+		}
+		if target.FroshRoomType != from.FroshRoomType {
+			return froshRuleError("Target room is not the same type as the original room")
+		}
+	}
+	if target.HasFrosh {
+		return froshRuleError("Room already has a frosh")
+	}
+	if target.CurrentOccupancy != 0 {
+		return froshRuleError("Room is not empty")
+	}
+	return policy.check(target, suite, from)
+}
+
+// setFrosh gives or takes frosh from a room, or from its whole suite if the policy
+// fills whole suites
+func setFrosh(tx *sql.Tx, policy froshPolicy, room models.RoomRaw, hasFrosh bool) error {
+	if policy.suiteWide() {
+		_, err := tx.Exec("UPDATE rooms SET has_frosh = $1 WHERE suite_uuid = $2", hasFrosh, room.SuiteUUID)
+		return err
+	}
+	_, err := tx.Exec("UPDATE rooms SET has_frosh = $1 WHERE room_uuid = $2", hasFrosh, room.RoomUUID)
+	return err
+}
+
+// loadFroshRoom reads a room for a frosh change, locking it
+func loadFroshRoom(tx *sql.Tx, roomUUID string) (models.RoomRaw, error) {
+	var room models.RoomRaw
+	err := tx.QueryRow("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms WHERE room_uuid = $1 FOR UPDATE", roomUUID).Scan(
+		&room.RoomUUID, &room.Dorm, &room.DormName, &room.RoomID, &room.SuiteUUID, &room.MaxOccupancy, &room.CurrentOccupancy, &room.Occupants, &room.PullPriority, &room.SGroupUUID, &room.HasFrosh, &room.FroshRoomType)
+	return room, err
+}
+
+// loadSuiteRooms reads every room of a suite, as a frosh policy checks them
+func loadSuiteRooms(tx *sql.Tx, suiteUUID uuid.UUID) ([]models.RoomRaw, error) {
+	rows, err := tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, has_frosh, frosh_room_type FROM rooms WHERE suite_uuid = $1 ORDER BY room_id", suiteUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []models.RoomRaw
+	for rows.Next() {
+		var r models.RoomRaw
+		if err := rows.Scan(&r.RoomUUID, &r.Dorm, &r.DormName, &r.RoomID, &r.SuiteUUID, &r.MaxOccupancy, &r.CurrentOccupancy, &r.HasFrosh, &r.FroshRoomType); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	return rooms, rows.Err()
+}
+
+// loadDormRooms reads every room of a dorm, for placing frosh across it
+func loadDormRooms(tx *sql.Tx, dormID int) ([]models.RoomRaw, error) {
+	rows, err := tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, has_frosh, frosh_room_type FROM rooms WHERE dorm = $1 ORDER BY room_id", dormID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rooms []models.RoomRaw
+	for rows.Next() {
+		var r models.RoomRaw
+		if err := rows.Scan(&r.RoomUUID, &r.Dorm, &r.DormName, &r.RoomID, &r.SuiteUUID, &r.MaxOccupancy, &r.CurrentOccupancy, &r.Occupants, &r.PullPriority, &r.HasFrosh, &r.FroshRoomType); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, r)
+	}
+	return rooms, rows.Err()
+}
+
+// respondFroshError answers a refused frosh change with a 400 and anything else with a 500
+func respondFroshError(c *gin.Context, err error, message string) {
+	var ruleErr froshRuleError
+	if errors.As(err, &ruleErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ruleErr.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
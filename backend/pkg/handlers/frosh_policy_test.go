@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"testing"
+
+	"roomdraw/backend/pkg/models"
+
+	"github.com/google/uuid"
+)
+
+func TestFroshPolicyCheck(t *testing.T) {
+	suiteUUID := uuid.New()
+	room := func(occupancy int, hasFrosh bool) models.RoomRaw {
+		return models.RoomRaw{RoomUUID: uuid.New(), SuiteUUID: suiteUUID, CurrentOccupancy: occupancy, HasFrosh: hasFrosh, FroshRoomType: 1}
+	}
+
+	target := room(0, false)
+	emptySuitemate := room(0, false)
+	froshSuitemate := room(0, true)
+	secondFroshSuitemate := room(0, true)
+	occupiedSuitemate := room(2, false)
+	// a frosh room in another suite of the same dorm, for bumps between suites
+	otherSuiteFrosh := models.RoomRaw{RoomUUID: uuid.New(), SuiteUUID: uuid.New(), HasFrosh: true, FroshRoomType: 1}
+
+	tests := []struct {
+		name    string
+		policy  froshPolicy
+		suite   []models.RoomRaw
+		from    *models.RoomRaw
+		wantErr bool
+	}{
+		{
+			name:   "room allows an empty suite",
+			policy: roomFroshPolicy{},
+			suite:  []models.RoomRaw{target, emptySuitemate},
+		},
+		{
+			name:   "room allows a second frosh room",
+			policy: roomFroshPolicy{},
+			suite:  []models.RoomRaw{target, froshSuitemate},
+		},
+		{
+			name:   "room allows occupied suitemates",
+			policy: roomFroshPolicy{},
+			suite:  []models.RoomRaw{target, occupiedSuitemate},
+		},
+		{
+			name:   "one per suite allows an empty suite",
+			policy: onePerSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, emptySuitemate, occupiedSuitemate},
+		},
+		{
+			name:    "one per suite refuses a second frosh room",
+			policy:  onePerSuiteFroshPolicy{},
+			suite:   []models.RoomRaw{target, froshSuitemate},
+			wantErr: true,
+		},
+		{
+			name:   "one per suite allows a bump within the suite",
+			policy: onePerSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, froshSuitemate},
+			from:   &froshSuitemate,
+		},
+		{
+			name:    "one per suite refuses a bump within a suite with another frosh room",
+			policy:  onePerSuiteFroshPolicy{},
+			suite:   []models.RoomRaw{target, froshSuitemate, secondFroshSuitemate},
+			from:    &froshSuitemate,
+			wantErr: true,
+		},
+		{
+			name:   "one per suite allows a bump from another suite into an empty suite",
+			policy: onePerSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, emptySuitemate, occupiedSuitemate},
+			from:   &otherSuiteFrosh,
+		},
+		{
+			name:    "one per suite refuses a bump from another suite into a suite with frosh",
+			policy:  onePerSuiteFroshPolicy{},
+			suite:   []models.RoomRaw{target, froshSuitemate},
+			from:    &otherSuiteFrosh,
+			wantErr: true,
+		},
+		{
+			name:   "whole suite allows an empty suite",
+			policy: wholeSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, emptySuitemate},
+		},
+		{
+			name:   "whole suite allows frosh suitemates",
+			policy: wholeSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, froshSuitemate},
+		},
+		{
+			name:    "whole suite refuses occupied suitemates",
+			policy:  wholeSuiteFroshPolicy{},
+			suite:   []models.RoomRaw{target, emptySuitemate, occupiedSuitemate},
+			wantErr: true,
+		},
+		{
+			name:   "whole suite allows a bump from another suite into an empty suite",
+			policy: wholeSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, emptySuitemate},
+			from:   &otherSuiteFrosh,
+		},
+		{
+			name:   "whole suite allows a bump within the frosh's own suite",
+			policy: wholeSuiteFroshPolicy{},
+			suite:  []models.RoomRaw{target, froshSuitemate},
+			from:   &froshSuitemate,
+		},
+		{
+			name:    "whole suite refuses a bump into a suite with occupants",
+			policy:  wholeSuiteFroshPolicy{},
+			suite:   []models.RoomRaw{target, occupiedSuitemate},
+			from:    &otherSuiteFrosh,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.check(target, tt.suite, tt.from)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, ok := err.(froshRuleError); err != nil && !ok {
+				t.Errorf("check() error = %T, want froshRuleError", err)
+			}
+		})
+	}
+}
+
+func TestFroshPolicySuiteWide(t *testing.T) {
+	tests := []struct {
+		policy froshPolicy
+		want   bool
+	}{
+		{roomFroshPolicy{}, false},
+		{onePerSuiteFroshPolicy{}, false},
+		{wholeSuiteFroshPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.policy.suiteWide(); got != tt.want {
+			t.Errorf("%T.suiteWide() = %v, want %v", tt.policy, got, tt.want)
+		}
+	}
+}
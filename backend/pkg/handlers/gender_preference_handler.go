@@ -0,0 +1,629 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// genderPreferenceError is a proposal change that was refused, and the status to answer it with
+type genderPreferenceError struct {
+	status  int
+	message string
+}
+
+func (e *genderPreferenceError) Error() string {
+	return e.message
+}
+
+type genderPreferenceProposalRequest struct {
+	GenderPreferences []string `json:"genderPreferences" binding:"required"`
+}
+
+// GetSuiteGenderPreferenceDetails returns a suite's gender preference, the rule that
+// decided it and the occupants whose preferences it used, with the changes its
+// occupants have proposed
+func GetSuiteGenderPreferenceDetails(c *gin.Context) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	suite, err := loadSuiteGenderPreference(database.DB, suiteUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load suite gender preference", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suite gender preference"})
+		return
+	}
+
+	c.JSON(http.StatusOK, suite)
+}
+
+// ProposeSuiteGenderPreference lets an occupant ask for a gender preference for their
+// suite. It counts as their approval, and takes over from the rules once every
+// occupant has approved it.
+func ProposeSuiteGenderPreference(c *gin.Context) {
+	var request genderPreferenceProposalRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preferences, ok := cleanGenderPreferences(request.GenderPreferences)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gender preferences cannot be blank"})
+		return
+	}
+	if preference, ok := unknownGenderPreference(preferences); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown gender preference", "genderPreference": preference})
+		return
+	}
+
+	updateSuiteGenderPreference(c, "PROPOSE_GENDER_PREFERENCE", func(tx *sql.Tx, userID int, suite *models.SuiteGenderPreference) (map[string]interface{}, error) {
+		if !suite.CanBeGenderPreferenced {
+			return nil, &genderPreferenceError{status: http.StatusConflict, message: "This suite cannot be gender preferenced"}
+		}
+		if err := checkProposalFitsOccupants(tx, suite, preferences); err != nil {
+			return nil, err
+		}
+
+		var proposalID int
+		err := tx.QueryRow(`
+            INSERT INTO suite_gender_preference_proposals (suite_uuid, proposed_by, gender_preferences)
+            VALUES ($1, $2, $3) RETURNING proposal_id`, suite.SuiteUUID, userID, pq.StringArray(preferences)).Scan(&proposalID)
+		if isUniqueViolation(err) {
+			return nil, &genderPreferenceError{status: http.StatusConflict, message: "The suite already has an open proposal, approve or reject it first"}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		approved, err := approveGenderPreferenceProposal(tx, suite, proposalID, userID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"proposal_id": proposalID, "gender_preferences": preferences, "approved": approved}, nil
+	})
+}
+
+// ApproveGenderPreferenceProposal records an occupant's approval of an open proposal,
+// and applies it once every occupant has approved it
+func ApproveGenderPreferenceProposal(c *gin.Context) {
+	proposalID, err := strconv.Atoi(c.Param("proposalid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal id"})
+		return
+	}
+
+	updateSuiteGenderPreference(c, "APPROVE_GENDER_PREFERENCE", func(tx *sql.Tx, userID int, suite *models.SuiteGenderPreference) (map[string]interface{}, error) {
+		proposal, err := openGenderPreferenceProposal(suite, proposalID)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(proposal.ApprovedBy, userID) {
+			return nil, &genderPreferenceError{status: http.StatusConflict, message: "You have already approved this proposal"}
+		}
+		// occupants may have moved in or changed their preferences since it was proposed
+		if err := checkProposalFitsOccupants(tx, suite, proposal.GenderPreferences); err != nil {
+			return nil, err
+		}
+
+		approved, err := approveGenderPreferenceProposal(tx, suite, proposalID, userID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"proposal_id": proposalID, "gender_preferences": proposal.GenderPreferences, "approved": approved}, nil
+	})
+}
+
+// RejectGenderPreferenceProposal closes an open proposal. Any occupant can reject it,
+// including the one who proposed it.
+func RejectGenderPreferenceProposal(c *gin.Context) {
+	proposalID, err := strconv.Atoi(c.Param("proposalid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid proposal id"})
+		return
+	}
+
+	updateSuiteGenderPreference(c, "REJECT_GENDER_PREFERENCE", func(tx *sql.Tx, userID int, suite *models.SuiteGenderPreference) (map[string]interface{}, error) {
+		proposal, err := openGenderPreferenceProposal(suite, proposalID)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec("UPDATE suite_gender_preference_proposals SET status = $1, decided_at = NOW() WHERE proposal_id = $2",
+			models.GenderPreferenceProposalRejected, proposalID)
+		return map[string]interface{}{"proposal_id": proposalID, "gender_preferences": proposal.GenderPreferences}, err
+	})
+}
+
+// updateSuiteGenderPreference runs change against a locked suite the signed in user
+// lives in, logs it as operation and responds with the suite's gender preference as it
+// is afterwards
+func updateSuiteGenderPreference(c *gin.Context, operation string, change func(tx *sql.Tx, userID int, suite *models.SuiteGenderPreference) (map[string]interface{}, error)) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT suite_uuid FROM suites WHERE suite_uuid = $1 FOR UPDATE", suiteUUID).Scan(&suiteUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to lock suite", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock suite"})
+		return
+	}
+
+	previous, err := loadSuiteGenderPreference(tx, suiteUUID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load suite gender preference", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suite gender preference"})
+		return
+	}
+	if !slices.Contains(previous.Occupants, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the suite's occupants can change its gender preference"})
+		return
+	}
+
+	details, err := change(tx, userID, previous)
+	if err != nil {
+		var preferenceErr *genderPreferenceError
+		if errors.As(err, &preferenceErr) {
+			c.JSON(preferenceErr.status, gin.H{"error": preferenceErr.message})
+			return
+		}
+		logging.FromContext(c).Error("Failed to update gender preference", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gender preference"})
+		return
+	}
+
+	current, err := loadSuiteGenderPreference(tx, suiteUUID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load suite gender preference", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load suite gender preference"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit "+operation, "entity", suiteUUID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+
+	details["user_id"] = userID
+	details["rule"] = current.Rule
+	logging.FromContext(c).Info("Committed "+operation, "entity", suiteUUID, "by", userID)
+	if err := logging.LogOperation(c, operation, models.EntityTypeSuite, suiteUUID.String(), previous, current, details); err != nil {
+		logging.FromContext(c).Warn("Failed to log "+operation+" operation", "suite", suiteUUID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, current)
+}
+
+// openGenderPreferenceProposal returns one of the suite's proposals if it can still be
+// approved or rejected
+func openGenderPreferenceProposal(suite *models.SuiteGenderPreference, proposalID int) (*models.GenderPreferenceProposal, error) {
+	for i := range suite.Proposals {
+		if suite.Proposals[i].ProposalID != proposalID {
+			continue
+		}
+		if suite.Proposals[i].Status != models.GenderPreferenceProposalOpen {
+			return nil, &genderPreferenceError{status: http.StatusConflict, message: "The proposal is already " + suite.Proposals[i].Status}
+		}
+		return &suite.Proposals[i], nil
+	}
+	return nil, &genderPreferenceError{status: http.StatusNotFound, message: "Proposal not found"}
+}
+
+// checkProposalFitsOccupants refuses a proposal that asks for a preference outside
+// the intersection of the current occupants' own preferences. Occupants without
+// preferences fit any suite, so they do not narrow it.
+func checkProposalFitsOccupants(tx *sql.Tx, suite *models.SuiteGenderPreference, preferences []string) error {
+	rows, err := tx.Query("SELECT gender_preferences FROM users WHERE id = ANY($1) AND cardinality(gender_preferences) > 0", pq.Array(suite.Occupants))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var occupantPreferences [][]string
+	for rows.Next() {
+		var own pq.StringArray
+		if err := rows.Scan(&own); err != nil {
+			return err
+		}
+		occupantPreferences = append(occupantPreferences, own)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(occupantPreferences) == 0 {
+		return nil
+	}
+
+	allowed := findIntersectionOfPreferences(occupantPreferences)
+	shared := strings.Join(allowed, ", ")
+	if shared == "" {
+		shared = "none"
+	}
+	for _, preference := range preferences {
+		if !slices.Contains(allowed, preference) {
+			return &genderPreferenceError{status: http.StatusConflict,
+				message: fmt.Sprintf("%s is not a preference every occupant shares (shared: %s)", preference, shared)}
+		}
+	}
+	return nil
+}
+
+// approveGenderPreferenceProposal records userID's approval. Once every current
+// occupant has approved, the proposal replaces the suite's previously approved one and
+// the suite's gender preference is worked out again. Reports whether it was applied.
+func approveGenderPreferenceProposal(tx *sql.Tx, suite *models.SuiteGenderPreference, proposalID int, userID int) (bool, error) {
+	_, err := tx.Exec("INSERT INTO suite_gender_preference_approvals (proposal_id, user_id) VALUES ($1, $2)", proposalID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	var waiting int
+	err = tx.QueryRow(`
+        SELECT COUNT(*) FROM unnest($1::int[]) AS o(user_id)
+        WHERE NOT EXISTS (SELECT 1 FROM suite_gender_preference_approvals a WHERE a.proposal_id = $2 AND a.user_id = o.user_id)`,
+		pq.Array(suite.Occupants), proposalID).Scan(&waiting)
+	if err != nil {
+		return false, err
+	}
+	if waiting > 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec("UPDATE suite_gender_preference_proposals SET status = $1, decided_at = NOW() WHERE suite_uuid = $2 AND status = $3",
+		models.GenderPreferenceProposalSuperseded, suite.SuiteUUID, models.GenderPreferenceProposalApproved)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec("UPDATE suite_gender_preference_proposals SET status = $1, decided_at = NOW() WHERE proposal_id = $2",
+		models.GenderPreferenceProposalApproved, proposalID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := UpdateSuiteGenderPreferencesBySuiteUUID(tx, suite.SuiteUUID); err != nil {
+		return false, fmt.Errorf("failed to update gender preferences for suite %s: %w", suite.SuiteUUID, err)
+	}
+	return true, nil
+}
+
+// approvedGenderPreferenceProposal is the proposal a suite's occupants approved last
+type approvedGenderPreferenceProposal struct {
+	proposalID        int
+	genderPreferences []string
+	approvedBy        []int
+}
+
+// loadApprovedGenderPreferenceProposal returns the suite's approved proposal, or nil if
+// it has none
+func loadApprovedGenderPreferenceProposal(tx *sql.Tx, suiteUUID uuid.UUID) (*approvedGenderPreferenceProposal, error) {
+	var proposalID int
+	var preferences pq.StringArray
+	var approvedBy models.IntArray
+	err := tx.QueryRow(`
+        SELECT p.proposal_id, p.gender_preferences,
+               ARRAY(SELECT a.user_id FROM suite_gender_preference_approvals a WHERE a.proposal_id = p.proposal_id ORDER BY a.user_id)
+        FROM suite_gender_preference_proposals p
+        WHERE p.suite_uuid = $1 AND p.status = $2
+        ORDER BY p.decided_at DESC LIMIT 1`, suiteUUID, models.GenderPreferenceProposalApproved).Scan(&proposalID, &preferences, &approvedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &approvedGenderPreferenceProposal{proposalID: proposalID, genderPreferences: preferences, approvedBy: approvedBy}, nil
+}
+
+// decideSuiteGenderPreference works out the gender preference of a suite the users live
+// in. A suite that cannot be gender preferenced has none (Rule 1). Otherwise the approved
+// proposal, if any, takes over from the rules while everyone living in the suite approved
+// it. Once someone who did not moves in, or the suite empties, the rules apply again and
+// superseded reports that the proposal should be retired.
+func decideSuiteGenderPreference(canBeGenderPreferenced bool, users []models.UserRaw, dormId int, proposal *approvedGenderPreferenceProposal) (decision models.GenderPreferenceDecision, superseded bool) {
+	if !canBeGenderPreferenced {
+		return models.GenderPreferenceDecision{
+			GenderPreferences: []string{},
+			Rule:              models.GenderPreferenceRuleCannotBePreferenced,
+			DecidedBy:         []int{},
+		}, false
+	}
+
+	if proposal != nil {
+		occupants := make([]int, 0, len(users))
+		for _, user := range users {
+			occupants = append(occupants, user.Id)
+			if !slices.Contains(proposal.approvedBy, user.Id) {
+				occupants = nil
+				break
+			}
+		}
+		if len(occupants) > 0 {
+			return models.GenderPreferenceDecision{GenderPreferences: proposal.genderPreferences, Rule: models.GenderPreferenceRuleProposal, DecidedBy: occupants}, false
+		}
+	}
+
+	return GetSuiteGenderPreference(users, dormId), proposal != nil
+}
+
+// resolveSuiteGenderPreference decides the gender preference of a suite the users live
+// in with decideSuiteGenderPreference, and retires its approved proposal if that no
+// longer applies
+func resolveSuiteGenderPreference(tx *sql.Tx, suiteUUID uuid.UUID, canBeGenderPreferenced bool, users []models.UserRaw, dormId int) (models.GenderPreferenceDecision, error) {
+	var proposal *approvedGenderPreferenceProposal
+	if canBeGenderPreferenced {
+		var err error
+		proposal, err = loadApprovedGenderPreferenceProposal(tx, suiteUUID)
+		if err != nil {
+			return models.GenderPreferenceDecision{}, err
+		}
+	}
+
+	decision, superseded := decideSuiteGenderPreference(canBeGenderPreferenced, users, dormId, proposal)
+	if !superseded {
+		return decision, nil
+	}
+
+	slog.Info("Occupants changed since the proposal was approved, falling back to the rules", "suite", suiteUUID, "proposal", proposal.proposalID, "rule", decision.Rule)
+	_, err := tx.Exec("UPDATE suite_gender_preference_proposals SET status = $1, decided_at = NOW() WHERE proposal_id = $2",
+		models.GenderPreferenceProposalSuperseded, proposal.proposalID)
+	return decision, err
+}
+
+// saveSuiteGenderPreference stores a suite's gender preference with the rule that
+// decided it, and logs it if the preference or the rule changed
+func saveSuiteGenderPreference(tx *sql.Tx, suiteUUID uuid.UUID, previous, decision models.GenderPreferenceDecision) error {
+	_, err := tx.Exec(`
+        UPDATE suites SET gender_preferences = COALESCE($1::varchar[], '{}'), gender_preference_rule = $2,
+            gender_preference_decided_by = COALESCE($3::int[], '{}')
+        WHERE suite_uuid = $4`,
+		pq.StringArray(decision.GenderPreferences), decision.Rule, pq.Array(decision.DecidedBy), suiteUUID)
+	if err != nil {
+		slog.Error("Failed to update gender preferences", "suite", suiteUUID, "gender_preferences", decision.GenderPreferences, "error", err)
+		return err
+	}
+
+	if decision.Rule == previous.Rule && slices.Equal(decision.GenderPreferences, previous.GenderPreferences) {
+		return nil
+	}
+
+	err = logging.LogSystemOperation(tx, "UPDATE_SUITE_GENDER_PREFERENCE", models.EntityTypeSuite, suiteUUID.String(), previous, decision, map[string]interface{}{
+		"rule":       decision.Rule,
+		"decided_by": decision.DecidedBy,
+		"conflict":   decision.Conflict,
+	})
+	if err != nil {
+		slog.Warn("Failed to log UPDATE_SUITE_GENDER_PREFERENCE operation", "suite", suiteUUID, "error", err)
+	}
+	return nil
+}
+
+// loadSuiteGenderPreference returns a suite's gender preference with its current
+// occupants and its proposals, newest first
+func loadSuiteGenderPreference(q drawGroupQuerier, suiteUUID uuid.UUID) (*models.SuiteGenderPreference, error) {
+	suite := models.SuiteGenderPreference{Occupants: []int{}, Proposals: []models.GenderPreferenceProposal{}}
+	var preferences pq.StringArray
+	var decidedBy models.IntArray
+	err := q.QueryRow(`
+        SELECT suite_uuid, can_be_gender_preferenced, gender_preferences, gender_preference_rule, gender_preference_decided_by
+        FROM suites WHERE suite_uuid = $1`, suiteUUID).Scan(
+		&suite.SuiteUUID, &suite.CanBeGenderPreferenced, &preferences, &suite.Rule, &decidedBy)
+	if err != nil {
+		return nil, err
+	}
+	suite.GenderPreferences = preferences
+	suite.DecidedBy = decidedBy
+	if suite.DecidedBy == nil {
+		suite.DecidedBy = []int{}
+	}
+
+	rows, err := q.Query(`
+        SELECT u.id FROM users u
+        JOIN rooms r ON r.room_uuid = u.room_uuid AND u.id = ANY(r.occupants)
+        WHERE r.suite_uuid = $1
+        ORDER BY u.id`, suiteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occupants of suite %s: %w", suiteUUID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		suite.Occupants = append(suite.Occupants, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.Query(`
+        SELECT p.proposal_id, p.suite_uuid, p.proposed_by, p.gender_preferences, p.status, p.created_at, p.decided_at,
+               ARRAY(SELECT a.user_id FROM suite_gender_preference_approvals a WHERE a.proposal_id = p.proposal_id ORDER BY a.approved_at)
+        FROM suite_gender_preference_proposals p
+        WHERE p.suite_uuid = $1
+        ORDER BY p.created_at DESC, p.proposal_id DESC`, suiteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gender preference proposals of suite %s: %w", suiteUUID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.GenderPreferenceProposal
+		var proposed pq.StringArray
+		var approvedBy models.IntArray
+		if err := rows.Scan(&p.ProposalID, &p.SuiteUUID, &p.ProposedBy, &proposed, &p.Status, &p.CreatedAt, &p.DecidedAt, &approvedBy); err != nil {
+			return nil, err
+		}
+		p.GenderPreferences = proposed
+		p.ApprovedBy = approvedBy
+		if p.ApprovedBy == nil {
+			p.ApprovedBy = []int{}
+		}
+		suite.Proposals = append(suite.Proposals, p)
+	}
+	return &suite, rows.Err()
+}
+
+// genderPreferenceConflicts returns the users whose own preferences have nothing in
+// common with a suite's. Users without preferences fit any suite.
+func genderPreferenceConflicts(suitePreferences []string, users []models.UserRaw) []models.UserRaw {
+	var conflicts []models.UserRaw
+	for _, u := range users {
+		if len(u.GenderPreferences) > 0 && len(findIntersection(u.GenderPreferences, suitePreferences)) == 0 {
+			conflicts = append(conflicts, u)
+		}
+	}
+	return conflicts
+}
+
+// outranksSuite reports whether a pull with priority beats every occupied room of the
+// suite other than the one being pulled, whose occupants it would bump anyway
+func outranksSuite(priority models.PullPriority, roomUUID uuid.UUID, suiteRooms []models.RoomRaw) bool {
+	for _, r := range suiteRooms {
+		if r.RoomUUID != roomUUID && r.CurrentOccupancy > 0 && !comparePullPriority(priority, r.PullPriority) {
+			return false
+		}
+	}
+	return true
+}
+
+// enforceSuiteGenderPreference answers a pull with a 409 if the room's dorm enforces
+// gender preferences, one of the proposed occupants' preferences has nothing in common
+// with the suite's and the pull does not outrank everyone already living in the suite.
+// The response names the suite preference that blocked the pull and who it blocked.
+// The preference is worked out again from the occupants who would stay in the suite,
+// so neither a stale preference nor that of the students being bumped can block it.
+func enforceSuiteGenderPreference(c *gin.Context, tx *sql.Tx, room models.RoomRaw, proposedOccupants []int, priority models.PullPriority) error {
+	if dorm, ok := lookupDorm(room.Dorm); !ok || dorm.GenderPreferenceMode != models.GenderPreferenceModeEnforced {
+		return nil
+	}
+
+	var canBeGenderPreferenced bool
+	err := tx.QueryRow("SELECT can_be_gender_preferenced FROM suites WHERE suite_uuid = $1", room.SuiteUUID).Scan(&canBeGenderPreferenced)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query gender preferences from suites table"})
+		return err
+	}
+	if !canBeGenderPreferenced {
+		return nil
+	}
+
+	// the room's occupants are about to be bumped, so they have no say in the
+	// preference the pull has to match
+	staying, err := loadSuiteOccupants(tx, room.SuiteUUID, room.RoomUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query suite occupants for gender preference"})
+		return err
+	}
+	decision, err := resolveSuiteGenderPreference(tx, room.SuiteUUID, true, staying, room.Dorm)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check gender preference proposals"})
+		return err
+	}
+	preferences, rule := decision.GenderPreferences, decision.Rule
+	if len(preferences) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query("SELECT room_uuid, current_occupancy, pull_priority FROM rooms WHERE suite_uuid = $1", room.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query suite rooms for gender preference"})
+		return err
+	}
+	defer rows.Close()
+	var suiteRooms []models.RoomRaw
+	for rows.Next() {
+		var r models.RoomRaw
+		if err := rows.Scan(&r.RoomUUID, &r.CurrentOccupancy, &r.PullPriority); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan suite rooms for gender preference"})
+			return err
+		}
+		suiteRooms = append(suiteRooms, r)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan suite rooms for gender preference"})
+		return err
+	}
+
+	rows, err = tx.Query("SELECT id, first_name, last_name, gender_preferences FROM users WHERE id = ANY($1) ORDER BY id", pq.Array(proposedOccupants))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query gender preferences from users table"})
+		return err
+	}
+	defer rows.Close()
+	var users []models.UserRaw
+	for rows.Next() {
+		var u models.UserRaw
+		if err := rows.Scan(&u.Id, &u.FirstName, &u.LastName, &u.GenderPreferences); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan gender preferences from users table"})
+			return err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan gender preferences from users table"})
+		return err
+	}
+
+	conflicts := genderPreferenceBlocks(preferences, priority, room, suiteRooms, users)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	blocked := make([]int, 0, len(conflicts))
+	names := make([]string, 0, len(conflicts))
+	for _, u := range conflicts {
+		blocked = append(blocked, u.Id)
+		names = append(names, fmt.Sprintf("%s %s (%s)", u.FirstName, u.LastName, strings.Join(u.GenderPreferences, ", ")))
+	}
+	rejectPull(c, http.StatusConflict, rejectedSuiteGenderPreference, gin.H{
+		"error":              fmt.Sprintf("Proposed occupants do not match the suite's gender preference (%s)", strings.Join(preferences, ", ")),
+		"blockingPreference": preferences,
+		"rule":               rule,
+		"occupants":          blocked,
+		"details": fmt.Sprintf("The suite's gender preference is %s (rule %s). It does not include the preferences of %s, and the pull does not outrank everyone already in the suite.",
+			strings.Join(preferences, ", "), rule, strings.Join(names, ", ")),
+	})
+	return errors.New("proposed occupants do not match the suite's gender preference")
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"roomdraw/backend/pkg/models"
+)
+
+func TestDecideSuiteGenderPreference(t *testing.T) {
+	const dorm = 1
+	senior := models.UserRaw{Id: 1, Year: "senior", DrawNumber: 40, GenderPreferences: []string{"Cis Woman", "Trans Woman"}}
+	junior := models.UserRaw{Id: 2, Year: "junior", DrawNumber: 10, GenderPreferences: []string{"Cis Man"}}
+	juniorWithout := models.UserRaw{Id: 3, Year: "junior", DrawNumber: 5}
+	preplaced := models.UserRaw{Id: 4, Year: "sophomore", Preplaced: true, GenderPreferences: []string{"Trans Woman", "Non-Binary"}}
+	preplacedOther := models.UserRaw{Id: 5, Year: "junior", Preplaced: true, GenderPreferences: []string{"Non-Binary", "Cis Man"}}
+	preplacedConflict := models.UserRaw{Id: 6, Year: "junior", Preplaced: true, GenderPreferences: []string{"Cis Woman"}}
+	preplacedWithout := models.UserRaw{Id: 7, Year: "senior", Preplaced: true}
+
+	proposal := &approvedGenderPreferenceProposal{proposalID: 1, genderPreferences: []string{"Non-Binary"}, approvedBy: []int{1, 3}}
+
+	tests := []struct {
+		name           string
+		canBe          bool
+		users          []models.UserRaw
+		proposal       *approvedGenderPreferenceProposal
+		want           models.GenderPreferenceDecision
+		wantSuperseded bool
+	}{
+		{
+			name:  "rule 1 when the suite cannot be preferenced",
+			canBe: false,
+			users: []models.UserRaw{senior},
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRuleCannotBePreferenced, DecidedBy: []int{}},
+		},
+		{
+			name:  "empty suite",
+			canBe: true,
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRuleEmpty, DecidedBy: []int{}},
+		},
+		{
+			name:  "rule 2 takes the highest priority occupant",
+			canBe: true,
+			users: []models.UserRaw{junior, senior},
+			want:  models.GenderPreferenceDecision{GenderPreferences: senior.GenderPreferences, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{1}},
+		},
+		{
+			name:  "rule 2 skips occupants without preferences",
+			canBe: true,
+			users: []models.UserRaw{juniorWithout, junior},
+			want:  models.GenderPreferenceDecision{GenderPreferences: junior.GenderPreferences, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{2}},
+		},
+		{
+			name:  "rule 2 without any preferences",
+			canBe: true,
+			users: []models.UserRaw{juniorWithout},
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{}},
+		},
+		{
+			name:  "rule 3a intersects the preplaced occupants",
+			canBe: true,
+			users: []models.UserRaw{senior, preplaced, preplacedOther, preplacedWithout},
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{"Non-Binary"}, Rule: models.GenderPreferenceRulePreplaced, DecidedBy: []int{4, 5}},
+		},
+		{
+			name:  "rule 3a with conflicting preplaced occupants",
+			canBe: true,
+			users: []models.UserRaw{preplaced, preplacedConflict},
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRulePreplaced, DecidedBy: []int{4, 6}, Conflict: true},
+		},
+		{
+			name:  "rule 3b when no preplaced occupant has preferences",
+			canBe: true,
+			users: []models.UserRaw{senior, preplacedWithout},
+			want:  models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRulePreplacedNone, DecidedBy: []int{7}},
+		},
+		{
+			name:     "proposal approved by every occupant",
+			canBe:    true,
+			users:    []models.UserRaw{senior, juniorWithout},
+			proposal: proposal,
+			want:     models.GenderPreferenceDecision{GenderPreferences: []string{"Non-Binary"}, Rule: models.GenderPreferenceRuleProposal, DecidedBy: []int{1, 3}},
+		},
+		{
+			name:           "proposal superseded once someone who did not approve moves in",
+			canBe:          true,
+			users:          []models.UserRaw{senior, junior},
+			proposal:       proposal,
+			want:           models.GenderPreferenceDecision{GenderPreferences: senior.GenderPreferences, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{1}},
+			wantSuperseded: true,
+		},
+		{
+			name:           "proposal superseded once the suite empties",
+			canBe:          true,
+			proposal:       proposal,
+			want:           models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRuleEmpty, DecidedBy: []int{}},
+			wantSuperseded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, superseded := decideSuiteGenderPreference(tt.canBe, tt.users, dorm, tt.proposal)
+			if got.Rule != tt.want.Rule || got.Conflict != tt.want.Conflict ||
+				!slices.Equal(got.GenderPreferences, tt.want.GenderPreferences) || !slices.Equal(got.DecidedBy, tt.want.DecidedBy) {
+				t.Errorf("decideSuiteGenderPreference() = %+v, want %+v", got, tt.want)
+			}
+			if superseded != tt.wantSuperseded {
+				t.Errorf("decideSuiteGenderPreference() superseded = %v, want %v", superseded, tt.wantSuperseded)
+			}
+		})
+	}
+}
@@ -0,0 +1,646 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// drawGroupError is a group change that was refused, and the status to answer it with
+type drawGroupError struct {
+	status  int
+	message string
+	details gin.H
+}
+
+func (e *drawGroupError) Error() string {
+	return e.message
+}
+
+// drawGroupQuerier is satisfied by both *sql.DB and *sql.Tx
+type drawGroupQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type drawGroupInviteRequest struct {
+	Name   string   `json:"name"`
+	Emails []string `json:"emails"`
+}
+
+// CreateDrawGroup starts a group led by the signed in user and invites students by email
+func CreateDrawGroup(c *gin.Context) {
+	var request drawGroupInviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	var groupID int
+	err = tx.QueryRow("INSERT INTO draw_groups (name, leader_id) VALUES ($1, $2) RETURNING group_id", request.Name, userID).Scan(&groupID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to create group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	_, err = tx.Exec("INSERT INTO draw_group_members (group_id, user_id, status, responded_at) VALUES ($1, $2, $3, NOW())", groupID, userID, models.DrawGroupMemberAccepted)
+	if err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already in a group, leave it first"})
+			return
+		}
+		logging.FromContext(c).Error("Failed to create group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	if err := inviteToDrawGroup(tx, groupID, userID, request.Emails); err != nil {
+		respondDrawGroupError(c, err)
+		return
+	}
+
+	group, _, err := loadDrawGroup(tx, groupID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+
+	logging.FromContext(c).Info("Committed CREATE_DRAW_GROUP", "entity", groupID, "by", userID)
+	if err := logging.LogOperation(c, "CREATE_DRAW_GROUP", models.EntityTypeDrawGroup, strconv.Itoa(groupID), nil, group, map[string]interface{}{"invited": request.Emails}); err != nil {
+		logging.FromContext(c).Warn("Failed to log CREATE_DRAW_GROUP operation", "group", groupID, "error", err)
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// GetMyDrawGroups lists the groups the signed in user is in or was invited to
+func GetMyDrawGroups(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.Query("SELECT group_id FROM draw_group_members WHERE user_id = $1 ORDER BY invited_at DESC", userID)
+	if err != nil {
+		logging.FromContext(c).Error("Database query failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
+		return
+	}
+	var groupIDs []int
+	for rows.Next() {
+		var groupID int
+		if err := rows.Scan(&groupID); err != nil {
+			rows.Close()
+			logging.FromContext(c).Error("Database scan failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
+			return
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	rows.Close()
+
+	groups := []*models.DrawGroup{}
+	for _, groupID := range groupIDs {
+		group, _, err := loadDrawGroup(database.DB, groupID)
+		if err != nil {
+			logging.FromContext(c).Error("Failed to load group", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+			return
+		}
+		groups = append(groups, group)
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// GetDrawGroup returns a group with its members, effective priority and anything that
+// keeps it from pulling. Only its members and invitees can see it.
+func GetDrawGroup(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("groupid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	group, _, err := loadDrawGroup(database.DB, groupID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && drawGroupMember(group, userID) == nil) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+// InviteToDrawGroup lets the leader invite more students by email
+func InviteToDrawGroup(c *gin.Context) {
+	var request drawGroupInviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Emails) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one email is required"})
+		return
+	}
+
+	updateDrawGroup(c, "INVITE_DRAW_GROUP", func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error) {
+		if group.LeaderID != userID {
+			return nil, &drawGroupError{status: http.StatusForbidden, message: "Only the group leader can invite"}
+		}
+		if group.Status == models.DrawGroupStatusPulled {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "The group has already pulled"}
+		}
+		if err := inviteToDrawGroup(tx, group.GroupID, userID, request.Emails); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"invited": request.Emails}, reopenDrawGroup(tx, group)
+	})
+}
+
+// AcceptDrawGroupInvite joins the signed in user to a group they were invited to
+func AcceptDrawGroupInvite(c *gin.Context) {
+	updateDrawGroup(c, "ACCEPT_DRAW_GROUP", func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error) {
+		if drawGroupMember(group, userID).Status != models.DrawGroupMemberInvited {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "You have no open invitation to this group"}
+		}
+		if group.Status == models.DrawGroupStatusPulled {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "The group has already pulled"}
+		}
+
+		_, err := tx.Exec("UPDATE draw_group_members SET status = $1, responded_at = NOW() WHERE group_id = $2 AND user_id = $3", models.DrawGroupMemberAccepted, group.GroupID, userID)
+		if isUniqueViolation(err) {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "You are already in a group, leave it first"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"user_id": userID}, reopenDrawGroup(tx, group)
+	})
+}
+
+// DeclineDrawGroupInvite turns down an invitation
+func DeclineDrawGroupInvite(c *gin.Context) {
+	updateDrawGroup(c, "DECLINE_DRAW_GROUP", func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error) {
+		if drawGroupMember(group, userID).Status != models.DrawGroupMemberInvited {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "You have no open invitation to this group"}
+		}
+
+		_, err := tx.Exec("UPDATE draw_group_members SET status = $1, responded_at = NOW() WHERE group_id = $2 AND user_id = $3", models.DrawGroupMemberDeclined, group.GroupID, userID)
+		return map[string]interface{}{"user_id": userID}, err
+	})
+}
+
+// LeaveDrawGroup takes the signed in user out of a group. The group is disbanded if the
+// leader leaves.
+func LeaveDrawGroup(c *gin.Context) {
+	updateDrawGroup(c, "LEAVE_DRAW_GROUP", func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error) {
+		details := map[string]interface{}{"user_id": userID, "disbanded": group.LeaderID == userID}
+		if group.LeaderID == userID {
+			_, err := tx.Exec("DELETE FROM draw_groups WHERE group_id = $1", group.GroupID)
+			return details, err
+		}
+
+		_, err := tx.Exec("DELETE FROM draw_group_members WHERE group_id = $1 AND user_id = $2", group.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		return details, reopenDrawGroup(tx, group)
+	})
+}
+
+// ConfirmDrawGroup lets the leader lock in the group once every invitation is answered
+// and the accepted members can pull together
+func ConfirmDrawGroup(c *gin.Context) {
+	updateDrawGroup(c, "CONFIRM_DRAW_GROUP", func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error) {
+		if group.LeaderID != userID {
+			return nil, &drawGroupError{status: http.StatusForbidden, message: "Only the group leader can confirm the group"}
+		}
+		if group.Status != models.DrawGroupStatusForming {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "The group is already " + group.Status}
+		}
+		if len(group.Issues) > 0 {
+			return nil, &drawGroupError{status: http.StatusConflict, message: "The group cannot be confirmed", details: gin.H{"issues": group.Issues}}
+		}
+
+		_, err := tx.Exec("UPDATE draw_groups SET status = $1, confirmed_at = NOW() WHERE group_id = $2", models.DrawGroupStatusConfirmed, group.GroupID)
+		return map[string]interface{}{"effective_priority": group.EffectivePriority}, err
+	})
+}
+
+// PullDrawGroup pulls every accepted member of a confirmed group into a room with one of
+// the existing pull types. The body is a pull request without proposedOccupants.
+func PullDrawGroup(c *gin.Context) {
+	groupID, err := strconv.Atoi(c.Param("groupid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	var request models.OccupantUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	group, accepted, err := loadDrawGroup(database.DB, groupID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && drawGroupMember(group, userID) == nil) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return
+	}
+
+	if drawGroupMember(group, userID).Status != models.DrawGroupMemberAccepted {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only members of the group can pull it"})
+		return
+	}
+	if group.Status != models.DrawGroupStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": "The group must be confirmed before it can pull"})
+		return
+	}
+	if len(group.Issues) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The group cannot pull", "issues": group.Issues})
+		return
+	}
+
+	request.ProposedOccupants = models.IntArray{}
+	for _, u := range accepted {
+		request.ProposedOccupants = append(request.ProposedOccupants, u.Id)
+	}
+
+	// The group is checked again and marked pulled inside the pull's transaction,
+	// so a member leaving or joining meanwhile fails the pull instead of being missed
+	c.Set(pullCommitHookKey, func(tx *sql.Tx) error {
+		return markDrawGroupPulled(c, tx, groupID, request.ProposedOccupants)
+	})
+	pullWithMetrics(c, request)
+}
+
+// markDrawGroupPulled locks a group inside its pull's transaction, checks it is still
+// confirmed with exactly the members being pulled and marks it pulled
+func markDrawGroupPulled(c *gin.Context, tx *sql.Tx, groupID int, members []int) error {
+	err := tx.QueryRow("SELECT group_id FROM draw_groups WHERE group_id = $1 FOR UPDATE", groupID).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		rejectPull(c, http.StatusConflict, rejectedDrawGroup, gin.H{"error": "The group was disbanded while it was pulling"})
+		return err
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock group"})
+		return err
+	}
+
+	group, accepted, err := loadDrawGroup(tx, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return err
+	}
+	current := make([]int, 0, len(accepted))
+	for _, u := range accepted {
+		current = append(current, u.Id)
+	}
+	slices.Sort(current)
+	pulled := slices.Sorted(slices.Values(members))
+	if group.Status != models.DrawGroupStatusConfirmed || !slices.Equal(current, pulled) {
+		rejectPull(c, http.StatusConflict, rejectedDrawGroup, gin.H{"error": "The group changed while it was pulling, check it and try again"})
+		return errors.New("group changed during its pull")
+	}
+
+	if _, err := tx.Exec("UPDATE draw_groups SET status = $1 WHERE group_id = $2", models.DrawGroupStatusPulled, groupID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark the group as pulled"})
+		return err
+	}
+	return nil
+}
+
+// updateDrawGroup runs change against a locked group the signed in user belongs to or was
+// invited to, logs it as operation and responds with the group as it is afterwards
+func updateDrawGroup(c *gin.Context, operation string, change func(tx *sql.Tx, userID int, group *models.DrawGroup) (map[string]interface{}, error)) {
+	groupID, err := strconv.Atoi(c.Param("groupid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT group_id FROM draw_groups WHERE group_id = $1 FOR UPDATE", groupID).Scan(&groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to lock group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock group"})
+		return
+	}
+
+	previous, _, err := loadDrawGroup(tx, groupID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to load group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return
+	}
+	if drawGroupMember(previous, userID) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	details, err := change(tx, userID, previous)
+	if err != nil {
+		respondDrawGroupError(c, err)
+		return
+	}
+
+	current, _, err := loadDrawGroup(tx, groupID)
+	if errors.Is(err, sql.ErrNoRows) {
+		current = nil // disbanded
+	} else if err != nil {
+		logging.FromContext(c).Error("Failed to load group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load group"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit "+operation, "entity", groupID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+
+	logging.FromContext(c).Info("Committed "+operation, "entity", groupID, "by", userID)
+	if err := logging.LogOperation(c, operation, models.EntityTypeDrawGroup, strconv.Itoa(groupID), previous, current, details); err != nil {
+		logging.FromContext(c).Warn("Failed to log "+operation+" operation", "group", groupID, "error", err)
+	}
+
+	if current == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Group disbanded"})
+		return
+	}
+	c.JSON(http.StatusOK, current)
+}
+
+// inviteToDrawGroup invites students by email. Students who declined before are invited again.
+func inviteToDrawGroup(tx *sql.Tx, groupID int, leaderID int, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query("SELECT id, email FROM users WHERE LOWER(email) = ANY($1)", pq.Array(lowerAll(emails)))
+	if err != nil {
+		return err
+	}
+	found := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			rows.Close()
+			return err
+		}
+		found[strings.ToLower(email)] = id
+	}
+	rows.Close()
+
+	var unknown []string
+	for _, email := range emails {
+		if _, ok := found[strings.ToLower(email)]; !ok {
+			unknown = append(unknown, email)
+		}
+	}
+	if len(unknown) > 0 {
+		return &drawGroupError{status: http.StatusNotFound, message: "No student with these emails", details: gin.H{"emails": unknown}}
+	}
+
+	for _, id := range found {
+		if id == leaderID {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO draw_group_members (group_id, user_id, status) VALUES ($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO UPDATE SET status = EXCLUDED.status, invited_at = NOW(), responded_at = NULL
+			WHERE draw_group_members.status = $4`,
+			groupID, id, models.DrawGroupMemberInvited, models.DrawGroupMemberDeclined)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reopenDrawGroup puts a confirmed group back to forming after its members changed, so it
+// has to be confirmed again
+func reopenDrawGroup(tx *sql.Tx, group *models.DrawGroup) error {
+	if group.Status != models.DrawGroupStatusConfirmed {
+		return nil
+	}
+	_, err := tx.Exec("UPDATE draw_groups SET status = $1, confirmed_at = NULL WHERE group_id = $2", models.DrawGroupStatusForming, group.GroupID)
+	return err
+}
+
+// loadDrawGroup returns a group and its accepted members, with the group's effective
+// priority and the issues that keep it from pulling worked out
+func loadDrawGroup(q drawGroupQuerier, groupID int) (*models.DrawGroup, []models.UserRaw, error) {
+	group := &models.DrawGroup{Members: []models.DrawGroupMember{}, Issues: []string{}}
+	err := q.QueryRow("SELECT group_id, name, leader_id, status, created_at, confirmed_at FROM draw_groups WHERE group_id = $1", groupID).Scan(
+		&group.GroupID, &group.Name, &group.LeaderID, &group.Status, &group.CreatedAt, &group.ConfirmedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := q.Query(`
+		SELECT m.user_id, u.first_name, u.last_name, COALESCE(u.email, ''), m.status, m.invited_at, m.responded_at,
+			u.year, u.draw_number, u.in_dorm, u.preplaced, u.room_uuid, u.gender_preferences, COALESCE(rl.is_blocklisted, false)
+		FROM draw_group_members m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN user_rate_limits rl ON rl.email = u.email
+		WHERE m.group_id = $1
+		ORDER BY m.invited_at, m.user_id`, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var accepted []models.UserRaw
+	var blocklisted []string
+	pending := 0
+	for rows.Next() {
+		var m models.DrawGroupMember
+		var u models.UserRaw
+		var isBlocklisted bool
+		if err := rows.Scan(&m.UserID, &m.FirstName, &m.LastName, &m.Email, &m.Status, &m.InvitedAt, &m.RespondedAt,
+			&u.Year, &u.DrawNumber, &u.InDorm, &u.Preplaced, &u.RoomUUID, &u.GenderPreferences, &isBlocklisted); err != nil {
+			return nil, nil, err
+		}
+		group.Members = append(group.Members, m)
+
+		switch m.Status {
+		case models.DrawGroupMemberInvited:
+			pending++
+		case models.DrawGroupMemberAccepted:
+			u.Id, u.FirstName, u.LastName, u.Email = m.UserID, m.FirstName, m.LastName, m.Email
+			accepted = append(accepted, u)
+			if isBlocklisted {
+				blocklisted = append(blocklisted, m.FirstName+" "+m.LastName)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if pending > 0 && group.Status == models.DrawGroupStatusForming {
+		group.Issues = append(group.Issues, fmt.Sprintf("%d invitations are still pending", pending))
+	}
+	for _, name := range blocklisted {
+		group.Issues = append(group.Issues, name+" is blocklisted")
+	}
+	if group.Status != models.DrawGroupStatusPulled {
+		group.Issues = append(group.Issues, drawGroupIssues(accepted)...)
+	}
+
+	if len(accepted) > 0 {
+		best := DrawOrder(slices.Clone(accepted))[0]
+		priority := generateUserPriority(best, -1)
+		priority.Valid = true
+		group.EffectivePriority = &priority
+		group.PriorityUserID = &best.Id
+	}
+
+	var preferences [][]string
+	for _, u := range accepted {
+		if len(u.GenderPreferences) > 0 {
+			preferences = append(preferences, u.GenderPreferences)
+		}
+	}
+	group.GenderPreferences = findIntersectionOfPreferences(preferences)
+
+	return group, accepted, nil
+}
+
+// drawGroupIssues checks that the accepted members could pull together: none of them is
+// preplaced or already has a room, and their gender preferences overlap
+func drawGroupIssues(accepted []models.UserRaw) []string {
+	var issues []string
+	if len(accepted) == 0 {
+		return append(issues, "The group has no accepted members")
+	}
+
+	var preferences [][]string
+	for _, u := range accepted {
+		name := u.FirstName + " " + u.LastName
+		if u.Preplaced {
+			issues = append(issues, name+" is preplaced")
+		}
+		if u.RoomUUID != uuid.Nil {
+			issues = append(issues, name+" already has a room")
+		}
+		if len(u.GenderPreferences) > 0 {
+			preferences = append(preferences, u.GenderPreferences)
+		}
+	}
+
+	if len(preferences) > 1 && len(findIntersectionOfPreferences(preferences)) == 0 {
+		issues = append(issues, "The members have no gender preference in common")
+	}
+	return issues
+}
+
+// drawGroupMember returns the user's membership of a group, or nil if they were never invited
+func drawGroupMember(group *models.DrawGroup, userID int) *models.DrawGroupMember {
+	for i := range group.Members {
+		if group.Members[i].UserID == userID {
+			return &group.Members[i]
+		}
+	}
+	return nil
+}
+
+func respondDrawGroupError(c *gin.Context, err error) {
+	var groupErr *drawGroupError
+	if !errors.As(err, &groupErr) {
+		logging.FromContext(c).Error("Failed to update group", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+
+	response := gin.H{"error": groupErr.message}
+	for k, v := range groupErr.details {
+		response[k] = v
+	}
+	c.JSON(groupErr.status, response)
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a duplicate key
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, v := range values {
+		lowered[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	return lowered
+}
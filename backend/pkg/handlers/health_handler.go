@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"roomdraw/backend/pkg/config"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// googleKeysMaxAge is how stale the Google key cache may get before the server
+// reports itself as not ready. Keys are refreshed after 24 hours, so anything
+// older means the refresh has been failing.
+const googleKeysMaxAge = 25 * time.Hour
+
+// HealthzHandler reports whether the server is alive and can reach the database
+func HealthzHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := database.DB.PingContext(ctx); err != nil {
+		logging.FromContext(c).Error("Health check failed to ping database", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "database": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ReadyzHandler reports whether the server should receive traffic: the
+// database answers, Google keys for JWT validation are fresh, and the write
+// queue is not draining for a shutdown
+func ReadyzHandler(auth config.AuthConfig, queue *middleware.RequestQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		checks := gin.H{}
+		ready := true
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := database.DB.PingContext(ctx); err != nil {
+			checks["database"] = err.Error()
+			ready = false
+		} else {
+			checks["database"] = "ok"
+		}
+
+		if auth.Required {
+			// only the cache is checked, RefreshGooglePublicKeys keeps it filled
+			age, loaded := middleware.GooglePublicKeysAge()
+			switch {
+			case !loaded:
+				checks["googleKeys"] = "not loaded"
+				ready = false
+			case age > googleKeysMaxAge:
+				checks["googleKeys"] = "stale, last refreshed " + age.Round(time.Minute).String() + " ago"
+				ready = false
+			default:
+				checks["googleKeys"] = "ok"
+			}
+		}
+
+		if queue.Draining() {
+			checks["writeQueue"] = "draining"
+			ready = false
+		} else {
+			checks["writeQueue"] = "ok"
+		}
+
+		if !ready {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": checks})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"roomdraw/backend/pkg/database"
+)
+
+// IntegrityIssue is one inconsistency found in the draw tables
+type IntegrityIssue struct {
+	Check    string `json:"check"`
+	EntityID string `json:"entityId"`
+	Detail   string `json:"detail"`
+}
+
+// integrityChecks are queries that each return (entity id, detail) for every
+// row that breaks the rule they are named after
+var integrityChecks = []struct {
+	name  string
+	query string
+}{
+	{"occupancy_count", `
+        SELECT room_uuid::text, room_id || ' in ' || dorm_name || ' has current_occupancy ' || current_occupancy || ' but ' || COALESCE(cardinality(occupants), 0) || ' occupants'
+        FROM rooms WHERE current_occupancy <> COALESCE(cardinality(occupants), 0)`},
+	{"over_capacity", `
+        SELECT room_uuid::text, room_id || ' in ' || dorm_name || ' has ' || current_occupancy || ' occupants but fits ' || max_occupancy
+        FROM rooms WHERE current_occupancy > max_occupancy`},
+	{"frosh_room_occupied", `
+        SELECT room_uuid::text, room_id || ' in ' || dorm_name || ' has frosh and ' || current_occupancy || ' occupants'
+        FROM rooms WHERE has_frosh AND current_occupancy > 0`},
+	{"unknown_occupant", `
+        SELECT r.room_uuid::text, r.room_id || ' in ' || r.dorm_name || ' lists user ' || o.id || ' who does not exist'
+        FROM rooms r CROSS JOIN LATERAL unnest(r.occupants) AS o(id)
+        WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = o.id)`},
+	{"occupant_in_many_rooms", `
+        SELECT o.id::text, 'user ' || o.id || ' is listed in ' || COUNT(*) || ' rooms'
+        FROM rooms r CROSS JOIN LATERAL unnest(r.occupants) AS o(id)
+        GROUP BY o.id HAVING COUNT(*) > 1`},
+	{"user_room_mismatch", `
+        SELECT o.id::text, 'user ' || o.id || ' is in room ' || r.room_id || ' in ' || r.dorm_name || ' but users.room_uuid is ' || COALESCE(u.room_uuid::text, 'null')
+        FROM rooms r CROSS JOIN LATERAL unnest(r.occupants) AS o(id)
+        JOIN users u ON u.id = o.id
+        WHERE u.room_uuid IS DISTINCT FROM r.room_uuid`},
+	{"user_room_stale", `
+        SELECT u.id::text, 'user ' || u.id || ' points at room ' || u.room_uuid || ' which does not list them'
+        FROM users u
+        WHERE u.room_uuid IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM rooms r WHERE r.room_uuid = u.room_uuid AND u.id = ANY(r.occupants))`},
+	{"room_missing_from_suite", `
+        SELECT r.room_uuid::text, r.room_id || ' in ' || r.dorm_name || ' is not in the rooms list of its suite'
+        FROM rooms r JOIN suites s ON s.suite_uuid = r.suite_uuid
+        WHERE NOT (r.room_uuid = ANY(COALESCE(s.rooms, '{}')))`},
+	{"lock_pull_outside_suite", `
+        SELECT s.suite_uuid::text, 'suite in ' || s.dorm_name || ' floor ' || s.floor || ' is lock pulled by a room outside it'
+        FROM suites s
+        WHERE s.lock_pulled_room IS NOT NULL
+          AND NOT EXISTS (SELECT 1 FROM rooms r WHERE r.room_uuid = s.lock_pulled_room AND r.suite_uuid = s.suite_uuid)`},
+	{"reslife_preplaced_without_room", `
+        SELECT u.id::text, u.reslife_role || ' ' || u.id || ' is preplaced but has no room'
+        FROM users u
+        WHERE u.reslife_role IN ('mentor', 'proctor') AND u.preplaced AND u.room_uuid IS NULL`},
+	{"disbanded_group_in_use", `
+        SELECT r.room_uuid::text, r.room_id || ' in ' || r.dorm_name || ' belongs to disbanded suite group ' || g.sgroup_uuid
+        FROM rooms r JOIN suitegroups g ON g.sgroup_uuid = r.sgroup_uuid
+        WHERE g.disbanded`},
+}
+
+// CheckIntegrity runs every integrity check against the live tables and
+// returns the issues found, an empty slice when the draw is consistent
+func CheckIntegrity(ctx context.Context) ([]IntegrityIssue, error) {
+	issues := []IntegrityIssue{}
+
+	for _, check := range integrityChecks {
+		rows, err := database.DB.QueryContext(ctx, check.query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			issue := IntegrityIssue{Check: check.name}
+			if err := rows.Scan(&issue.EntityID, &issue.Detail); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			issues = append(issues, issue)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return issues, nil
+}
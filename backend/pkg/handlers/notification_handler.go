@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"net/http"
+	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/metrics"
 	"roomdraw/backend/pkg/models"
 	"roomdraw/backend/pkg/services"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,10 +18,14 @@ import (
 
 var emailService *services.EmailService
 
+// pendingNotifications tracks notifications sent in the background so a
+// shutdown can wait for them instead of dropping them
+var pendingNotifications sync.WaitGroup
+
 // InitializeEmailService initializes the email service with loaded configuration
-func InitializeEmailService() {
-	log.Println("Initializing email service from handlers...")
-	emailService = services.NewEmailService()
+func InitializeEmailService(email config.EmailConfig) {
+	slog.Info("Initializing email service")
+	emailService = services.NewEmailService(email)
 }
 
 func SetNotificationPreference(c *gin.Context) {
@@ -41,11 +49,12 @@ func SetNotificationPreference(c *gin.Context) {
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	defer func() {
 		if err != nil {
@@ -95,37 +104,92 @@ func GetNotificationPreference(c *gin.Context) {
 	c.JSON(http.StatusOK, pref)
 }
 
+// SendBumpNotificationAsync sends a bump notification in the background
+func SendBumpNotificationAsync(userID int, roomID string, dormName string) {
+	pendingNotifications.Add(1)
+	go func() {
+		defer pendingNotifications.Done()
+		SendBumpNotification(userID, roomID, dormName)
+	}()
+}
+
+// WaitForNotifications blocks until every background notification has been
+// sent, or ctx is done
+func WaitForNotifications(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		pendingNotifications.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func SendBumpNotification(userID int, roomID string, dormName string) {
+	slog.Debug("Sending bump notification", "user", userID, "room", roomID, "dorm", dormName)
+	notifyUser(userID, models.NotificationChannelBumps, func(user models.UserRaw) error {
+		return emailService.SendBumpNotification(user, roomID, dormName)
+	})
+}
+
+// notifyUser emails a user through send if they opted in to the channel and have
+// an email address, and counts the outcome
+func notifyUser(userID int, channel string, send func(user models.UserRaw) error) {
+	if emailService == nil {
+		slog.Info("Email service is not initialized, skipping notification", "user", userID, "channel", channel)
+		metrics.NotificationsTotal.WithLabelValues("failed").Inc()
+		return
+	}
+
 	var user models.UserRaw
 	var email sql.NullString
 	err := database.DB.QueryRow(
-		"SELECT id, first_name, last_name, email, notifications_enabled FROM users WHERE id = $1",
-		userID,
+		"SELECT id, first_name, last_name, email, notifications_enabled AND $2 = ANY(notification_channels) FROM users WHERE id = $1",
+		userID, channel,
 	).Scan(&user.Id, &user.FirstName, &user.LastName, &email, &user.NotificationsEnabled)
-
 	if err != nil {
-		log.Printf("Failed to fetch user: %v", err)
-		return // User not found
+		slog.Error("Failed to fetch user for notification", "user", userID, "channel", channel, "error", err)
+		metrics.NotificationsTotal.WithLabelValues("user_lookup_failed").Inc()
+		return
 	}
-
-	// Handle NULL email
 	if !email.Valid || email.String == "" {
-		log.Printf("User %d has no email, skipping notification", userID)
+		slog.Info("User has no email, skipping notification", "user", userID, "channel", channel)
+		metrics.NotificationsTotal.WithLabelValues("no_email").Inc()
 		return
 	}
 	user.Email = email.String
 
-	log.Println("User: ", user)
-
-	log.Println("Checking if user has opted in ...")
-
 	if !user.NotificationsEnabled {
-		log.Println("User hasn't opted in or preferences not found")
-		return // User hasn't opted in or preferences not found
+		slog.Info("User has not opted in to notifications, skipping", "user", userID, "channel", channel)
+		metrics.NotificationsTotal.WithLabelValues("opted_out").Inc()
+		return
 	}
 
-	err = emailService.SendBumpNotification(user, roomID, dormName)
-	if err != nil {
-		log.Printf("Failed to send bump notification: %v", err)
+	if err := send(user); err != nil {
+		slog.Error("Failed to send notification", "user", userID, "channel", channel, "error", err)
+		metrics.NotificationsTotal.WithLabelValues("failed").Inc()
+		return
 	}
+	metrics.NotificationsTotal.WithLabelValues("sent").Inc()
+}
+
+// SendFroshNotificationAsync tells a student in the background that frosh moved
+// into or out of their suite
+func SendFroshNotificationAsync(userID int, change string) {
+	pendingNotifications.Add(1)
+	go func() {
+		defer pendingNotifications.Done()
+		sendFroshNotification(userID, change)
+	}()
+}
+
+func sendFroshNotification(userID int, change string) {
+	notifyUser(userID, models.NotificationChannelFrosh, func(user models.UserRaw) error {
+		return emailService.SendFroshNotification(user, change)
+	})
 }
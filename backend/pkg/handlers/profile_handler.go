@@ -0,0 +1,430 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxDisplayNameLength bounds the display name a student can choose
+const maxDisplayNameLength = 100
+
+// profileFields are the profile fields an admin can open an edit window for
+var profileFields = []string{
+	models.ProfileFieldGenderPreferences,
+	models.ProfileFieldNotifications,
+	models.ProfileFieldDisplayName,
+}
+
+// notificationChannels are the kinds of email a student can opt in or out of
+var notificationChannels = []string{
+	models.NotificationChannelBumps,
+	models.NotificationChannelFrosh,
+	models.NotificationChannelFavorites,
+}
+
+// cleanGenderPreferences trims the preferences and drops duplicates, and reports
+// false if any of them is blank
+func cleanGenderPreferences(preferences []string) ([]string, bool) {
+	cleaned := make([]string, 0, len(preferences))
+	for _, preference := range preferences {
+		preference = strings.TrimSpace(preference)
+		if preference == "" {
+			return nil, false
+		}
+		if !slices.Contains(cleaned, preference) {
+			cleaned = append(cleaned, preference)
+		}
+	}
+	return cleaned, true
+}
+
+// unknownGenderPreference returns the first preference that is not one of
+// models.GenderPreferences
+func unknownGenderPreference(preferences []string) (string, bool) {
+	for _, preference := range preferences {
+		if !slices.Contains(models.GenderPreferences, preference) {
+			return preference, true
+		}
+	}
+	return "", false
+}
+
+// loadProfileEditWindows returns the edit windows that have not closed yet,
+// soonest first
+func loadProfileEditWindows(q drawGroupQuerier, now time.Time) ([]models.ProfileEditWindow, error) {
+	rows, err := q.Query(`
+        SELECT window_id, field, opens_at, closes_at, created_by
+        FROM profile_edit_windows
+        WHERE closes_at > $1
+        ORDER BY opens_at, window_id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []models.ProfileEditWindow{}
+	for rows.Next() {
+		var w models.ProfileEditWindow
+		if err := rows.Scan(&w.WindowID, &w.Field, &w.OpensAt, &w.ClosesAt, &w.CreatedBy); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// editableProfileFields returns the fields with an edit window open at now
+func editableProfileFields(windows []models.ProfileEditWindow, now time.Time) []string {
+	editable := []string{}
+	for _, w := range windows {
+		if !now.Before(w.OpensAt) && now.Before(w.ClosesAt) && !slices.Contains(editable, w.Field) {
+			editable = append(editable, w.Field)
+		}
+	}
+	return editable
+}
+
+// loadProfile reads a student's profile and the edit windows that apply to it
+func loadProfile(q drawGroupQuerier, userID int, now time.Time) (models.UserProfile, error) {
+	var profile models.UserProfile
+	var genderPreferences, channels pq.StringArray
+	var roomUUID uuid.NullUUID
+	err := q.QueryRow(`
+        SELECT id, email, first_name, last_name, display_name, gender_preferences,
+               notifications_enabled, notification_channels, room_uuid
+        FROM users
+        WHERE id = $1`, userID).Scan(
+		&profile.UserID, &profile.Email, &profile.FirstName, &profile.LastName, &profile.DisplayName,
+		&genderPreferences, &profile.NotificationsEnabled, &channels, &roomUUID,
+	)
+	if err != nil {
+		return profile, err
+	}
+	profile.GenderPreferences = []string(genderPreferences)
+	if profile.GenderPreferences == nil {
+		profile.GenderPreferences = []string{}
+	}
+	profile.NotificationChannels = []string(channels)
+	if roomUUID.Valid {
+		profile.RoomUUID = &roomUUID.UUID
+	}
+
+	profile.EditWindows, err = loadProfileEditWindows(q, now)
+	if err != nil {
+		return profile, err
+	}
+	profile.Editable = editableProfileFields(profile.EditWindows, now)
+	return profile, nil
+}
+
+// GetMyProfile returns the signed in student's profile, which fields they can
+// edit right now, and the open and upcoming edit windows
+func GetMyProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := loadProfile(database.DB, userID, time.Now())
+	if err != nil {
+		logging.FromContext(c).Error("Error loading profile", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateMyProfile changes the signed in student's display name, gender
+// preferences or notification settings. Each field can only be changed while an
+// admin has an edit window open for it. Changing gender preferences recomputes
+// the preference of the student's suite.
+func UpdateMyProfile(c *gin.Context) {
+	var request struct {
+		DisplayName          *string   `json:"displayName"`
+		GenderPreferences    *[]string `json:"genderPreferences"`
+		NotificationsEnabled *bool     `json:"notificationsEnabled"`
+		NotificationChannels *[]string `json:"notificationChannels"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var requested []string
+	if request.DisplayName != nil {
+		name := strings.TrimSpace(*request.DisplayName)
+		if len(name) > maxDisplayNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Display name is too long"})
+			return
+		}
+		request.DisplayName = &name
+		requested = append(requested, models.ProfileFieldDisplayName)
+	}
+	if request.GenderPreferences != nil {
+		preferences, ok := cleanGenderPreferences(*request.GenderPreferences)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Gender preferences cannot be blank"})
+			return
+		}
+		if preference, ok := unknownGenderPreference(preferences); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown gender preference", "genderPreference": preference})
+			return
+		}
+		request.GenderPreferences = &preferences
+		requested = append(requested, models.ProfileFieldGenderPreferences)
+	}
+	if request.NotificationChannels != nil {
+		channels := []string{}
+		for _, channel := range *request.NotificationChannels {
+			if !slices.Contains(notificationChannels, channel) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Notification channels must be bumps, frosh or favorites", "channel": channel})
+				return
+			}
+			if !slices.Contains(channels, channel) {
+				channels = append(channels, channel)
+			}
+		}
+		request.NotificationChannels = &channels
+	}
+	if request.NotificationsEnabled != nil || request.NotificationChannels != nil {
+		requested = append(requested, models.ProfileFieldNotifications)
+	}
+	if len(requested) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	// Lock the student first so a bump cannot move them to another suite
+	// between reading their room and recomputing its preference
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&userID); err != nil {
+		logging.FromContext(c).Error("Error locking user", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+
+	now := time.Now()
+	previous, err := loadProfile(tx, userID, now)
+	if err != nil {
+		logging.FromContext(c).Error("Error loading profile", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+	for _, field := range requested {
+		if !slices.Contains(previous.Editable, field) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This field cannot be edited right now", "field": field})
+			return
+		}
+	}
+
+	// The queue cannot know the caller's suite, so lock it here so the
+	// recomputed preference cannot race a pull. Pulls take the suite before the
+	// students in it, so waiting for it while holding the student could
+	// deadlock; give up instead if a pull holds it.
+	var suiteUUID uuid.NullUUID
+	if request.GenderPreferences != nil && previous.RoomUUID != nil {
+		if err := tx.QueryRow("SELECT suite_uuid FROM rooms WHERE room_uuid = $1", *previous.RoomUUID).Scan(&suiteUUID); err != nil {
+			logging.FromContext(c).Error("Error finding suite of room", "room", *previous.RoomUUID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find your suite"})
+			return
+		}
+		locked, err := database.TryLockKeys(c.Request.Context(), tx, "suite:"+suiteUUID.UUID.String())
+		if err != nil {
+			logging.FromContext(c).Error("Error locking suite", "suite", suiteUUID.UUID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock your suite"})
+			return
+		}
+		if !locked {
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Your suite is being changed, please try again shortly"})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+        UPDATE users
+        SET display_name = COALESCE($2, display_name),
+            gender_preferences = COALESCE($3::varchar[], gender_preferences),
+            notifications_enabled = COALESCE($4, notifications_enabled),
+            notification_channels = COALESCE($5::varchar[], notification_channels),
+            notification_updated_at = CASE WHEN $4::boolean IS NULL AND $5::varchar[] IS NULL THEN notification_updated_at ELSE $6 END
+        WHERE id = $1`,
+		userID, request.DisplayName, nullableStringArray(request.GenderPreferences),
+		request.NotificationsEnabled, nullableStringArray(request.NotificationChannels), now)
+	if err != nil {
+		logging.FromContext(c).Error("Error updating profile", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	if suiteUUID.Valid {
+		if err := UpdateSuiteGenderPreferencesBySuiteUUID(tx, suiteUUID.UUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update suite gender preferences"})
+			return
+		}
+	}
+
+	updated, err := loadProfile(tx, userID, now)
+	if err != nil {
+		logging.FromContext(c).Error("Error loading profile", "user", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
+		return
+	}
+
+	logging.FromContext(c).Info("Committed UPDATE_PROFILE", "user", userID, "fields", requested)
+
+	if err := logging.LogOperation(c, "UPDATE_PROFILE", models.EntityTypeUser, strconv.Itoa(userID),
+		previous, updated, map[string]interface{}{"fields": requested}); err != nil {
+		logging.FromContext(c).Warn("Failed to log UPDATE_PROFILE operation", "user", userID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// nullableStringArray passes a missing list to SQL as NULL
+func nullableStringArray(values *[]string) interface{} {
+	if values == nil {
+		return nil
+	}
+	return pq.StringArray(*values)
+}
+
+// GetProfileEditWindows lists every profile edit window, newest first
+func GetProfileEditWindows(c *gin.Context) {
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
+        SELECT window_id, field, opens_at, closes_at, created_by
+        FROM profile_edit_windows
+        ORDER BY opens_at DESC, window_id DESC`)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying profile edit windows", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile edit windows"})
+		return
+	}
+	defer rows.Close()
+
+	windows := []models.ProfileEditWindow{}
+	for rows.Next() {
+		var w models.ProfileEditWindow
+		if err := rows.Scan(&w.WindowID, &w.Field, &w.OpensAt, &w.ClosesAt, &w.CreatedBy); err != nil {
+			logging.FromContext(c).Error("Error scanning profile edit window", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan profile edit windows"})
+			return
+		}
+		windows = append(windows, w)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(c).Error("Error iterating profile edit windows", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile edit windows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, windows)
+}
+
+// CreateProfileEditWindow opens a profile field for editing between opensAt and
+// closesAt (RFC 3339)
+func CreateProfileEditWindow(c *gin.Context) {
+	var request struct {
+		Field    string    `json:"field" binding:"required"`
+		OpensAt  time.Time `json:"opensAt" binding:"required"`
+		ClosesAt time.Time `json:"closesAt" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !slices.Contains(profileFields, request.Field) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field must be gender_preferences, notifications or display_name"})
+		return
+	}
+	if !request.ClosesAt.After(request.OpensAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "closesAt must be after opensAt"})
+		return
+	}
+
+	email, _ := c.Get("email")
+	createdBy, _ := email.(string)
+
+	window := models.ProfileEditWindow{
+		Field:     request.Field,
+		OpensAt:   request.OpensAt,
+		ClosesAt:  request.ClosesAt,
+		CreatedBy: createdBy,
+	}
+	err := database.DB.QueryRowContext(c.Request.Context(), `
+        INSERT INTO profile_edit_windows (field, opens_at, closes_at, created_by)
+        VALUES ($1, $2, $3, $4) RETURNING window_id`,
+		window.Field, window.OpensAt, window.ClosesAt, window.CreatedBy).Scan(&window.WindowID)
+	if err != nil {
+		logging.FromContext(c).Error("Error creating profile edit window", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile edit window"})
+		return
+	}
+
+	if err := logging.LogOperation(c, "CREATE_PROFILE_WINDOW", models.EntityTypeProfileWindow, strconv.Itoa(window.WindowID), nil, window, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log CREATE_PROFILE_WINDOW operation", "window", window.WindowID, "error", err)
+	}
+
+	c.JSON(http.StatusCreated, window)
+}
+
+// DeleteProfileEditWindow removes an edit window, closing it if it is open
+func DeleteProfileEditWindow(c *gin.Context) {
+	windowID, err := strconv.Atoi(c.Param("windowid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window id"})
+		return
+	}
+
+	var window models.ProfileEditWindow
+	err = database.DB.QueryRowContext(c.Request.Context(), `
+        DELETE FROM profile_edit_windows WHERE window_id = $1
+        RETURNING window_id, field, opens_at, closes_at, created_by`, windowID).Scan(
+		&window.WindowID, &window.Field, &window.OpensAt, &window.ClosesAt, &window.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile edit window not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Error deleting profile edit window", "window", windowID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile edit window"})
+		return
+	}
+
+	if err := logging.LogOperation(c, "DELETE_PROFILE_WINDOW", models.EntityTypeProfileWindow, strconv.Itoa(windowID), window, nil, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log DELETE_PROFILE_WINDOW operation", "window", windowID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile edit window deleted"})
+}
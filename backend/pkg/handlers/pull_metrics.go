@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// pullRejection is the fixed reason a pull turned away by the draw rules is
+// counted under. Each rejection site names its own, so the messages can be
+// reworded freely.
+type pullRejection string
+
+const (
+	rejectedInvalidRequest                pullRejection = "invalid_request"
+	rejectedRoomNotFound                  pullRejection = "room_not_found"
+	rejectedDuplicateOccupant             pullRejection = "duplicate_occupant"
+	rejectedAlreadyPlaced                 pullRejection = "already_placed"
+	rejectedPreplacedOccupant             pullRejection = "preplaced_occupant"
+	rejectedPreplacedRoom                 pullRejection = "preplaced_room"
+	rejectedReslifeRoom                   pullRejection = "reslife_room"
+	rejectedFroshRoom                     pullRejection = "frosh_room"
+	rejectedLockPulledRoom                pullRejection = "lock_pulled_room"
+	rejectedOverCapacity                  pullRejection = "over_capacity"
+	rejectedOutranked                     pullRejection = "outranked"
+	rejectedIncompatibleGenderPreferences pullRejection = "incompatible_gender_preferences"
+	rejectedSuiteGenderPreference         pullRejection = "suite_gender_preference"
+	rejectedPullLeader                    pullRejection = "pull_leader"
+	rejectedRoomSize                      pullRejection = "room_size"
+	rejectedSuitePolicy                   pullRejection = "suite_policy"
+	rejectedDrawGroup                     pullRejection = "draw_group"
+)
+
+// pullRejectionKey is the context key a rejected pull records its reason under
+const pullRejectionKey = "pull_rejection"
+
+// rejectPull answers a pull the draw rules turn away with status and response,
+// and records reason for the pull metrics
+func rejectPull(c *gin.Context, status int, reason pullRejection, response gin.H) {
+	c.Set(pullRejectionKey, reason)
+	c.JSON(status, response)
+}
+
+// pullRejectionReason returns the reason the pull was rejected with, or other
+// if the handler answered with an error without naming one
+func pullRejectionReason(c *gin.Context) string {
+	if reason, ok := c.Get(pullRejectionKey); ok {
+		return string(reason.(pullRejection))
+	}
+	return "other"
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"roomdraw/backend/pkg/models"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// The rules below decide the priority a pull would give a room, or why the draw
+// turns it away. They only look at what they are given, so the pull handlers run
+// them inside their transaction and the eligibility search runs them against a
+// snapshot of the draw, and both reach the same answer.
+
+// pullRuleError is a pull the draw rules turn away, with the reason it is counted under
+type pullRuleError struct {
+	reason  pullRejection
+	message string
+}
+
+func (e *pullRuleError) Error() string { return e.message }
+
+// rejectPullRule answers a pull with the rule that turned it away
+func rejectPullRule(c *gin.Context, err error) {
+	var ruleErr *pullRuleError
+	if errors.As(err, &ruleErr) {
+		rejectPull(c, http.StatusBadRequest, ruleErr.reason, gin.H{"error": ruleErr.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// forfeitInDorm copies users, dropping everyone's in dorm if any of them does not have
+// in dorm for the dorm
+func forfeitInDorm(users []models.UserRaw, dorm int) []models.UserRaw {
+	users = slices.Clone(users)
+	for _, u := range users {
+		if u.InDorm != dorm {
+			for i := range users {
+				users[i].InDorm = 0
+			}
+			break
+		}
+	}
+	return users
+}
+
+// checkBump refuses a pull with proposed priority that cannot take room from its
+// current occupants
+func checkBump(proposed models.PullPriority, room models.RoomRaw) error {
+	if room.PullPriority.PullType == 3 {
+		return &pullRuleError{rejectedLockPulledRoom, "Cannot bump a lock pulled room"}
+	}
+	if !comparePullPriority(proposed, room.PullPriority) {
+		return &pullRuleError{rejectedOutranked, "Proposed occupants do not have higher priority than current occupants"}
+	}
+	return nil
+}
+
+// selfPullPriority returns the priority the occupants would self pull room with
+func selfPullPriority(occupants []models.UserRaw, room models.RoomRaw) (models.PullPriority, error) {
+	occupants = forfeitInDorm(occupants, room.Dorm)
+	proposed := generateUserPriority(sortUsersByPriority(occupants, room.Dorm)[0], room.Dorm)
+	proposed.Valid = true
+	proposed.PullType = 1
+
+	return proposed, checkBump(proposed, room)
+}
+
+// lockPullPriority returns the priority the occupants would lock pull room with. Every
+// other room of the suite without frosh has to be full, and at least one of them
+// taken by a pull rather than preplaced.
+func lockPullPriority(occupants []models.UserRaw, room models.RoomRaw, suite models.SuiteRaw, suiteRooms []models.RoomRaw) (models.PullPriority, error) {
+	if !suite.CanLockPull {
+		return models.PullPriority{}, &pullRuleError{rejectedSuitePolicy, "Lock pull is not allowed for the suite"}
+	}
+
+	nonPreplacedRooms := 0
+	for _, r := range suiteRooms {
+		if r.RoomUUID == room.RoomUUID || r.HasFrosh {
+			continue
+		}
+		if r.CurrentOccupancy < r.MaxOccupancy {
+			return models.PullPriority{}, &pullRuleError{rejectedRoomSize, "One or more rooms in the suite are not full " + r.RoomID}
+		}
+		if !r.PullPriority.IsPreplaced {
+			nonPreplacedRooms++
+		}
+	}
+	if nonPreplacedRooms == 0 {
+		return models.PullPriority{}, &pullRuleError{rejectedPreplacedRoom, "Cannot lock pull into a suite with all preplaced rooms"}
+	}
+
+	proposed := generateUserPriority(sortUsersByPriority(slices.Clone(occupants), room.Dorm)[0], room.Dorm)
+	proposed.Valid = true
+	proposed.PullType = 3
+	proposed.Inherited.Valid = true
+
+	return proposed, checkBump(proposed, room)
+}
+
+// normalPullPriority returns the priority the occupants would take room with when
+// pulled by the single occupant of leader, who has to outrank them. The pulled room
+// inherits the leader's priority.
+func normalPullPriority(occupants []models.UserRaw, room, leader models.RoomRaw, policy models.SuitePullPolicy) (models.PullPriority, error) {
+	isDrinkwardSuiteTriple := policy.IsNormalPullTriple(room.RoomID)
+	if room.MaxOccupancy > 1 && !isDrinkwardSuiteTriple {
+		return models.PullPriority{}, &pullRuleError{rejectedSuitePolicy, "You may only initiate a normal pull for singles other than in a Drinkward suite"}
+	}
+	if leader.SuiteUUID != room.SuiteUUID {
+		return models.PullPriority{}, &pullRuleError{rejectedPullLeader, "Pull leader is not in the same suite"}
+	}
+	if leader.CurrentOccupancy != 1 {
+		return models.PullPriority{}, &pullRuleError{rejectedRoomSize, "You can only initiate a normal pull with a single"}
+	}
+
+	leaderPriority := leader.PullPriority
+	// accounts for in dorm forfeit
+	leaderEffectiveInDorm := leaderPriority.HasInDorm
+	if leaderPriority.Inherited.Valid {
+		leaderEffectiveInDorm = leaderPriority.Inherited.HasInDorm
+	}
+
+	if isDrinkwardSuiteTriple {
+		if !leaderPriority.HasInDorm {
+			return models.PullPriority{}, &pullRuleError{rejectedSuitePolicy, "You may only initiate a normal pull for singles in a Drinkward suite if the pull leader has in dorm"}
+		}
+		if len(occupants) != 3 {
+			return models.PullPriority{}, &pullRuleError{rejectedRoomSize, "The triple being pulled with in dorm must have 3 occupants"}
+		}
+	}
+
+	// an in dorm leader can only pull other in dorm users, except three people into a Drinkward triple
+	sortedOccupants := sortUsersByPriority(slices.Clone(occupants), room.Dorm)
+	if leaderEffectiveInDorm && !isDrinkwardSuiteTriple {
+		for _, occupant := range sortedOccupants {
+			if !generateUserPriority(occupant, room.Dorm).HasInDorm {
+				return models.PullPriority{}, &pullRuleError{rejectedPullLeader, "Pull leader has in dorm and proposed occupants do not"}
+			}
+		}
+	}
+
+	proposed := generateUserPriority(sortedOccupants[0], room.Dorm)
+	proposed.Valid = true
+	proposed.PullType = 2
+	if !comparePullPriority(leaderPriority, proposed) {
+		return models.PullPriority{}, &pullRuleError{rejectedOutranked, "Pull leader does not have higher priority than proposed occupants"}
+	}
+
+	proposed.Inherited.Valid = true
+	if leaderPriority.Inherited.Valid {
+		proposed.Inherited.DrawNumber = leaderPriority.Inherited.DrawNumber
+		proposed.Inherited.HasInDorm = leaderPriority.Inherited.HasInDorm
+		proposed.Inherited.Year = leaderPriority.Inherited.Year
+	} else {
+		proposed.Inherited.DrawNumber = leaderPriority.DrawNumber
+		proposed.Inherited.HasInDorm = leaderPriority.HasInDorm
+		proposed.Inherited.Year = leaderPriority.Year
+	}
+
+	return proposed, checkBump(proposed, room)
+}
+
+// alternativePullPriority returns the priority the occupants would take room with in
+// an alternative pull led by the full room leader, whose occupants are
+// leaderOccupants, and the priority of the suite group the two rooms form: the second
+// best of everyone in both rooms, which the pulled room inherits
+func alternativePullPriority(occupants, leaderOccupants []models.UserRaw, room, leader models.RoomRaw, suite models.SuiteRaw) (proposed, groupPriority models.PullPriority, err error) {
+	if !suite.AlternativePull {
+		return proposed, groupPriority, &pullRuleError{rejectedSuitePolicy, "Alternative pull is not allowed for the suite"}
+	}
+	if leader.SuiteUUID != room.SuiteUUID {
+		return proposed, groupPriority, &pullRuleError{rejectedPullLeader, "Pull leader is not in the same suite"}
+	}
+	if leader.CurrentOccupancy == 0 || leader.CurrentOccupancy != leader.MaxOccupancy {
+		return proposed, groupPriority, &pullRuleError{rejectedRoomSize, "You can only initiate an alternative pull with a full room"}
+	}
+	if leader.SGroupUUID != uuid.Nil {
+		return proposed, groupPriority, &pullRuleError{rejectedPullLeader, "Pull leader is in a suite group for alternative pull"}
+	}
+
+	occupants = forfeitInDorm(occupants, room.Dorm)
+	allOccupants := forfeitInDorm(append(slices.Clone(occupants), leaderOccupants...), room.Dorm)
+
+	proposed = generateUserPriority(sortUsersByPriority(occupants, room.Dorm)[0], room.Dorm)
+	proposed.Valid = true
+	proposed.PullType = 4
+
+	groupPriority = generateUserPriority(sortUsersByPriority(allOccupants, room.Dorm)[1], room.Dorm)
+	proposed.Inherited.Valid = true
+	proposed.Inherited.DrawNumber = groupPriority.DrawNumber
+	proposed.Inherited.HasInDorm = groupPriority.HasInDorm
+	proposed.Inherited.Year = groupPriority.Year
+
+	return proposed, groupPriority, checkBump(proposed, room)
+}
+
+// genderPreferenceBlocks returns the occupants a suite's gender preference keeps out of
+// room, none if the suite has no preference or the pull outranks everyone staying in
+// the suite
+func genderPreferenceBlocks(preferences []string, priority models.PullPriority, room models.RoomRaw, suiteRooms []models.RoomRaw, occupants []models.UserRaw) []models.UserRaw {
+	if len(preferences) == 0 || outranksSuite(priority, room.RoomUUID, suiteRooms) {
+		return nil
+	}
+	return genderPreferenceConflicts(preferences, occupants)
+}
@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type reslifeRoomRequest struct {
+	// RoomUUID is the room to hold for ResLife, or the zero UUID to stop holding one
+	RoomUUID uuid.UUID `json:"roomUuid"`
+	Role     string    `json:"role"`
+}
+
+type reslifeRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// reslifeRoomOf returns the ResLife room of a suite and who it is held for, or
+// uuid.Nil if the suite has none
+func reslifeRoomOf(tx *sql.Tx, suiteUUID uuid.UUID) (uuid.UUID, string, error) {
+	var roomUUID uuid.NullUUID
+	var role sql.NullString
+	err := tx.QueryRow("SELECT reslife_room, reslife_room_role FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&roomUUID, &role)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	return roomUUID.UUID, role.String, nil
+}
+
+// refuseReslifeRoom answers a pull into a suite's ResLife room with a 400. ResLife
+// rooms are held for staff whether or not anyone has been placed in them yet.
+func refuseReslifeRoom(c *gin.Context, tx *sql.Tx, room models.RoomRaw) error {
+	reslifeRoom, _, err := reslifeRoomOf(tx, room.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reslife room from suites table"})
+		return err
+	}
+	if reslifeRoom == room.RoomUUID {
+		rejectPull(c, http.StatusBadRequest, rejectedReslifeRoom, gin.H{"error": "Cannot pull into a ResLife room"})
+		return errors.New("room is a reslife room")
+	}
+	return nil
+}
+
+func isReslifeRole(role string) bool {
+	return role == models.ReslifeRoleMentor || role == models.ReslifeRoleProctor
+}
+
+// GetReslifeAllocations lists every suite's ResLife room and the staff placed in
+// it, optionally only in ?dorm=
+func GetReslifeAllocations(c *gin.Context) {
+	dorm, err := strconv.Atoi(c.DefaultQuery("dorm", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm"})
+		return
+	}
+
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
+        SELECT s.suite_uuid, s.dorm, s.dorm_name, r.room_uuid, r.room_id, COALESCE(s.reslife_room_role, ''),
+               COALESCE(json_agg(json_build_object('userId', u.id, 'firstName', u.first_name, 'lastName', u.last_name, 'reslifeRole', u.reslife_role)
+                        ORDER BY u.id) FILTER (WHERE u.id IS NOT NULL), '[]')
+        FROM suites s
+        JOIN rooms r ON r.room_uuid = s.reslife_room
+        LEFT JOIN users u ON u.room_uuid = r.room_uuid
+        WHERE $1 = 0 OR s.dorm = $1
+        GROUP BY s.suite_uuid, s.dorm, s.dorm_name, r.room_uuid, r.room_id, s.reslife_room_role
+        ORDER BY s.dorm, r.room_id`, dorm)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying reslife allocations", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ResLife allocations"})
+		return
+	}
+	defer rows.Close()
+
+	allocations := []models.ReslifeAllocation{}
+	for rows.Next() {
+		var a models.ReslifeAllocation
+		var occupants []byte
+		if err := rows.Scan(&a.SuiteUUID, &a.Dorm, &a.DormName, &a.RoomUUID, &a.RoomID, &a.Role, &occupants); err != nil {
+			logging.FromContext(c).Error("Error scanning reslife allocation", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan ResLife allocations"})
+			return
+		}
+		if err := json.Unmarshal(occupants, &a.Occupants); err != nil {
+			logging.FromContext(c).Error("Error reading occupants of reslife room", "room", a.RoomUUID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan ResLife allocations"})
+			return
+		}
+		allocations = append(allocations, a)
+	}
+
+	c.JSON(http.StatusOK, allocations)
+}
+
+// SetReslifeRoom designates the room of a suite held for a mentor or proctor.
+// The room must be empty, and the staff in the room held before have to be
+// unassigned first. The zero room UUID stops holding a room.
+func SetReslifeRoom(c *gin.Context) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	var request reslifeRoomRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.RoomUUID == uuid.Nil {
+		request.Role = ""
+	} else if !isReslifeRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be mentor or proctor"})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	var previousRoom uuid.NullUUID
+	var previousRole sql.NullString
+	err = tx.QueryRow("SELECT reslife_room, reslife_room_role FROM suites WHERE suite_uuid = $1 FOR UPDATE", suiteUUID).Scan(&previousRoom, &previousRole)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to get suite from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suite from database"})
+		return
+	}
+
+	// staff placed in the room held now have to be unassigned before it moves or
+	// is held for someone else
+	if previousRoom.Valid && (previousRoom.UUID != request.RoomUUID || previousRole.String != request.Role) {
+		var occupancy int
+		if err := tx.QueryRow("SELECT current_occupancy FROM rooms WHERE room_uuid = $1", previousRoom.UUID).Scan(&occupancy); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logging.FromContext(c).Error("Failed to get room from database", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+			return
+		}
+		if occupancy > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unassign the ResLife staff in the current ResLife room first"})
+			return
+		}
+	}
+
+	if request.RoomUUID != uuid.Nil && request.RoomUUID != previousRoom.UUID {
+		room, err := loadFroshRoom(tx, request.RoomUUID.String())
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+			return
+		}
+		if err != nil {
+			logging.FromContext(c).Error("Failed to get room from database", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+			return
+		}
+		if room.SuiteUUID != suiteUUID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Room is not in this suite"})
+			return
+		}
+		if room.HasFrosh {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Room has frosh"})
+			return
+		}
+		if room.CurrentOccupancy > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Room is not empty"})
+			return
+		}
+	}
+
+	_, err = tx.Exec("UPDATE suites SET reslife_room = $1, reslife_room_role = NULLIF($2, '') WHERE suite_uuid = $3",
+		uuid.NullUUID{UUID: request.RoomUUID, Valid: request.RoomUUID != uuid.Nil}, request.Role, suiteUUID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to update reslife_room in suites table", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reslife_room in suites table"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit SET_RESLIFE_ROOM", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+	logging.FromContext(c).Info("Committed SET_RESLIFE_ROOM", "suite", suiteUUID, "room", request.RoomUUID, "role", request.Role)
+
+	if err := logging.LogOperation(c, "SET_RESLIFE_ROOM", models.EntityTypeSuite, suiteUUID.String(),
+		map[string]interface{}{"reslifeRoom": previousRoom, "reslifeRoomRole": previousRole.String},
+		map[string]interface{}{"reslifeRoom": request.RoomUUID, "reslifeRoomRole": request.Role}, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log SET_RESLIFE_ROOM operation", "suite", suiteUUID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ResLife room updated"})
+}
+
+// AssignReslifeStaff places students in a suite's ResLife room as preplaced
+// occupants. Each must hold the role the room is held for and not be placed yet.
+func AssignReslifeStaff(c *gin.Context) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	var request models.PreplacedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.ProposedOccupants) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No ResLife staff to assign"})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	roomUUID, role, err := reslifeRoomOf(tx, suiteUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to get suite from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suite from database"})
+		return
+	}
+	if roomUUID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Suite has no ResLife room"})
+		return
+	}
+
+	room, err := loadFroshRoom(tx, roomUUID.String())
+	if err != nil {
+		logging.FromContext(c).Error("Failed to get room from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room from database"})
+		return
+	}
+	if room.HasFrosh {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room has frosh"})
+		return
+	}
+	occupants := append(models.IntArray{}, room.Occupants...)
+	occupants = append(occupants, request.ProposedOccupants...)
+	if len(occupants) > room.MaxOccupancy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		return
+	}
+
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	if err := lockUsersForUpdate(tx, request.ProposedOccupants); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
+		return
+	}
+
+	rows, err := tx.Query("SELECT id, reslife_role, room_uuid FROM users WHERE id = ANY($1)", pq.Array(request.ProposedOccupants))
+	if err != nil {
+		logging.FromContext(c).Error("Failed to query users", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query users"})
+		return
+	}
+	found := 0
+	var wrongRole, placed []int
+	for rows.Next() {
+		var id int
+		var reslifeRole string
+		var userRoom uuid.NullUUID
+		if err := rows.Scan(&id, &reslifeRole, &userRoom); err != nil {
+			rows.Close()
+			logging.FromContext(c).Error("Failed to scan users", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan users"})
+			return
+		}
+		found++
+		if reslifeRole != role {
+			wrongRole = append(wrongRole, id)
+		}
+		if userRoom.Valid {
+			placed = append(placed, id)
+		}
+	}
+	rows.Close()
+	if found != len(request.ProposedOccupants) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants does not exist"})
+		return
+	}
+	if len(wrongRole) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is not a " + role, "occupants": wrongRole})
+		return
+	}
+	if len(placed) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": placed})
+		return
+	}
+
+	previousState, err := getRoomStateRaw(roomUUID.String())
+	if err != nil {
+		logging.FromContext(c).Error("Error fetching room state for ASSIGN_RESLIFE", "room", roomUUID, "error", err)
+	}
+
+	_, err = tx.Exec("UPDATE users SET room_uuid = $1, preplaced = true WHERE id = ANY($2)", roomUUID, pq.Array(request.ProposedOccupants))
+	if err != nil {
+		logging.FromContext(c).Error("Failed to update room_uuid in users table", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room_uuid in users table"})
+		return
+	}
+
+	pullPriority := generateEmptyPriority()
+	pullPriority.Valid = true
+	pullPriority.IsPreplaced = true
+	pullPriorityJSON, err := json.Marshal(pullPriority)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal proposed pull priority"})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE rooms SET occupants = $1, current_occupancy = $2, pull_priority = $3 WHERE room_uuid = $4",
+		pq.Array(occupants), len(occupants), pullPriorityJSON, roomUUID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to update room occupants", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room occupants"})
+		return
+	}
+
+	if err := UpdateSuiteGenderPreferencesBySuiteUUID(tx, suiteUUID); err != nil {
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", suiteUUID, "error", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit ASSIGN_RESLIFE", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+	logging.FromContext(c).Info("Committed ASSIGN_RESLIFE", "room", roomUUID, "occupants", request.ProposedOccupants)
+
+	newState, err := getRoomStateRaw(roomUUID.String())
+	if err != nil {
+		logging.FromContext(c).Error("Error fetching new room state for ASSIGN_RESLIFE after commit", "room", roomUUID, "error", err)
+	}
+	if err := logging.LogOperation(c, "ASSIGN_RESLIFE", models.EntityTypeRoom, roomUUID.String(), previousState, newState,
+		map[string]interface{}{"proposed_occupants": request.ProposedOccupants, "reslife_room_role": role}); err != nil {
+		logging.FromContext(c).Warn("Failed to log ASSIGN_RESLIFE operation", "room", roomUUID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ResLife staff assigned"})
+}
+
+// UnassignReslifeStaff removes everyone from a suite's ResLife room. The room
+// stays held for ResLife.
+func UnassignReslifeStaff(c *gin.Context) {
+	suiteUUID, err := uuid.Parse(c.Param("suiteuuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suite UUID"})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	roomUUID, _, err := reslifeRoomOf(tx, suiteUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Suite not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to get suite from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suite from database"})
+		return
+	}
+	if roomUUID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Suite has no ResLife room"})
+		return
+	}
+
+	previousState, err := getRoomStateRaw(roomUUID.String())
+	if err != nil {
+		logging.FromContext(c).Error("Error fetching room state for UNASSIGN_RESLIFE", "room", roomUUID, "error", err)
+	}
+
+	// assigning staff marked them preplaced, which would keep them out of every pull
+	_, err = tx.Exec("UPDATE users SET preplaced = false WHERE id = ANY(SELECT unnest(occupants) FROM rooms WHERE room_uuid = $1)", roomUUID)
+	if err != nil {
+		logging.FromContext(c).Error("Failed to clear preplaced flag of ResLife staff", "room", roomUUID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update users table"})
+		return
+	}
+
+	// staff are moved by admins, so nobody is sent a bump notification
+	if err := clearRoom(roomUUID, tx, models.NewBumpNotificationQueue(), c.GetString("email")); err != nil {
+		logging.FromContext(c).Error("Failed to remove the occupants of the room", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the occupants of the room"})
+		return
+	}
+
+	if err := UpdateSuiteGenderPreferencesBySuiteUUID(tx, suiteUUID); err != nil {
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", suiteUUID, "error", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit UNASSIGN_RESLIFE", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+	logging.FromContext(c).Info("Committed UNASSIGN_RESLIFE", "room", roomUUID)
+
+	newState, err := getRoomStateRaw(roomUUID.String())
+	if err != nil {
+		logging.FromContext(c).Error("Error fetching new room state for UNASSIGN_RESLIFE after commit", "room", roomUUID, "error", err)
+	}
+	if err := logging.LogOperation(c, "UNASSIGN_RESLIFE", models.EntityTypeRoom, roomUUID.String(), previousState, newState, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log UNASSIGN_RESLIFE operation", "room", roomUUID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ResLife staff unassigned"})
+}
+
+// SetReslifeRole makes a student a mentor, a proctor or neither. A student placed
+// in a ResLife room keeps the role the room is held for until unassigned.
+func SetReslifeRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var request reslifeRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Role != models.ReslifeRoleNone && !isReslifeRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be none, mentor or proctor"})
+		return
+	}
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+	defer tx.Rollback()
+
+	var previousRole string
+	var heldRole sql.NullString
+	err = tx.QueryRow(`
+        SELECT u.reslife_role, s.reslife_room_role
+        FROM users u
+        LEFT JOIN suites s ON s.reslife_room = u.room_uuid
+        WHERE u.id = $1
+        FOR UPDATE OF u`, userID).Scan(&previousRole, &heldRole)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Failed to get user from database", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user from database"})
+		return
+	}
+	if heldRole.Valid && heldRole.String != request.Role {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is placed in a ResLife room held for a " + heldRole.String + ", unassign them first"})
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE users SET reslife_role = $1 WHERE id = $2", request.Role, userID); err != nil {
+		logging.FromContext(c).Error("Failed to update reslife role", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reslife role"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.FromContext(c).Error("Failed to commit SET_RESLIFE_ROLE", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+		return
+	}
+	logging.FromContext(c).Info("Committed SET_RESLIFE_ROLE", "user", userID, "role", request.Role)
+
+	if err := logging.LogOperation(c, "SET_RESLIFE_ROLE", models.EntityTypeUser, strconv.Itoa(userID),
+		map[string]string{"reslifeRole": previousRole}, map[string]string{"reslifeRole": request.Role}, nil); err != nil {
+		logging.FromContext(c).Warn("Failed to log SET_RESLIFE_ROLE operation", "user", userID, "error", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ResLife role updated"})
+}
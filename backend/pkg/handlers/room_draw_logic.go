@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"log"
+	"log/slog"
 	"roomdraw/backend/pkg/models"
 )
 
@@ -73,6 +73,13 @@ func sortUsersByPriority(users []models.UserRaw, dormId int) []models.UserRaw {
 	return append(append(left, pivot), right...)
 }
 
+// DrawOrder sorts users into the order they pick rooms in on draw night:
+// preplaced users, then by class year, then by draw number. In-dorm seniors
+// only outrank other seniors within their own dorm, so they are not boosted here.
+func DrawOrder(users []models.UserRaw) []models.UserRaw {
+	return sortUsersByPriority(users, -1)
+}
+
 func generateEmptyPriority() models.PullPriority {
 	return models.PullPriority{
 		Valid:       false,
@@ -106,7 +113,7 @@ func generateUserPriority(user models.UserRaw, dormId int) models.PullPriority {
 			yearNumber = 3
 		case "senior":
 			yearNumber = 4
-			if dormId == user.InDorm {
+			if dormId == user.InDorm && dormGrantsInDorm(dormId) {
 				hasInDorm = true
 			}
 		}
@@ -180,10 +187,9 @@ func comparePullPriority(priority1 models.PullPriority, priority2 models.PullPri
 		p2EffectiveYear = 6
 	}
 
-	log.Println("p1EffectiveDrawNumber", p1EffectiveDrawNumber)
-	log.Println("p1EffectiveYear", p1EffectiveYear)
-	log.Println("p2EffectiveDrawNumber", p2EffectiveDrawNumber)
-	log.Println("p2EffectiveYear", p2EffectiveYear)
+	slog.Debug("Comparing pull priority",
+		"p1EffectiveDrawNumber", p1EffectiveDrawNumber, "p1EffectiveYear", p1EffectiveYear,
+		"p2EffectiveDrawNumber", p2EffectiveDrawNumber, "p2EffectiveYear", p2EffectiveYear)
 
 	if p1EffectiveYear > p2EffectiveYear {
 		return true
@@ -222,20 +228,23 @@ func findIntersectionOfPreferences(preferencesArray [][]string) []string {
 }
 
 // GetSuiteGenderPreference determines the gender preference for a suite based on the priority of its occupants
-// Returns the gender preferences array and a boolean indicating if a preference was found
-func GetSuiteGenderPreference(users []models.UserRaw, dormId int) ([]string, bool) {
+// Returns the gender preferences, the rule that decided them and the occupants whose preferences were used
+func GetSuiteGenderPreference(users []models.UserRaw, dormId int) models.GenderPreferenceDecision {
 	if len(users) == 0 {
-		return []string{}, false
+		return models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRuleEmpty, DecidedBy: []int{}}
 	}
 
 	var preplacedUsersPreferences [][]string
+	var preplacedUsers, preplacedWithPreferences []int
 	anyPreplacedExist := false // Flag to track if Rule 3 applies at all
 
 	for _, user := range users {
 		if user.Preplaced {
 			anyPreplacedExist = true // Found at least one preplaced user
+			preplacedUsers = append(preplacedUsers, user.Id)
 			if len(user.GenderPreferences) > 0 {
 				preplacedUsersPreferences = append(preplacedUsersPreferences, user.GenderPreferences)
+				preplacedWithPreferences = append(preplacedWithPreferences, user.Id)
 			}
 		}
 	}
@@ -245,35 +254,36 @@ func GetSuiteGenderPreference(users []models.UserRaw, dormId int) ([]string, boo
 		// Subcase 3a: At least one preplaced user HAS preferences
 		if len(preplacedUsersPreferences) > 0 {
 			intersection := findIntersectionOfPreferences(preplacedUsersPreferences)
-			if len(intersection) > 0 {
-				return intersection, true // Found valid intersection
-			} else {
+			if len(intersection) == 0 {
 				// Conflict among preplaced users with preferences
-				log.Printf("Warning: No intersection found between preplaced users' gender preferences in suite (conflict)")
-				return []string{}, false // Error state
+				slog.Warn("No intersection found between preplaced users' gender preferences in suite (conflict)")
+			}
+			return models.GenderPreferenceDecision{
+				GenderPreferences: intersection,
+				Rule:              models.GenderPreferenceRulePreplaced,
+				DecidedBy:         preplacedWithPreferences,
+				Conflict:          len(intersection) == 0,
 			}
-		} else {
-			// Subcase 3b: Preplaced users exist, but NONE have preferences
-			log.Printf("Info: Preplaced users exist, but none have specified preferences. Suite preference is empty.")
-			return []string{}, false // Suite preference is empty, indicate no specific pref found
-			// Note: Returning 'false' here signifies "no specific preference determined by rule",
-			// which aligns with returning empty prefs due to conflict or lack of input.
-			// The CALLER needs to know that {} + false means "set suite to {}"
 		}
+
+		// Subcase 3b: Preplaced users exist, but NONE have preferences
+		slog.Info("Preplaced users exist, but none have specified preferences. Suite preference is empty")
+		return models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRulePreplacedNone, DecidedBy: preplacedUsers}
 	}
 
 	// --- Rule 2 Logic (Only reached if !anyPreplacedExist) ---
-	log.Printf("Info: No preplaced users found. Determining preference by highest priority.")
+	slog.Info("No preplaced users found. Determining preference by highest priority")
 	sortedUsers := sortUsersByPriority(users, dormId) // Sort only non-preplaced users
 
 	for _, user := range sortedUsers {
 		// Since we already know no preplaced users exist, we just check preferences
 		if len(user.GenderPreferences) > 0 {
-			return user.GenderPreferences, true // Found highest priority user with preferences
+			// Found highest priority user with preferences
+			return models.GenderPreferenceDecision{GenderPreferences: user.GenderPreferences, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{user.Id}}
 		}
 	}
 
 	// No user (in the non-preplaced group) had preferences, or list was empty after filtering
-	log.Printf("Info: No preplaced users, and no non-preplaced users had preferences. Suite preference is empty.")
-	return []string{}, false // No preference found based on Rule 2
+	slog.Info("No preplaced users, and no non-preplaced users had preferences. Suite preference is empty")
+	return models.GenderPreferenceDecision{GenderPreferences: []string{}, Rule: models.GenderPreferenceRulePriority, DecidedBy: []int{}}
 }
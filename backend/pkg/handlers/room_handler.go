@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
 	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
 	"roomdraw/backend/pkg/models"
 	"strconv"
 	"strings"
@@ -23,11 +25,12 @@ import (
 
 func GetRoomsHandler(c *gin.Context) {
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -45,7 +48,7 @@ func GetRoomsHandler(c *gin.Context) {
 	rows, err := tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh, frosh_room_type FROM rooms")
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -55,7 +58,7 @@ func GetRoomsHandler(c *gin.Context) {
 		var d models.RoomRaw
 		if err := rows.Scan(&d.RoomUUID, &d.Dorm, &d.DormName, &d.RoomID, &d.SuiteUUID, &d.MaxOccupancy, &d.CurrentOccupancy, &d.Occupants, &d.PullPriority, &d.SGroupUUID, &d.HasFrosh, &d.FroshRoomType); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
 			return
 		}
@@ -69,11 +72,12 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 	dormNameParam := c.Param("dormName")
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -90,7 +94,7 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 	rows, err := tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, has_frosh, frosh_room_type FROM rooms WHERE UPPER(dorm_name) = UPPER($1)", dormNameParam)
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on rooms", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on rooms"})
 		return
 	}
@@ -99,16 +103,16 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 	for rows.Next() {
 		var d models.RoomRaw
 		if err := rows.Scan(&d.RoomUUID, &d.Dorm, &d.DormName, &d.RoomID, &d.SuiteUUID, &d.MaxOccupancy, &d.CurrentOccupancy, &d.Occupants, &d.PullPriority, &d.HasFrosh, &d.FroshRoomType); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on rooms", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on rooms"})
 			return
 		}
 		rooms = append(rooms, d)
 	}
 
-	rows, err = tx.Query("SELECT suite_uuid, dorm, dorm_name, floor, room_count, rooms, alternative_pull, suite_design, can_lock_pull, reslife_room, gender_preferences, animal_in_suite, legacy_suite, suite_notes FROM suites WHERE UPPER(dorm_name) = UPPER($1)", dormNameParam)
+	rows, err = tx.Query("SELECT suite_uuid, dorm, dorm_name, floor, room_count, rooms, alternative_pull, suite_design, can_lock_pull, reslife_room, gender_preferences, gender_preference_rule, animal_in_suite, legacy_suite, suite_notes FROM suites WHERE UPPER(dorm_name) = UPPER($1)", dormNameParam)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on suites", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on suites"})
 		return
 	}
@@ -116,8 +120,8 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 	var suites []models.SuiteRaw
 	for rows.Next() {
 		var s models.SuiteRaw
-		if err := rows.Scan(&s.SuiteUUID, &s.Dorm, &s.DormName, &s.Floor, &s.RoomCount, &s.Rooms, &s.AlternativePull, &s.SuiteDesign, &s.CanLockPull, &s.ReslifeRoom, &s.GenderPreferences, &s.AnimalInSuite, &s.LegacySuite, &s.SuiteNotes); err != nil {
-			log.Println(err)
+		if err := rows.Scan(&s.SuiteUUID, &s.Dorm, &s.DormName, &s.Floor, &s.RoomCount, &s.Rooms, &s.AlternativePull, &s.SuiteDesign, &s.CanLockPull, &s.ReslifeRoom, &s.GenderPreferences, &s.GenderPreferenceRule, &s.AnimalInSuite, &s.LegacySuite, &s.SuiteNotes); err != nil {
+			logging.FromContext(c).Error("Database scan failed on suites", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on suites"})
 			return
 		}
@@ -170,15 +174,16 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 		suiteUUIDString := s.SuiteUUID.String()
 		floor := suiteUUIDToFloorMap[s.SuiteUUID]
 		suite := models.SuiteSimple{
-			Rooms:             suiteToRoomMap[suiteUUIDString],
-			SuiteDesign:       s.SuiteDesign,
-			SuiteUUID:         s.SuiteUUID,
-			AlternativePull:   s.AlternativePull,
-			CanLockPull:       s.CanLockPull,
-			GenderPreferences: s.GenderPreferences,
-			AnimalInSuite:     s.AnimalInSuite,
-			LegacySuite:       s.LegacySuite,
-			SuiteNotes:        s.SuiteNotes,
+			Rooms:                suiteToRoomMap[suiteUUIDString],
+			SuiteDesign:          s.SuiteDesign,
+			SuiteUUID:            s.SuiteUUID,
+			AlternativePull:      s.AlternativePull,
+			CanLockPull:          s.CanLockPull,
+			GenderPreferences:    s.GenderPreferences,
+			GenderPreferenceRule: s.GenderPreferenceRule,
+			AnimalInSuite:        s.AnimalInSuite,
+			LegacySuite:          s.LegacySuite,
+			SuiteNotes:           s.SuiteNotes,
 		}
 
 		floorMap[floor] = append(floorMap[floor], suite)
@@ -188,7 +193,10 @@ func GetSimpleFormattedDorm(c *gin.Context) {
 	var dorm models.DormSimple
 
 	dorm.DormName = cases.Title(language.English).String(dormNameParam)
-	dorm.Description = "This is a description of " + dorm.DormName + "."
+	if registered, ok := lookupDormByName(dormNameParam); ok {
+		dorm.DormName = registered.Name
+		dorm.Description = registered.Description
+	}
 
 	// for all floors (using the map keys)
 	for i, floor := range floorMap {
@@ -205,11 +213,12 @@ func GetSimplerFormattedDorm(c *gin.Context) {
 	dormNameParam := c.Param("dormName")
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -226,7 +235,7 @@ func GetSimplerFormattedDorm(c *gin.Context) {
 	rows, err := tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority FROM rooms WHERE UPPER(dorm_name) = UPPER($1)", dormNameParam)
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on rooms", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on rooms"})
 		return
 	}
@@ -235,7 +244,7 @@ func GetSimplerFormattedDorm(c *gin.Context) {
 	for rows.Next() {
 		var d models.RoomRaw
 		if err := rows.Scan(&d.RoomUUID, &d.Dorm, &d.DormName, &d.RoomID, &d.SuiteUUID, &d.MaxOccupancy, &d.CurrentOccupancy, &d.Occupants, &d.PullPriority); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on rooms", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on rooms"})
 			return
 		}
@@ -244,7 +253,7 @@ func GetSimplerFormattedDorm(c *gin.Context) {
 
 	rows, err = tx.Query("SELECT suite_uuid, dorm, dorm_name, floor, room_count, rooms, alternative_pull, suite_design FROM suites WHERE UPPER(dorm_name) = UPPER($1)", dormNameParam)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on suites", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on suites"})
 		return
 	}
@@ -253,7 +262,7 @@ func GetSimplerFormattedDorm(c *gin.Context) {
 	for rows.Next() {
 		var s models.SuiteRaw
 		if err := rows.Scan(&s.SuiteUUID, &s.Dorm, &s.DormName, &s.Floor, &s.RoomCount, &s.Rooms, &s.AlternativePull, &s.SuiteDesign); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on suites", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on suites"})
 			return
 		}
@@ -348,6 +357,14 @@ func compareRoomStatesForLogging(state1, state2 *models.RoomRaw) bool {
 
 }
 
+// lockUsersForUpdate takes row locks on the given users in id order. Requests are
+// only serialized per suite, so this stops two pulls in different suites from
+// placing the same student at the same time.
+func lockUsersForUpdate(tx *sql.Tx, userIDs []int) error {
+	_, err := tx.Exec("SELECT id FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(userIDs))
+	return err
+}
+
 func ToggleInDorm(c *gin.Context) {
 	roomUUIDParam := c.Param("roomuuid")
 	_, err := uuid.Parse(roomUUIDParam) // Validate UUID format early
@@ -359,7 +376,7 @@ func ToggleInDorm(c *gin.Context) {
 	// --- Transactional Logging: Get Previous State ---
 	previousRoomState, err := getRoomStateRaw(roomUUIDParam)
 	if err != nil {
-		log.Printf("Error fetching previous room state for TOGGLE_IN_DORM %s: %v", roomUUIDParam, err)
+		logging.FromContext(c).Error("Error fetching previous room state for TOGGLE_IN_DORM", "room", roomUUIDParam, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve current room state"})
 		return
 	}
@@ -369,11 +386,12 @@ func ToggleInDorm(c *gin.Context) {
 	}
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Defer rollback and handle commit/error for logging
 	var commitErr error
@@ -381,7 +399,7 @@ func ToggleInDorm(c *gin.Context) {
 		if r := recover(); r != nil {
 			tx.Rollback()
 			// Re-panic if needed, or handle
-			log.Printf("PANIC during TOGGLE_IN_DORM for %s: %v", roomUUIDParam, r)
+			logging.FromContext(c).Error("Panic during TOGGLE_IN_DORM", "entity", roomUUIDParam, "panic", r)
 			// Ensure response indicates server error if not already sent
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
@@ -389,7 +407,7 @@ func ToggleInDorm(c *gin.Context) {
 			return
 		}
 		if err != nil { // Error occurred within the handler logic before commit
-			log.Printf("Rolling back transaction for TOGGLE_IN_DORM %s due to error: %v", roomUUIDParam, err)
+			logging.FromContext(c).Error("Rolling back TOGGLE_IN_DORM", "entity", roomUUIDParam, "error", err)
 			tx.Rollback()
 			// We won't log if an error caused rollback before commit
 			if !c.Writer.Written() {
@@ -400,7 +418,7 @@ func ToggleInDorm(c *gin.Context) {
 		// No error before commit, attempt commit
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("Failed to commit transaction for TOGGLE_IN_DORM %s: %v", roomUUIDParam, commitErr)
+			logging.FromContext(c).Error("Failed to commit TOGGLE_IN_DORM", "entity", roomUUIDParam, "error", commitErr)
 			// Don't log if commit failed
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
@@ -411,7 +429,7 @@ func ToggleInDorm(c *gin.Context) {
 		// --- Transactional Logging: Get New State & Log (ONLY IF COMMIT SUCCEEDED) ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state for TOGGLE_IN_DORM %s after commit: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for TOGGLE_IN_DORM after commit", "room", roomUUIDParam, "error", fetchErr)
 			// Log the operation anyway, but new state might be nil/incomplete
 		}
 
@@ -431,7 +449,7 @@ func ToggleInDorm(c *gin.Context) {
 		)
 		if loggingErr != nil {
 			// Log the logging error, but the main operation succeeded.
-			log.Printf("WARNING: Failed to log TOGGLE_IN_DORM operation for %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log TOGGLE_IN_DORM operation", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// Send success response *after* logging attempt
@@ -509,29 +527,60 @@ func ToggleInDorm(c *gin.Context) {
 func UpdateRoomOccupants(c *gin.Context) {
 	var request models.OccupantUpdateRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("JSON unmarshal error: %v", err)
+		logging.FromContext(c).Warn("JSON unmarshal error", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var err error
+	pullWithMetrics(c, request)
+}
+
+// pullWithMetrics runs a pull and counts its outcome in the pull metrics
+func pullWithMetrics(c *gin.Context, request models.OccupantUpdateRequest) {
+	defer func() {
+		pullType := metrics.PullTypeLabel(request.PullType)
+		outcome := metrics.PullOutcome(c.Writer.Status())
+		metrics.PullsTotal.WithLabelValues(pullType, outcome).Inc()
+		if outcome == "rejected" {
+			metrics.PullRejectionsTotal.WithLabelValues(pullType, pullRejectionReason(c)).Inc()
+		}
+	}()
+
+	err := runPull(c, request)
+	if err != nil {
+		logging.FromContext(c).Warn("Pull failed", "pull_type", request.PullType, "error", err)
+		// c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// pullCommitHookKey holds a func(*sql.Tx) error that a pull runs in its
+// transaction just before committing, so a caller's own changes commit or fail
+// with the pull. The hook answers the request itself when it fails.
+const pullCommitHookKey = "pull_commit_hook"
+
+// runPullCommitHook runs the hook set under pullCommitHookKey, if any
+func runPullCommitHook(c *gin.Context, tx *sql.Tx) error {
+	hook, ok := c.Get(pullCommitHookKey)
+	if !ok {
+		return nil
+	}
+	return hook.(func(*sql.Tx) error)(tx)
+}
+
+// runPull dispatches a pull request to the handler for its pull type
+func runPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	switch request.PullType {
 	case 1: // self pull
-		err = SelfPull(c, request)
+		return SelfPull(c, request)
 	case 2: // normal pull
-		err = NormalPull(c, request)
+		return NormalPull(c, request)
 	case 3: // lock pull
-		err = LockPull(c, request)
+		return LockPull(c, request)
 	case 4: // alternative pull
-		err = AlternativePull(c, request)
+		return AlternativePull(c, request)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pull type"})
-		return
-	}
-
-	if err != nil {
-		log.Println(err)
-		// c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		rejectPull(c, http.StatusBadRequest, rejectedInvalidRequest, gin.H{"error": "Invalid pull type"})
+		return nil
 	}
 }
 
@@ -540,14 +589,14 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	roomUUIDParam := c.Param("roomuuid")
 	_, err := uuid.Parse(roomUUIDParam) // Validate UUID format early
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room UUID format"})
+		rejectPull(c, http.StatusBadRequest, rejectedInvalidRequest, gin.H{"error": "Invalid room UUID format"})
 		return errors.New("invalid room UUID format")
 	}
 
 	// --- Transactional Logging: Get Previous State ---
 	previousRoomState, err := getRoomStateRaw(roomUUIDParam)
 	if err != nil {
-		log.Printf("Error fetching previous room state for SELF_PULL %s: %v", roomUUIDParam, err)
+		logging.FromContext(c).Error("Error fetching previous room state for SELF_PULL", "room", roomUUIDParam, "error", err)
 		return errors.New("failed to retrieve current room state")
 	}
 	if previousRoomState == nil {
@@ -556,17 +605,17 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
-	log.Println(userFullName.(string) + " is attempting a self pull for room " + roomUUIDParam)
+	logging.FromContext(c).Info("Attempting a self pull", "user", userFullName, "room", roomUUIDParam)
 
 	proposedOccupants := request.ProposedOccupants
 
@@ -574,7 +623,7 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	proposedOccupantsMap := make(map[int]bool)
 	for _, occupant := range proposedOccupants {
 		if proposedOccupantsMap[occupant] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate user was specified in the occupants list"})
+			rejectPull(c, http.StatusBadRequest, rejectedDuplicateOccupant, gin.H{"error": "Duplicate user was specified in the occupants list"})
 			return errors.New("duplicate user was specified in the occupants list")
 		}
 		proposedOccupantsMap[occupant] = true
@@ -584,11 +633,12 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return err
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Defer rollback/commit and logging logic
 	var commitErr error
@@ -597,42 +647,55 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		// Handle panic first
 		if r := recover(); r != nil {
 			tx.Rollback()
-			log.Printf("PANIC during SELF_PULL for %s by %s: %v", roomUUIDParam, userEmail, r)
+			logging.FromContext(c).Error("Panic during SELF_PULL", "entity", roomUUIDParam, "panic", r)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			return
 		}
 
 		// Handle errors that occurred *before* commit attempt
 		if err != nil {
-			log.Printf("Rolling back transaction for SELF_PULL %s by %s due to error: %v", roomUUIDParam, userEmail, err)
+			logging.FromContext(c).Error("Rolling back SELF_PULL", "entity", roomUUIDParam, "error", err)
 			tx.Rollback()
 			// Don't log or send notifications if core logic failed
 			// Response should have been sent where the error occurred
 			return
 		}
 
+		// Whatever the caller changes along with the pull commits or fails with it
+		if hookErr := runPullCommitHook(c, tx); hookErr != nil {
+			logging.FromContext(c).Warn("Rolling back SELF_PULL", "entity", roomUUIDParam, "error", hookErr)
+			tx.Rollback()
+			return
+		}
+
+		// A preview stops here and undoes the pull
+		if isDryRun(c) {
+			finishDryRun(c, tx, notificationQueue)
+			return
+		}
+
 		// Attempt to commit
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("Failed to commit transaction for SELF_PULL %s by %s: %v", roomUUIDParam, userEmail, commitErr)
+			logging.FromContext(c).Error("Failed to commit SELF_PULL", "entity", roomUUIDParam, "error", commitErr)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			return
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Successfully committed SELF_PULL for room %s by %s", roomUUIDParam, userEmail)
+		logging.FromContext(c).Info("Committed SELF_PULL", "entity", roomUUIDParam, "by", userEmail)
 
 		// Send Bump Notifications (only after successful commit)
 		for _, notification := range notificationQueue.Notifications {
-			log.Printf("Queueing bump notification for user %d from room %s (%s)", notification.UserID, notification.RoomID, notification.DormName)
+			logging.FromContext(c).Info("Queueing bump notification", "user", notification.UserID, "room", notification.RoomID, "dorm", notification.DormName)
 			// Call the actual send function (make sure it's non-blocking or handled async)
-			go SendBumpNotification(notification.UserID, notification.RoomID, notification.DormName)
+			SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
 		}
 
 		// --- Transactional Logging: Get New State & Log ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state for SELF_PULL %s after commit: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for SELF_PULL after commit", "room", roomUUIDParam, "error", fetchErr)
 			// Log the operation anyway, new state might be nil/incomplete in the log record
 		}
 
@@ -667,7 +730,7 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		)
 		if loggingErr != nil {
 			// Log the logging error, but the main operation succeeded.
-			log.Printf("WARNING: Failed to log SELF_PULL operation for %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log SELF_PULL operation", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// Send success response *after* logging attempt
@@ -694,26 +757,31 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	}
 
 	// log room uuid
-	log.Println(currentRoomInfo.RoomUUID)
+	logging.FromContext(c).Debug("Pulling into room", "room", currentRoomInfo.RoomUUID)
 
 	// make sure the room does not have frosh
 	if currentRoomInfo.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull into a room with frosh"})
+		rejectPull(c, http.StatusBadRequest, rejectedFroshRoom, gin.H{"error": "Cannot pull into a room with frosh"})
 		err = errors.New("room has frosh")
 		return err
 	}
 
 	if currentRoomInfo.PullPriority.IsPreplaced {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull into a preplaced room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPreplacedRoom, gin.H{"error": "Cannot pull into a preplaced room"})
 		err = errors.New("room is preplaced")
 		return err
 	}
 
+	err = refuseReslifeRoom(c, tx, currentRoomInfo)
+	if err != nil {
+		return err
+	}
+
 	if len(proposedOccupants) == 0 {
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to clear room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear room"})
 			tx.Rollback()
 		}
@@ -722,18 +790,25 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	// check that the proposed occupants are not more than the max occupancy
 	if len(proposedOccupants) > currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		rejectPull(c, http.StatusBadRequest, rejectedOverCapacity, gin.H{"error": "Proposed occupants exceeds max occupancy"})
 		err = errors.New("proposed occupants exceeds max occupancy")
 		return err
 	}
 
 	// ensure that room is full before self pulling
 	if len(proposedOccupants) < currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is not full"})
+		rejectPull(c, http.StatusBadRequest, rejectedRoomSize, gin.H{"error": "Room is not full"})
 		err = errors.New("room is not full")
 		return err
 	}
 
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	err = lockUsersForUpdate(tx, proposedOccupants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
+		return err
+	}
+
 	var occupantsAlreadyInRoom models.IntArray
 	rows, err := tx.Query("SELECT id FROM users WHERE id = ANY($1) AND room_uuid IS NOT NULL", pq.Array(proposedOccupants))
 	if err != nil {
@@ -753,18 +828,18 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	if len(occupantsAlreadyInRoom) > 0 {
 		err = errors.New("one or more of the proposed occupants is already in a room")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
+		rejectPull(c, http.StatusBadRequest, rejectedAlreadyPlaced, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
 		return err
 	}
 
 	var proposedPullPriority models.PullPriority
-	log.Println(request.PullType)
+	logging.FromContext(c).Debug("Pull type", "pull_type", request.PullType)
 
-	log.Println("Self pull")
+	logging.FromContext(c).Debug("Self pull")
 	var occupantsInfo []models.UserRaw
 	rows, err = tx.Query("SELECT id, draw_number, year, in_dorm, participated, preplaced FROM users WHERE id = ANY($1)", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on users for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on users for pull priority"})
 		tx.Rollback()
 		return err
@@ -773,7 +848,7 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		var u models.UserRaw
 		if err := rows.Scan(&u.Id, &u.DrawNumber, &u.Year, &u.InDorm, &u.Participated, &u.Preplaced); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on users for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on users for pull priority"})
 			tx.Rollback()
 			return err
@@ -781,7 +856,7 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 		// if any of the proposed occupants are preplaced, return an error
 		if u.Preplaced {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull with a preplaced user"})
+			rejectPull(c, http.StatusBadRequest, rejectedPreplacedOccupant, gin.H{"error": "Cannot pull with a preplaced user"})
 			err = errors.New("cannot pull with a preplaced user")
 			tx.Rollback()
 			return err
@@ -793,42 +868,22 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	// for all users who currently have not participated, set their participated field to true and partitipation time to now
 	_, err = tx.Exec("UPDATE users SET participated = true, participation_time = NOW() WHERE id = ANY($1) AND participated = false", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to update participated field in users table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update participated field in users table"})
 		tx.Rollback()
 		return err
 	}
 
-	// loop through all occupants and check if they have in dorm
-	// if at least one does not have in dorm, change each of the pull priorities of each occupant to not have in dorm
-	for _, occupant := range occupantsInfo {
-		if occupant.InDorm != currentRoomInfo.Dorm {
-			log.Println("Forfeited in dorm to pull non-in dorm user")
-			for i := range occupantsInfo {
-				occupantsInfo[i].InDorm = 0
-			}
-			break
-		}
-	}
-
-	sortedOccupants := sortUsersByPriority(occupantsInfo, currentRoomInfo.Dorm)
-
-	proposedPullPriority = generateUserPriority(sortedOccupants[0], currentRoomInfo.Dorm)
-
-	proposedPullPriority.Valid = true
-	proposedPullPriority.PullType = 1
-
-	if currentRoomInfo.PullPriority.PullType == 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot bump a lock pulled room"})
-		err = errors.New("cannot bump a lock pulled room")
+	proposedPullPriority, err = selfPullPriority(occupantsInfo, currentRoomInfo)
+	if err != nil {
+		rejectPullRule(c, err)
 		tx.Rollback()
 		return err
 	}
 
-	if !comparePullPriority(proposedPullPriority, currentRoomInfo.PullPriority) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants do not have higher priority than current occupants"})
-		err = errors.New("proposed occupants do not have higher priority than current occupants")
-		tx.Rollback()
+	// in dorms that enforce gender preferences, the suite's preference has to fit unless the pull outranks the suite
+	err = enforceSuiteGenderPreference(c, tx, currentRoomInfo, proposedOccupants, proposedPullPriority)
+	if err != nil {
 		return err
 	}
 
@@ -847,7 +902,7 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to remove the current occupants of the room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the current occupants of the room"})
 			tx.Rollback()
 		}
@@ -887,12 +942,12 @@ func SelfPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	if err != nil {
 		// If there's a gender preference conflict, fail the transaction
 		if strings.Contains(err.Error(), "no valid intersection of gender preferences") {
-			c.JSON(http.StatusConflict, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
+			rejectPull(c, http.StatusConflict, rejectedIncompatibleGenderPreferences, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
 			return err
 		}
 
 		// For other errors, log a warning but continue
-		log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, err)
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", err)
 	}
 
 	return nil
@@ -904,13 +959,13 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
@@ -922,13 +977,13 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		proposedOccupantStrings[i] = strconv.Itoa(occupant)
 	}
 
-	log.Println(userFullName.(string) + " is attempting a normal pull for room " + roomUUIDParam + " with occupants " + strings.Join(proposedOccupantStrings, ", "))
+	logging.FromContext(c).Info("Attempting a normal pull", "user", userFullName, "room", roomUUIDParam, "occupants", proposedOccupantStrings)
 
 	// verify that the proposed occupants are unique
 	proposedOccupantsMap := make(map[int]bool)
 	for _, occupant := range proposedOccupants {
 		if proposedOccupantsMap[occupant] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate user was specified in the occupants list"})
+			rejectPull(c, http.StatusBadRequest, rejectedDuplicateOccupant, gin.H{"error": "Duplicate user was specified in the occupants list"})
 			return errors.New("duplicate user was specified in the occupants list")
 		}
 		proposedOccupantsMap[occupant] = true
@@ -937,23 +992,23 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	// --- Transactional Logging: Get Previous State ---
 	previousRoomState, err := getRoomStateRaw(roomUUIDParam)
 	if err != nil {
-		log.Printf("Error fetching previous room state for NORMAL_PULL %s: %v", roomUUIDParam, err)
+		logging.FromContext(c).Error("Error fetching previous room state for NORMAL_PULL", "room", roomUUIDParam, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve current room state"})
 		return err
 	}
 	if previousRoomState == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Target room not found"})
+		rejectPull(c, http.StatusNotFound, rejectedRoomNotFound, gin.H{"error": "Target room not found"})
 		return sql.ErrNoRows
 	}
 	// Fetch previous state of pull leader room IF its state might change significantly (e.g., sgroup_uuid, inherited priority)
 	previousPullLeaderRoomState, err := getRoomStateRaw(request.PullLeaderRoom.String())
 	if err != nil {
-		log.Printf("Error fetching previous pull leader room state for NORMAL_PULL %s: %v", request.PullLeaderRoom.String(), err)
+		logging.FromContext(c).Error("Error fetching previous pull leader room state for NORMAL_PULL", "room", request.PullLeaderRoom, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pull leader room state"})
 		return err
 	}
 	if previousPullLeaderRoomState == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pull leader room not found"})
+		rejectPull(c, http.StatusNotFound, rejectedPullLeader, gin.H{"error": "Pull leader room not found"})
 		return sql.ErrNoRows
 	}
 
@@ -961,11 +1016,12 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return err
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	var commitErr error
 	var genderUpdateErr error       // To store non-fatal gender update errors
@@ -976,23 +1032,33 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			log.Printf("PANIC during NORMAL_PULL for %s by %s: %v", roomUUIDParam, userEmail, r)
+			logging.FromContext(c).Error("Panic during NORMAL_PULL", "entity", roomUUIDParam, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
 			return
 		}
 		if err != nil {
-			log.Printf("Rolling back transaction for NORMAL_PULL %s by %s due to error: %v", roomUUIDParam, userEmail, err)
+			logging.FromContext(c).Error("Rolling back NORMAL_PULL", "entity", roomUUIDParam, "error", err)
 			tx.Rollback()
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
 			}
 			return // Error response should have been sent
 		}
+		// Whatever the caller changes along with the pull commits or fails with it
+		if hookErr := runPullCommitHook(c, tx); hookErr != nil {
+			logging.FromContext(c).Warn("Rolling back NORMAL_PULL", "entity", roomUUIDParam, "error", hookErr)
+			tx.Rollback()
+			return
+		}
+		if isDryRun(c) {
+			finishDryRun(c, tx, notificationQueue)
+			return
+		}
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("Failed to commit transaction for NORMAL_PULL %s by %s: %v", roomUUIDParam, userEmail, commitErr)
+			logging.FromContext(c).Error("Failed to commit NORMAL_PULL", "entity", roomUUIDParam, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -1000,23 +1066,23 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Successfully committed NORMAL_PULL for room %s by %s", roomUUIDParam, userEmail)
+		logging.FromContext(c).Info("Committed NORMAL_PULL", "entity", roomUUIDParam, "by", userEmail)
 
 		// Send Bump Notifications
 		for _, notification := range notificationQueue.Notifications {
-			go SendBumpNotification(notification.UserID, notification.RoomID, notification.DormName)
+			SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
 		}
 
 		// --- Transactional Logging: Get New State & Log ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state for NORMAL_PULL %s after commit: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for NORMAL_PULL after commit", "room", roomUUIDParam, "error", fetchErr)
 		}
 
 		// Fetch new state of pull leader room
 		newPullLeaderRoomState, fetchLeaderErr := getRoomStateRaw(request.PullLeaderRoom.String())
 		if fetchLeaderErr != nil {
-			log.Printf("Error fetching new pull leader room state for NORMAL_PULL %s after commit: %v", request.PullLeaderRoom.String(), fetchLeaderErr)
+			logging.FromContext(c).Error("Error fetching new pull leader room state for NORMAL_PULL after commit", "room", request.PullLeaderRoom, "error", fetchLeaderErr)
 		}
 
 		// Prepare details
@@ -1057,7 +1123,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 			logDetails,            // Additional Details
 		)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed to log NORMAL_PULL operation for target room %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log NORMAL_PULL operation for target room", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// Optionally log the change to the pull leader room if significant state changed
@@ -1082,7 +1148,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 				leaderLogDetails,
 			)
 			if leaderLoggingErr != nil {
-				log.Printf("WARNING: Failed to log state change for pull leader room %s during NORMAL_PULL: %v", request.PullLeaderRoom.String(), leaderLoggingErr)
+				logging.FromContext(c).Warn("Failed to log state change for pull leader room during NORMAL_PULL", "room", request.PullLeaderRoom, "error", leaderLoggingErr)
 			}
 		}
 
@@ -1111,28 +1177,40 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	}
 
 	// log room uuid
-	log.Println(currentRoomInfo.RoomUUID)
+	logging.FromContext(c).Debug("Pulling into room", "room", currentRoomInfo.RoomUUID)
 
 	// make sure the room does not have frosh
 	if currentRoomInfo.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room has frosh"})
+		rejectPull(c, http.StatusBadRequest, rejectedFroshRoom, gin.H{"error": "Room has frosh"})
 		err = errors.New("room has frosh")
 		return err
 	}
 
 	if currentRoomInfo.PullPriority.IsPreplaced {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull into a preplaced room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPreplacedRoom, gin.H{"error": "Cannot pull into a preplaced room"})
 		err = errors.New("room is preplaced")
 		return err
 	}
 
+	err = refuseReslifeRoom(c, tx, currentRoomInfo)
+	if err != nil {
+		return err
+	}
+
 	// check that the proposed occupants are not more than the max occupancy
 	if len(proposedOccupants) > currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		rejectPull(c, http.StatusBadRequest, rejectedOverCapacity, gin.H{"error": "Proposed occupants exceeds max occupancy"})
 		err = errors.New("proposed occupants exceeds max occupancy")
 		return err
 	}
 
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	err = lockUsersForUpdate(tx, proposedOccupants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
+		return err
+	}
+
 	var occupantsAlreadyInRoom models.IntArray
 	rows, err := tx.Query("SELECT id FROM users WHERE id = ANY($1) AND room_uuid IS NOT NULL", pq.Array(proposedOccupants))
 	if err != nil {
@@ -1152,7 +1230,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	if len(occupantsAlreadyInRoom) > 0 {
 		err = errors.New("one or more of the proposed occupants is already in a room")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
+		rejectPull(c, http.StatusBadRequest, rejectedAlreadyPlaced, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
 		return err
 	}
 
@@ -1162,7 +1240,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	if len(proposedOccupants) == 0 {
 		// error because normal pull requires at least one occupant
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Normal pull requires at least one occupant"})
+		rejectPull(c, http.StatusBadRequest, rejectedRoomSize, gin.H{"error": "Normal pull requires at least one occupant"})
 		err = errors.New("normal pull requires at least one occupant")
 		tx.Rollback()
 		return err
@@ -1170,37 +1248,16 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	if currentRoomInfo.RoomUUID == request.PullLeaderRoom {
 		// error because the pull leader is already in the room
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader is already in the room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPullLeader, gin.H{"error": "Pull leader is already in the room"})
 		err = errors.New("pull leader is already in the room")
 		tx.Rollback()
 		return err
 	}
 
-	drinkwardTripleInDormPullExceptions := []string{
-		"123C",
-		"124C",
-		"221C",
-		"222C",
-		"223C",
-		"224C",
-		"321C",
-		"322C",
-		"323C",
-		"324C",
-	}
-
-	isDrinkwardSuiteTriple := false
-	for _, drinkwardRoomID := range drinkwardTripleInDormPullExceptions {
-		if currentRoomInfo.RoomID == drinkwardRoomID && currentRoomInfo.Dorm == 8 {
-			isDrinkwardSuiteTriple = true
-		}
-	}
-
-	if currentRoomInfo.MaxOccupancy > 1 && !isDrinkwardSuiteTriple {
-		// error because normal pull is not allowed for rooms with max occupancy > 1
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You may only initiate a normal pull for singles other than in a Drinkward suite"})
-		err = errors.New("normal pull is not allowed for rooms with max occupancy > 1 other than in a Drinkward suite")
-		tx.Rollback()
+	// the suite's pull policy decides which triples can be normal pulled (e.g. the drinkward suite triples)
+	suitePolicy, err := loadSuitePullPolicy(tx, currentRoomInfo.SuiteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query suite pull policy"})
 		return err
 	}
 
@@ -1209,7 +1266,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	var occupantsInfo []models.UserRaw
 	rows, err = tx.Query("SELECT id, draw_number, year, in_dorm, participated, preplaced FROM users WHERE id = ANY($1)", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on users for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on users for pull priority"})
 		tx.Rollback()
 		return err
@@ -1218,7 +1275,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	for rows.Next() {
 		var u models.UserRaw
 		if err := rows.Scan(&u.Id, &u.DrawNumber, &u.Year, &u.InDorm, &u.Participated, &u.Preplaced); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on users for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on users for pull priority"})
 			tx.Rollback()
 			return err
@@ -1226,7 +1283,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 		// if any of the proposed occupants are preplaced, return an error
 		if u.Preplaced {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull with a preplaced user"})
+			rejectPull(c, http.StatusBadRequest, rejectedPreplacedOccupant, gin.H{"error": "Cannot pull with a preplaced user"})
 			err = errors.New("cannot pull with a preplaced user")
 			tx.Rollback()
 			return err
@@ -1238,132 +1295,38 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	// for all users who currently have not participated, set their participated field to true and partitipation time to now
 	_, err = tx.Exec("UPDATE users SET participated = true, participation_time = NOW() WHERE id = ANY($1) AND participated = false", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to update participated field in users table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update participated field in users table"})
 		tx.Rollback()
 		return err
 	}
 
-	var suiteUUID = currentRoomInfo.SuiteUUID
-	var leaderSuiteUUID uuid.UUID
-	var pullLeaderCurrentOccupancy int
+	var leaderRoom models.RoomRaw
 
-	// get the pull leader's priority
-	err = tx.QueryRow("SELECT pull_priority, sgroup_uuid, suite_uuid, current_occupancy FROM rooms WHERE room_uuid = $1", pullLeaderRoomUUID).Scan(&pullLeaderPriority, &pullLeaderSuiteGroupUUID, &leaderSuiteUUID, &pullLeaderCurrentOccupancy)
+	// get the pull leader's room
+	err = tx.QueryRow("SELECT room_uuid, pull_priority, sgroup_uuid, suite_uuid, current_occupancy FROM rooms WHERE room_uuid = $1", pullLeaderRoomUUID).Scan(&leaderRoom.RoomUUID, &leaderRoom.PullPriority, &leaderRoom.SGroupUUID, &leaderRoom.SuiteUUID, &leaderRoom.CurrentOccupancy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query pull leader's priority from rooms table"})
 		tx.Rollback()
 		return err
 	}
+	pullLeaderPriority = leaderRoom.PullPriority
+	pullLeaderSuiteGroupUUID = leaderRoom.SGroupUUID
 
-	if leaderSuiteUUID != suiteUUID {
-		// error because the pull leader is not in the same suite
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader is not in the same suite"})
-		tx.Rollback()
-		return err
-	}
-
-	if pullLeaderCurrentOccupancy != 1 {
-		// error because the pull leader is not in a single
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You can only initiate a normal pull with a single"})
-		tx.Rollback()
-		return err
-	}
-
-	sortedOccupants := sortUsersByPriority(occupantsInfo, currentRoomInfo.Dorm)
-
-	var pullLeaderEffectiveInDorm bool
-
-	// accounts for in dorm forfeit
-	if pullLeaderPriority.Inherited.Valid {
-		pullLeaderEffectiveInDorm = pullLeaderPriority.Inherited.HasInDorm
-	} else {
-		pullLeaderEffectiveInDorm = pullLeaderPriority.HasInDorm
-	}
-
-	if isDrinkwardSuiteTriple {
-		// check if the pull leader is in dorm
-		if !pullLeaderPriority.HasInDorm {
-			log.Println("pullLeaderPriority", pullLeaderPriority)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "You may only initiate a normal pull for singles in a Drinkward suite if the pull leader has in dorm"})
-			err = errors.New("you may only initiate a normal pull for singles in a Drinkward suite if the pull leader has in dorm")
-			tx.Rollback()
-			return err
-		}
-
-		if len(proposedOccupants) != 3 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "The triple being pulled with in dorm must have 3 occupants"})
-			err = errors.New("the triple being pulled with in dorm must have 3 occupants")
-			tx.Rollback()
-			return err
-		}
-	}
-
-	// check if this is a case of an in dorm pull leader pulling three people into a drinkward triple, this will be used in the future as an exception to the rule
-	// that in dorm pulls can only pull other in dorm users
-	isDrinkwardTripleException := false
-	if pullLeaderEffectiveInDorm && currentRoomInfo.Dorm == 8 { // 8 is the dorm code for drinkward
-		for _, roomID := range drinkwardTripleInDormPullExceptions {
-			if currentRoomInfo.RoomID == roomID {
-				isDrinkwardTripleException = true
-			}
-		}
-	}
-
-	// if the pull leader has indorm and the proposed occupants do not, it is invalid
-	// however, if the drinkward triple exception is true, then the pull leader can pull three people into a drinkward triple
-	if !isDrinkwardTripleException {
-		for _, occupant := range sortedOccupants {
-			if pullLeaderEffectiveInDorm && !(generateUserPriority(occupant, currentRoomInfo.Dorm).HasInDorm) {
-				log.Println("Pull leader has in dorm and proposed occupants do not")
-				err = errors.New("pull leader has in dorm and proposed occupants do not")
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader has in dorm and proposed occupants do not"})
-				return err
-			}
-		}
-	} else {
-		log.Println("Special case: Drinkward triple exception where pull leader has in dorm and proposed occupants do not")
-	}
-
-	proposedPullPriority = generateUserPriority(sortedOccupants[0], currentRoomInfo.Dorm)
-	proposedPullPriority.Valid = true
-	proposedPullPriority.PullType = 2
-
-	log.Println(proposedPullPriority)
-	log.Println(pullLeaderPriority)
-
-	if !comparePullPriority(pullLeaderPriority, proposedPullPriority) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader does not have higher priority than proposed occupants"})
-		tx.Rollback()
-		return err
-	}
-
-	proposedPullPriority.Inherited.Valid = true
-	if pullLeaderPriority.Inherited.Valid {
-		proposedPullPriority.Inherited.DrawNumber = pullLeaderPriority.Inherited.DrawNumber
-		proposedPullPriority.Inherited.HasInDorm = pullLeaderPriority.Inherited.HasInDorm
-		proposedPullPriority.Inherited.Year = pullLeaderPriority.Inherited.Year
-	} else {
-		proposedPullPriority.Inherited.DrawNumber = pullLeaderPriority.DrawNumber
-		proposedPullPriority.Inherited.HasInDorm = pullLeaderPriority.HasInDorm
-		proposedPullPriority.Inherited.Year = pullLeaderPriority.Year
-	}
-
-	if currentRoomInfo.PullPriority.PullType == 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot bump a lock pulled room"})
-		err = errors.New("cannot bump a lock pulled room")
+	proposedPullPriority, err = normalPullPriority(occupantsInfo, currentRoomInfo, leaderRoom, suitePolicy)
+	if err != nil {
+		rejectPullRule(c, err)
 		tx.Rollback()
 		return err
 	}
 
-	if !comparePullPriority(proposedPullPriority, currentRoomInfo.PullPriority) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants do not have higher priority than current occupants"})
-		err = errors.New("proposed occupants do not have higher priority than current occupants")
-		tx.Rollback()
+	// in dorms that enforce gender preferences, the suite's preference has to fit unless the pull outranks the suite
+	err = enforceSuiteGenderPreference(c, tx, currentRoomInfo, proposedOccupants, proposedPullPriority)
+	if err != nil {
 		return err
 	}
 
-	log.Println(proposedOccupants)
+	logging.FromContext(c).Debug("Proposed occupants", "occupants", proposedOccupants)
 
 	// disband the suite group if there is one
 	if currentRoomInfo.SGroupUUID != uuid.Nil {
@@ -1380,7 +1343,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to remove the current occupants of the room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the current occupants of the room"})
 			tx.Rollback()
 			return err
@@ -1417,7 +1380,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	}
 
 	if pullLeaderSuiteGroupUUID == uuid.Nil {
-		log.Println("Pull leader is not in a suite group")
+		logging.FromContext(c).Debug("Pull leader is not in a suite group")
 		// create new suite group with the pull leader's priority
 		pullLeaderPriorityJSON, err := json.Marshal(pullLeaderPriority)
 		if err != nil {
@@ -1436,7 +1399,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 			false,
 		)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to insert new suite group into suitegroups table", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert new suite group into suitegroups table"})
 			return err
 		}
@@ -1460,123 +1423,54 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		if err != nil {
 			// If there's a gender preference conflict, fail the transaction
 			if strings.Contains(err.Error(), "no valid intersection of gender preferences") {
-				c.JSON(http.StatusConflict, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
+				rejectPull(c, http.StatusConflict, rejectedIncompatibleGenderPreferences, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
 				return err
 			}
 
 			// For other errors, log a warning but continue
-			log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, err)
+			logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", err)
 		}
 	} else {
-		log.Println("Pull leader is in a suite group")
-
-		// first check that the room in in south or drinkward and that the number of rooms in the suite is 3
-		var suiteInfo models.SuiteRaw
-		err = tx.QueryRow("SELECT suite_uuid, dorm, dorm_name, floor, room_count, rooms, alternative_pull FROM suites WHERE suite_uuid = $1", currentRoomInfo.SuiteUUID).Scan(
-			&suiteInfo.SuiteUUID,
-			&suiteInfo.Dorm,
-			&suiteInfo.DormName,
-			&suiteInfo.Floor,
-			&suiteInfo.RoomCount,
-			&suiteInfo.Rooms,
-			&suiteInfo.AlternativePull,
-		)
+		logging.FromContext(c).Debug("Pull leader is in a suite group")
 
+		// check the suite's pull policy allows the suite group to grow by this room
+		var suiteRoomCount int
+		err = tx.QueryRow("SELECT room_count FROM suites WHERE suite_uuid = $1", currentRoomInfo.SuiteUUID).Scan(&suiteRoomCount)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query suite info from suites table"})
 			return err
 		}
 
-		if suiteInfo.Dorm != 3 && suiteInfo.Dorm != 8 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "You can only pull two rooms with your number in South or Drinkward suite side"})
-			err = errors.New("you can only pull two rooms with your number in South or Drinkward suite side")
-			tx.Rollback()
+		var groupRooms []int
+		rows, err = tx.Query("SELECT max_occupancy FROM rooms WHERE sgroup_uuid = $1", pullLeaderSuiteGroupUUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query other rooms in suite group from rooms table"})
 			return err
-		} else if suiteInfo.Dorm == 3 { // the suite is in south
-			if suiteInfo.RoomCount != 3 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "You can only pull two suitemates in South in a suite with three rooms"})
-				err = errors.New("you can only pull two suitemates in South in a suite with three rooms")
-				tx.Rollback()
+		}
+		for rows.Next() {
+			var maxOccupancy int
+			if err := rows.Scan(&maxOccupancy); err != nil {
+				rows.Close()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan other rooms in suite group from rooms table"})
 				return err
 			}
-		} else { // the suite is in drinkward
-			// the rule in drinkward is that if pull leader has in dorm they can pull a person with in dorm into a single
-			// along with three people that don't necessarily have in dorm into a triple
-
-			if pullLeaderPriority.HasInDorm {
-				// now see the other room in the suite group
-				var otherRooms []models.RoomRaw
-				rows, err = tx.Query("SELECT room_uuid, max_occupancy, current_occupancy, occupants FROM rooms WHERE sgroup_uuid = $1 AND room_uuid != $2", pullLeaderSuiteGroupUUID, pullLeaderRoomUUID)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query other room in suite group from rooms table"})
-					return err
-				}
-
-				for rows.Next() {
-					var otherRoom models.RoomRaw
-					if err := rows.Scan(&otherRoom.RoomUUID, &otherRoom.MaxOccupancy, &otherRoom.CurrentOccupancy, &otherRoom.Occupants); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan other room in suite group from rooms table"})
-						return err
-					}
-					otherRooms = append(otherRooms, otherRoom)
-				}
-
-				if len(otherRooms) > 1 {
-					c.JSON(http.StatusBadRequest, gin.H{"error": "You can only pull two rooms (a single and a triple) in a Drinkward suite"})
-					log.Println("otherRooms", otherRooms)
-					err = errors.New("you can only pull two rooms (a single and a triple) in a Drinkward suite")
-					tx.Rollback()
-					return err
-				}
-
-				otherRoom := otherRooms[0]
-				// two cases:
-				// 1. the other room is a single, in which case the pull leader can pull the triple with people who don't necessarily have in dorm
-				// 2. the other room is a triple, in which case the pull leader can pull a person with in dorm into a single
-
-				if otherRoom.MaxOccupancy == 1 {
-					// the other room is a single
-					// its okay for the proposed occupants to not have in dorm
-					if currentRoomInfo.MaxOccupancy != 3 {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "In Drinkward suite side, someone with in dorm can only pull a triple and a single"})
-						err = errors.New("with Drinkward in dorm, you can only pull a triple and a single in a Drinkward suite")
-						tx.Rollback()
-						return err
-					}
-					log.Println("Pull leader can pull a triple with people who don't necessarily have in dorm, while already having a single with in dorm in the suite")
-				} else if otherRoom.MaxOccupancy == 3 {
-					// the other room is a triple
-					// the proposed occupants must have in dorm and the room being pulled must be a single
-					if currentRoomInfo.MaxOccupancy != 1 {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "In Drinkward suite side, someone with in dorm can only pull a triple and a single"})
-						err = errors.New("with Drinkward in dorm, you can only pull a triple and a single in a Drinkward suite")
-						tx.Rollback()
-						return err
-					}
-
-					if len(proposedOccupants) != 1 {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "A single being pulled with in dorm must have 1 occupant"})
-						err = errors.New("you can only have 1 occupant in a single")
-						tx.Rollback()
-						return err
-					}
-
-					if !proposedPullPriority.HasInDorm {
-						c.JSON(http.StatusBadRequest, gin.H{"error": "The occupant being pulled with in dorm must have in dorm"})
-						err = errors.New("the occupant being pulled with in dorm must have in dorm")
-						tx.Rollback()
-						return err
-					}
+			groupRooms = append(groupRooms, maxOccupancy)
+		}
+		rows.Close()
 
-					log.Println("Pull leader with in dorm can pull a single with someone who has in dorm while already having a triple in the suite")
-				}
-			} else {
-				// pull leader does not have in dorm and thus cannot pull a third room into the suite
-				c.JSON(http.StatusBadRequest, gin.H{"error": "You can only pull two rooms (a single and a triple) in a Drinkward suite if the pull leader has in dorm"})
-				err = errors.New("you can only pull two rooms (a single and a triple) in a Drinkward suite if the pull leader has in dorm")
-				tx.Rollback()
-				return err
-			}
+		err = suitePolicy.CheckAddRoom(models.SuiteGroupPull{
+			SuiteRoomCount:      suiteRoomCount,
+			GroupRooms:          groupRooms,
+			RoomSize:            currentRoomInfo.MaxOccupancy,
+			Occupants:           len(proposedOccupants),
+			LeaderHasInDorm:     pullLeaderPriority.HasInDorm,
+			OccupantsHaveInDorm: proposedPullPriority.HasInDorm,
+		})
+		if err != nil {
+			logging.FromContext(c).Info("Suite pull policy rejected the pull", "policy", suitePolicy.Name, "error", err)
+			rejectPull(c, http.StatusBadRequest, rejectedSuitePolicy, gin.H{"error": err.Error()})
+			tx.Rollback()
+			return err
 		}
 
 		// check if the pull leader is the leader of the suite group by checking if the suite group's pull priority is the same as the pull leader's pull priority
@@ -1588,8 +1482,7 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 		// deep equal the pull leader's priority and the suite group's priority
 		if pullLeaderSuiteGroupPriority != pullLeaderPriority {
-			log.Println(pullLeaderSuiteGroupPriority)
-			log.Println(pullLeaderPriority)
+			logging.FromContext(c).Debug("Comparing pull priorities", "suite_group", pullLeaderSuiteGroupPriority, "pull_leader", pullLeaderPriority)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Pull leader is not the leader of the suite group"})
 			tx.Rollback()
 			return err
@@ -1621,12 +1514,12 @@ func NormalPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		if err != nil {
 			// If there's a gender preference conflict, fail the transaction
 			if strings.Contains(err.Error(), "no valid intersection of gender preferences") {
-				c.JSON(http.StatusConflict, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
+				rejectPull(c, http.StatusConflict, rejectedIncompatibleGenderPreferences, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
 				return err
 			}
 
 			// For other errors, log a warning but continue
-			log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, err)
+			logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", err)
 		}
 	}
 
@@ -1639,17 +1532,17 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
-	log.Println(userFullName.(string) + " is attempting a lock pull for room " + roomUUIDParam)
+	logging.FromContext(c).Info("Attempting a lock pull", "user", userFullName, "room", roomUUIDParam)
 
 	proposedOccupants := request.ProposedOccupants
 
@@ -1657,7 +1550,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	proposedOccupantsMap := make(map[int]bool)
 	for _, occupant := range proposedOccupants {
 		if proposedOccupantsMap[occupant] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate user was specified in the occupants list"})
+			rejectPull(c, http.StatusBadRequest, rejectedDuplicateOccupant, gin.H{"error": "Duplicate user was specified in the occupants list"})
 			return errors.New("duplicate user was specified in the occupants list")
 		}
 		proposedOccupantsMap[occupant] = true
@@ -1675,13 +1568,13 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	// Need getSuiteStateRaw helper function
 	previousSuiteState, err = getSuiteStateRaw(previousRoomState.SuiteUUID.String()) // Fetch suite using room's suite_uuid
 	if err != nil {
-		log.Printf("Error fetching previous suite state %s for LOCK_PULL: %v", previousRoomState.SuiteUUID.String(), err)
+		logging.FromContext(c).Error("Error fetching previous suite state for LOCK_PULL", "suite", previousRoomState.SuiteUUID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve suite state"})
 		return err
 	}
 	if previousSuiteState == nil {
 		// This shouldn't happen if the room exists, implies data inconsistency
-		log.Printf("ERROR: Suite %s not found for existing room %s during LOCK_PULL", previousRoomState.SuiteUUID.String(), roomUUIDParam)
+		logging.FromContext(c).Error("Suite not found for existing room during LOCK_PULL", "suite", previousRoomState.SuiteUUID, "room", roomUUIDParam)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal data inconsistency: Suite not found"})
 		return errors.New("suite not found for room")
 	}
@@ -1690,11 +1583,12 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return err
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	var commitErr error
 	var genderUpdateErr error
@@ -1703,7 +1597,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	defer func() {
 		if r := recover(); r != nil { /* ... handle panic rollback ... */
 			tx.Rollback()
-			log.Printf("PANIC during LOCK_PULL for %s: %v", roomUUIDParam, r)
+			logging.FromContext(c).Error("Panic during LOCK_PULL", "entity", roomUUIDParam, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
@@ -1711,15 +1605,25 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		}
 		if err != nil { /* ... handle error rollback ... */
 			tx.Rollback()
-			log.Printf("Error during LOCK_PULL for %s: %v", roomUUIDParam, err)
+			logging.FromContext(c).Error("Error during LOCK_PULL", "room", roomUUIDParam, "error", err)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
 			}
 				return
 		}
+		// Whatever the caller changes along with the pull commits or fails with it
+		if hookErr := runPullCommitHook(c, tx); hookErr != nil {
+			logging.FromContext(c).Warn("Rolling back LOCK_PULL", "entity", roomUUIDParam, "error", hookErr)
+			tx.Rollback()
+			return
+		}
+		if isDryRun(c) {
+			finishDryRun(c, tx, notificationQueue)
+			return
+		}
 		commitErr = tx.Commit()
 		if commitErr != nil { /* ... handle commit error ... */
-			log.Printf("Error during commit transaction for LOCK_PULL for %s: %v", roomUUIDParam, commitErr)
+			logging.FromContext(c).Error("Error during commit transaction for LOCK_PULL", "room", roomUUIDParam, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -1727,22 +1631,22 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Successfully committed LOCK_PULL for room %s by %s", roomUUIDParam, userEmail)
+		logging.FromContext(c).Info("Committed LOCK_PULL", "entity", roomUUIDParam, "by", userEmail)
 
 		// Send Bump Notifications (if any, unlikely for lock pull)
 		for _, notification := range notificationQueue.Notifications {
-			go SendBumpNotification(notification.UserID, notification.RoomID, notification.DormName)
+			SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
 		}
 
 		// --- Transactional Logging: Get New State & Log ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state for LOCK_PULL %s: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for LOCK_PULL", "room", roomUUIDParam, "error", fetchErr)
 		}
 		// Fetch new suite state
 		newSuiteState, fetchSuiteErr := getSuiteStateRaw(previousRoomState.SuiteUUID.String()) // Use stored SuiteUUID
 		if fetchSuiteErr != nil {
-			log.Printf("Error fetching new suite state %s for LOCK_PULL: %v", previousRoomState.SuiteUUID.String(), fetchSuiteErr)
+			logging.FromContext(c).Error("Error fetching new suite state for LOCK_PULL", "suite", previousRoomState.SuiteUUID, "error", fetchSuiteErr)
 		}
 
 		// Prepare details
@@ -1771,7 +1675,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 			logDetails,
 		)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed to log LOCK_PULL room operation %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log LOCK_PULL room operation", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// Log the change to the suite entity
@@ -1794,7 +1698,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 				suiteLogDetails,
 			)
 			if suiteLoggingErr != nil {
-				log.Printf("WARNING: Failed to log LOCK_PULL suite operation %s: %v", previousSuiteState.SuiteUUID.String(), suiteLoggingErr)
+				logging.FromContext(c).Warn("Failed to log LOCK_PULL suite operation", "suite", previousSuiteState.SuiteUUID, "error", suiteLoggingErr)
 			}
 		}
 
@@ -1824,28 +1728,40 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	}
 
 	// log room uuid
-	log.Println(currentRoomInfo.RoomUUID)
+	logging.FromContext(c).Debug("Pulling into room", "room", currentRoomInfo.RoomUUID)
 
 	// make sure the room does not have frosh
 	if currentRoomInfo.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room has frosh"})
+		rejectPull(c, http.StatusBadRequest, rejectedFroshRoom, gin.H{"error": "Room has frosh"})
 		err = errors.New("room has frosh")
 		return err
 	}
 
 	if currentRoomInfo.PullPriority.IsPreplaced {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull into a preplaced room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPreplacedRoom, gin.H{"error": "Cannot pull into a preplaced room"})
 		err = errors.New("room is preplaced")
 		return err
 	}
 
+	err = refuseReslifeRoom(c, tx, currentRoomInfo)
+	if err != nil {
+		return err
+	}
+
 	// check that the proposed occupants are not more than the max occupancy
 	if len(proposedOccupants) > currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		rejectPull(c, http.StatusBadRequest, rejectedOverCapacity, gin.H{"error": "Proposed occupants exceeds max occupancy"})
 		err = errors.New("proposed occupants exceeds max occupancy")
 		return err
 	}
 
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	err = lockUsersForUpdate(tx, proposedOccupants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
+		return err
+	}
+
 	var occupantsAlreadyInRoom models.IntArray
 	rows, err := tx.Query("SELECT id FROM users WHERE id = ANY($1) AND room_uuid IS NOT NULL", pq.Array(proposedOccupants))
 	if err != nil {
@@ -1865,16 +1781,16 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	if len(occupantsAlreadyInRoom) > 0 {
 		err = errors.New("one or more of the proposed occupants is already in a room")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
+		rejectPull(c, http.StatusBadRequest, rejectedAlreadyPlaced, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
 		return err
 	}
 
 	var proposedPullPriority models.PullPriority
-	log.Println(request.PullType)
+	logging.FromContext(c).Debug("Pull type", "pull_type", request.PullType)
 
 	// can only lock pull info an empty room
 	if currentRoomInfo.CurrentOccupancy > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lock pull is only allowed for empty rooms"})
+		rejectPull(c, http.StatusBadRequest, rejectedRoomSize, gin.H{"error": "Lock pull is only allowed for empty rooms"})
 		err = errors.New("lock pull is only allowed for empty rooms")
 		tx.Rollback()
 		return err
@@ -1884,7 +1800,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to remove the current occupants of the room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the current occupants of the room"})
 			return err
 		}
@@ -1893,7 +1809,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 	// lock pulled room must be full
 	if len(proposedOccupants) != currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lock pull requires the room to be full"})
+		rejectPull(c, http.StatusBadRequest, rejectedRoomSize, gin.H{"error": "Lock pull requires the room to be full"})
 		err = errors.New("lock pull requires the room to be full")
 		tx.Rollback()
 		return err
@@ -1915,22 +1831,16 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query suite info from suites table"})
 		tx.Rollback()
-	}
-
-	// ensure that lock pull is allowed for the suite
-	if !suiteInfo.CanLockPull {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lock pull is not allowed for the suite"})
-		tx.Rollback()
 		return err
 	}
 
-	// query all the rooms and ensure that they are full
+	// query all the rooms in the suite, which have to be full for a lock pull
 	var roomsInSuite []models.RoomRaw
 
 	rows, err = tx.Query("SELECT room_uuid, dorm, dorm_name, room_id, suite_uuid, max_occupancy, current_occupancy, occupants, pull_priority, sgroup_uuid, has_frosh FROM rooms WHERE suite_uuid = $1", currentRoomInfo.SuiteUUID)
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on rooms for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on rooms for pull priority"})
 		tx.Rollback()
 		return err
@@ -1940,7 +1850,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		var r models.RoomRaw
 		if err := rows.Scan(&r.RoomUUID, &r.Dorm, &r.DormName, &r.RoomID, &r.SuiteUUID, &r.MaxOccupancy, &r.CurrentOccupancy, &r.Occupants, &r.PullPriority, &r.SGroupUUID, &r.HasFrosh); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on rooms for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on rooms for pull priority"})
 			tx.Rollback()
 			return err
@@ -1948,32 +1858,10 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		roomsInSuite = append(roomsInSuite, r)
 	}
 
-	nonPreplacedRooms := 0
-
-	for _, roomInSuite := range roomsInSuite {
-		if roomInSuite.CurrentOccupancy < roomInSuite.MaxOccupancy && !roomInSuite.HasFrosh && roomInSuite.RoomUUID != currentRoomInfo.RoomUUID {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more rooms in the suite are not full " + roomInSuite.RoomID})
-			tx.Rollback()
-			return err
-		}
-
-		// check if pull priority is not preplace
-		if !roomInSuite.PullPriority.IsPreplaced && !roomInSuite.HasFrosh && roomInSuite.RoomUUID != currentRoomInfo.RoomUUID {
-			nonPreplacedRooms++
-		}
-	}
-
-	if nonPreplacedRooms == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot lock pull into a suite with all preplaced rooms"}) // all rooms in the suite are preplaced
-		err = errors.New("cannot lock pull into a suite with all preplaced rooms")
-		tx.Rollback()
-		return err
-	}
-
 	var occupantsInfo []models.UserRaw
 	rows, err = tx.Query("SELECT id, draw_number, year, in_dorm, participated, preplaced FROM users WHERE id = ANY($1)", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on users for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on users for pull priority"})
 		tx.Rollback()
 		return err
@@ -1982,7 +1870,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		var u models.UserRaw
 		if err := rows.Scan(&u.Id, &u.DrawNumber, &u.Year, &u.InDorm, &u.Participated, &u.Preplaced); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on users for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on users for pull priority"})
 			tx.Rollback()
 			return err
@@ -1990,7 +1878,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 
 		// if any of the proposed occupants are preplaced, return an error
 		if u.Preplaced {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull with a preplaced user"})
+			rejectPull(c, http.StatusBadRequest, rejectedPreplacedOccupant, gin.H{"error": "Cannot pull with a preplaced user"})
 			err = errors.New("cannot pull with a preplaced user")
 			tx.Rollback()
 			return err
@@ -2002,31 +1890,22 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	// for all users who currently have not participated, set their participated field to true and partitipation time to now
 	_, err = tx.Exec("UPDATE users SET participated = true, participation_time = NOW() WHERE id = ANY($1) AND participated = false", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to update participated field in users table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update participated field in users table"})
 		tx.Rollback()
 		return err
 	}
 
-	sortedOccupants := sortUsersByPriority(occupantsInfo, currentRoomInfo.Dorm)
-
-	proposedPullPriority = generateUserPriority(sortedOccupants[0], currentRoomInfo.Dorm)
-
-	proposedPullPriority.Valid = true
-	proposedPullPriority.PullType = 3
-	proposedPullPriority.Inherited.Valid = true
-
-	if currentRoomInfo.PullPriority.PullType == 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot bump a lock pulled room"})
-		err = errors.New("cannot bump a lock pulled room")
+	proposedPullPriority, err = lockPullPriority(occupantsInfo, currentRoomInfo, suiteInfo, roomsInSuite)
+	if err != nil {
+		rejectPullRule(c, err)
 		tx.Rollback()
 		return err
 	}
 
-	if !comparePullPriority(proposedPullPriority, currentRoomInfo.PullPriority) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants do not have higher priority than current occupants"})
-		err = errors.New("proposed occupants do not have higher priority than current occupants")
-		tx.Rollback()
+	// in dorms that enforce gender preferences, the suite's preference has to fit unless the pull outranks the suite
+	err = enforceSuiteGenderPreference(c, tx, currentRoomInfo, proposedOccupants, proposedPullPriority)
+	if err != nil {
 		return err
 	}
 
@@ -2045,7 +1924,7 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to remove the current occupants of the room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the current occupants of the room"})
 			tx.Rollback()
 			return err
@@ -2093,12 +1972,12 @@ func LockPull(c *gin.Context, request models.OccupantUpdateRequest) error {
 	if err != nil {
 		// If there's a gender preference conflict, fail the transaction
 		if strings.Contains(err.Error(), "no valid intersection of gender preferences") {
-			c.JSON(http.StatusConflict, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
+			rejectPull(c, http.StatusConflict, rejectedIncompatibleGenderPreferences, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
 			return err
 		}
 
 		// For other errors, log a warning but continue
-		log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, err)
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", err)
 	}
 
 	return nil
@@ -2118,13 +1997,13 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
@@ -2144,13 +2023,13 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		return sql.ErrNoRows
 	}
 
-	log.Println(userFullName.(string) + " is attempting an alternative pull for room " + roomUUIDParam + " with occupants " + strings.Join(proposedOccupantStrings, ", "))
+	logging.FromContext(c).Info("Attempting an alternative pull", "user", userFullName, "room", roomUUIDParam, "occupants", proposedOccupantStrings)
 
 	// verify that the proposed occupants are unique
 	proposedOccupantsMap := make(map[int]bool)
 	for _, occupant := range proposedOccupants {
 		if proposedOccupantsMap[occupant] {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Duplicate user was specified in the occupants list"})
+			rejectPull(c, http.StatusBadRequest, rejectedDuplicateOccupant, gin.H{"error": "Duplicate user was specified in the occupants list"})
 			return errors.New("duplicate user was specified in the occupants list")
 		}
 		proposedOccupantsMap[occupant] = true
@@ -2160,11 +2039,12 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return err
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Defer rollback/commit and logging logic
 	var commitErr error
@@ -2175,7 +2055,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	defer func() {
 		if r := recover(); r != nil { /* ... handle panic rollback ... */
 			tx.Rollback()
-			log.Printf("PANIC during ALTERNATIVE_PULL for %s: %v", roomUUIDParam, r)
+			logging.FromContext(c).Error("Panic during ALTERNATIVE_PULL", "entity", roomUUIDParam, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
@@ -2183,15 +2063,25 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		}
 		if err != nil { /* ... handle error rollback ... */
 			tx.Rollback()
-			log.Printf("Error during ALTERNATIVE_PULL for %s: %v", roomUUIDParam, err)
+			logging.FromContext(c).Error("Error during ALTERNATIVE_PULL", "room", roomUUIDParam, "error", err)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
 			}
 			return
 		}
+		// Whatever the caller changes along with the pull commits or fails with it
+		if hookErr := runPullCommitHook(c, tx); hookErr != nil {
+			logging.FromContext(c).Warn("Rolling back ALTERNATIVE_PULL", "entity", roomUUIDParam, "error", hookErr)
+			tx.Rollback()
+			return
+		}
+		if isDryRun(c) {
+			finishDryRun(c, tx, notificationQueue)
+			return
+		}
 		commitErr = tx.Commit()
 		if commitErr != nil { /* ... handle commit error ... */
-			log.Printf("Error during commit transaction for ALTERNATIVE_PULL for %s: %v", roomUUIDParam, commitErr)
+			logging.FromContext(c).Error("Error during commit transaction for ALTERNATIVE_PULL", "room", roomUUIDParam, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -2199,21 +2089,21 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Successfully committed ALTERNATIVE_PULL for room %s by %s", roomUUIDParam, userEmail)
+		logging.FromContext(c).Info("Committed ALTERNATIVE_PULL", "entity", roomUUIDParam, "by", userEmail)
 
 		// Send Bump Notifications
 		for _, notification := range notificationQueue.Notifications {
-			go SendBumpNotification(notification.UserID, notification.RoomID, notification.DormName)
+			SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
 		}
 
 		// --- Transactional Logging: Get New State & Log ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state %s: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for ALTERNATIVE_PULL after commit", "room", roomUUIDParam, "error", fetchErr)
 		}
 		newPullLeaderRoomState, fetchLeaderErr := getRoomStateRaw(request.PullLeaderRoom.String())
 		if fetchLeaderErr != nil {
-			log.Printf("Error fetching new leader room state %s: %v", request.PullLeaderRoom.String(), fetchLeaderErr)
+			logging.FromContext(c).Error("Error fetching new pull leader room state for ALTERNATIVE_PULL after commit", "room", request.PullLeaderRoom, "error", fetchLeaderErr)
 		}
 
 		// Prepare details
@@ -2245,7 +2135,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 			logDetails,
 		)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed log ALT_PULL target room %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log ALTERNATIVE_PULL operation for target room", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// Log change to the pull leader room
@@ -2269,7 +2159,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 				leaderLogDetails,
 			)
 			if leaderLoggingErr != nil {
-				log.Printf("WARNING: Failed log ALT_PULL leader room %s: %v", request.PullLeaderRoom.String(), leaderLoggingErr)
+				logging.FromContext(c).Warn("Failed to log ALTERNATIVE_PULL operation for pull leader room", "room", request.PullLeaderRoom, "error", leaderLoggingErr)
 			}
 		}
 
@@ -2299,24 +2189,36 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	}
 
 	// log room uuid
-	log.Println(currentRoomInfo.RoomUUID)
+	logging.FromContext(c).Debug("Pulling into room", "room", currentRoomInfo.RoomUUID)
 
 	// make sure the room does not have frosh
 	if currentRoomInfo.HasFrosh {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Room has frosh"})
+		rejectPull(c, http.StatusBadRequest, rejectedFroshRoom, gin.H{"error": "Room has frosh"})
 		err = errors.New("room has frosh")
 		return err
 	}
 
 	if currentRoomInfo.PullPriority.IsPreplaced {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull into a preplaced room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPreplacedRoom, gin.H{"error": "Cannot pull into a preplaced room"})
 		err = errors.New("room is preplaced")
 		return err
 	}
 
+	err = refuseReslifeRoom(c, tx, currentRoomInfo)
+	if err != nil {
+		return err
+	}
+
 	// check that the proposed occupants are not more than the max occupancy
 	if len(proposedOccupants) > currentRoomInfo.MaxOccupancy {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		rejectPull(c, http.StatusBadRequest, rejectedOverCapacity, gin.H{"error": "Proposed occupants exceeds max occupancy"})
+		return err
+	}
+
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	err = lockUsersForUpdate(tx, proposedOccupants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
 		return err
 	}
 
@@ -2339,26 +2241,24 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 
 	if len(occupantsAlreadyInRoom) > 0 {
 		err = errors.New("one or more of the proposed occupants is already in a room")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
+		rejectPull(c, http.StatusBadRequest, rejectedAlreadyPlaced, gin.H{"error": "One or more of the proposed occupants is already in a room", "occupants": occupantsAlreadyInRoom})
 		return err
 	}
 
 	var proposedPullPriority models.PullPriority
-	var pullLeaderPriority models.PullPriority
 	var alternativeGroupPriority models.PullPriority
-	var pullLeaderSuiteGroupUUID uuid.UUID
-	log.Println(request.PullType)
+	logging.FromContext(c).Debug("Pull type", "pull_type", request.PullType)
 
 	if len(proposedOccupants) != currentRoomInfo.MaxOccupancy {
 		// error because normal pull requires a full room
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Alternative pull requires the room to be full"})
+		rejectPull(c, http.StatusBadRequest, rejectedRoomSize, gin.H{"error": "Alternative pull requires the room to be full"})
 		tx.Rollback()
 		return err
 	}
 
 	if currentRoomInfo.RoomUUID == request.PullLeaderRoom {
 		// error because the pull leader is already in the room
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader is already in the room"})
+		rejectPull(c, http.StatusBadRequest, rejectedPullLeader, gin.H{"error": "Pull leader is already in the room"})
 		err = errors.New("pull leader is already in the room")
 		tx.Rollback()
 		return err
@@ -2382,18 +2282,12 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		return err
 	}
 
-	// ensure that alternative pull is allowed for the suite
-	if !suiteInfo.AlternativePull {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Alternative pull is not allowed for the suite"})
-		tx.Rollback()
-		return err
-	}
 
 	pullLeaderRoomUUID := request.PullLeaderRoom
 	var occupantsInfo []models.UserRaw
 	rows, err = tx.Query("SELECT id, draw_number, year, in_dorm, participated, preplaced FROM users WHERE id = ANY($1)", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on users for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on users for pull priority"})
 		tx.Rollback()
 		return err
@@ -2402,7 +2296,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	for rows.Next() {
 		var u models.UserRaw
 		if err := rows.Scan(&u.Id, &u.DrawNumber, &u.Year, &u.InDorm, &u.Participated, &u.Preplaced); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on users for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on users for pull priority"})
 			tx.Rollback()
 			return err
@@ -2410,7 +2304,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 
 		// if any of the proposed occupants are preplaced, return an error
 		if u.Preplaced {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot pull with a preplaced user"})
+			rejectPull(c, http.StatusBadRequest, rejectedPreplacedOccupant, gin.H{"error": "Cannot pull with a preplaced user"})
 			err = errors.New("cannot pull with a preplaced user")
 			tx.Rollback()
 			return err
@@ -2422,39 +2316,21 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	// for all users who currently have not participated, set their participated field to true and partitipation time to now
 	_, err = tx.Exec("UPDATE users SET participated = true, participation_time = NOW() WHERE id = ANY($1) AND participated = false", pq.Array(proposedOccupants))
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to update participated field in users table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update participated field in users table"})
 		tx.Rollback()
 		return err
 	}
 
-	var suiteUUID = currentRoomInfo.SuiteUUID
-	var leaderSuiteUUID uuid.UUID
-	var pullLeaderCurrentOccupancy int
-	var pullLeaderMaxOccupancy int
+	var leaderRoom models.RoomRaw
 
-	log.Println("pull leader room uuid: " + pullLeaderRoomUUID.String())
+	logging.FromContext(c).Debug("Pull leader room", "room", pullLeaderRoomUUID)
 
 	// get the pull leader's info
-	err = tx.QueryRow("SELECT pull_priority, sgroup_uuid, suite_uuid, current_occupancy, max_occupancy FROM rooms WHERE room_uuid = $1", pullLeaderRoomUUID).Scan(&pullLeaderPriority, &pullLeaderSuiteGroupUUID, &leaderSuiteUUID, &pullLeaderCurrentOccupancy, &pullLeaderMaxOccupancy)
+	err = tx.QueryRow("SELECT room_uuid, pull_priority, sgroup_uuid, suite_uuid, current_occupancy, max_occupancy FROM rooms WHERE room_uuid = $1", pullLeaderRoomUUID).Scan(&leaderRoom.RoomUUID, &leaderRoom.PullPriority, &leaderRoom.SGroupUUID, &leaderRoom.SuiteUUID, &leaderRoom.CurrentOccupancy, &leaderRoom.MaxOccupancy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query pull leader's info from rooms table"})
-		log.Println(err)
-		tx.Rollback()
-		return err
-	}
-
-	if leaderSuiteUUID != suiteUUID {
-		// error because the pull leader is not in the same suite
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader is not in the same suite"})
-		tx.Rollback()
-		return err
-	}
-
-	if pullLeaderCurrentOccupancy != pullLeaderMaxOccupancy {
-		log.Println(pullLeaderCurrentOccupancy, pullLeaderMaxOccupancy)
-		// error because the pull leader is not in a single
-		c.JSON(http.StatusBadRequest, gin.H{"error": "You can only initiate an alternative pull with a full room"})
+		logging.FromContext(c).Error("Failed to query pull leader's info from rooms table", "error", err)
 		tx.Rollback()
 		return err
 	}
@@ -2463,7 +2339,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	var pullLeaderOccupantsInfo []models.UserRaw
 	rows, err = tx.Query("SELECT id, draw_number, year, in_dorm FROM users WHERE room_uuid = $1", pullLeaderRoomUUID)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed on users for pull priority", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed on users for pull priority"})
 		tx.Rollback()
 		return err
@@ -2472,7 +2348,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 	for rows.Next() {
 		var u models.UserRaw
 		if err := rows.Scan(&u.Id, &u.DrawNumber, &u.Year, &u.InDorm); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed on users for pull priority", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed on users for pull priority"})
 			tx.Rollback()
 			return err
@@ -2480,63 +2356,20 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		pullLeaderOccupantsInfo = append(pullLeaderOccupantsInfo, u)
 	}
 
-	// loop through all occupants and check if they have in dorm
-	// if at least one does not have in dorm, change each of the pull priorities of each occupant to not have in dorm
-	for _, occupant := range occupantsInfo {
-		if occupant.InDorm != currentRoomInfo.Dorm {
-			log.Println("Forfeited in dorm to pull non-in dorm user")
-			for i := range occupantsInfo {
-				occupantsInfo[i].InDorm = 0
-			}
-			break
-		}
-	}
-
-	var allOccupantsInfo = append(occupantsInfo, pullLeaderOccupantsInfo...)
-
-	// loop through all occupants and check if they have in dorm
-	// if at least one does not have in dorm, change each of the pull priorities of each occupant to not have in dorm
-	for _, occupant := range allOccupantsInfo {
-		if occupant.InDorm != currentRoomInfo.Dorm {
-			log.Println("Forfeited in dorm to pull non-in dorm user")
-			for i := range allOccupantsInfo {
-				allOccupantsInfo[i].InDorm = 0
-			}
-			break
-		}
-	}
-
-	// sort all of the occupants by priority and get the second highest priority
-	sortedAllOccupants := sortUsersByPriority(allOccupantsInfo, currentRoomInfo.Dorm)
-
-	sortedProposedOccupants := sortUsersByPriority(occupantsInfo, currentRoomInfo.Dorm)
-
-	proposedPullPriority = generateUserPriority(sortedProposedOccupants[0], currentRoomInfo.Dorm)
-	proposedPullPriority.Valid = true
-	proposedPullPriority.PullType = 4
-
-	alternativeGroupPriority = generateUserPriority(sortedAllOccupants[1], currentRoomInfo.Dorm)
-
-	proposedPullPriority.Inherited.Valid = true
-	proposedPullPriority.Inherited.DrawNumber = alternativeGroupPriority.DrawNumber
-	proposedPullPriority.Inherited.HasInDorm = alternativeGroupPriority.HasInDorm
-	proposedPullPriority.Inherited.Year = alternativeGroupPriority.Year
-
-	if currentRoomInfo.PullPriority.PullType == 3 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot bump a lock pulled room"})
-		err = errors.New("cannot bump a lock pulled room")
+	proposedPullPriority, alternativeGroupPriority, err = alternativePullPriority(occupantsInfo, pullLeaderOccupantsInfo, currentRoomInfo, leaderRoom, suiteInfo)
+	if err != nil {
+		rejectPullRule(c, err)
 		tx.Rollback()
 		return err
 	}
 
-	if !comparePullPriority(proposedPullPriority, currentRoomInfo.PullPriority) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Proposed occupants do not have higher priority than current occupants"})
-		err = errors.New("proposed occupants do not have higher priority than current occupants")
-		tx.Rollback()
+	// in dorms that enforce gender preferences, the suite's preference has to fit unless the pull outranks the suite
+	err = enforceSuiteGenderPreference(c, tx, currentRoomInfo, proposedOccupants, proposedPullPriority)
+	if err != nil {
 		return err
 	}
 
-	log.Println(proposedOccupants)
+	logging.FromContext(c).Debug("Proposed occupants", "occupants", proposedOccupants)
 
 	// disband the suite group if there is one
 	if currentRoomInfo.SGroupUUID != uuid.Nil {
@@ -2553,7 +2386,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		email := c.MustGet("email").(string)
 		err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 		if err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Failed to remove the current occupants of the room", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the current occupants of the room"})
 			tx.Rollback()
 		}
@@ -2588,14 +2421,6 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		return err
 	}
 
-	if pullLeaderSuiteGroupUUID != uuid.Nil {
-		// error out because the pull leader is in a suite group
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Pull leader is in a suite group for alternative pull"})
-		err = errors.New("pull leader is in a suite group for alternative pull")
-		tx.Rollback()
-		return err
-	}
-
 	// do the same thing as for pull type 2
 	// create new suite group with the pull leader's priority
 	alternativeGroupPriorityJSON, err := json.Marshal(alternativeGroupPriority)
@@ -2615,7 +2440,7 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		false,
 	)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to insert new suite group into suitegroups table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to insert new suite group into suitegroups table"})
 		return err
 	}
@@ -2657,24 +2482,24 @@ func AlternativePull(c *gin.Context, request models.OccupantUpdateRequest) error
 		alternativeGroupPriority.HasInDorm,
 		request.PullLeaderRoom)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to update inherited priority in rooms table", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inherited priority in rooms table"})
 		return err
 	}
 
-	log.Println("Pull leader room: " + request.PullLeaderRoom.String())
+	logging.FromContext(c).Debug("Pull leader room", "room", request.PullLeaderRoom)
 
 	// Update gender preferences for the suite
 	err = UpdateSuiteGenderPreferencesBySuiteUUID(tx, currentRoomInfo.SuiteUUID)
 	if err != nil {
 		// If there's a gender preference conflict, fail the transaction
 		if strings.Contains(err.Error(), "no valid intersection of gender preferences") {
-			c.JSON(http.StatusConflict, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
+			rejectPull(c, http.StatusConflict, rejectedIncompatibleGenderPreferences, gin.H{"error": "Cannot pull users with incompatible gender preferences. The users must have at least one gender preference in common."})
 			return err
 		}
 
 		// For other errors, log a warning but continue
-		log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, err)
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", err)
 	}
 
 	return nil
@@ -2692,7 +2517,7 @@ func clearRoom(roomUUID uuid.UUID, tx *sql.Tx, notificationQueue *models.BumpNot
 		if err == sql.ErrNoRows {
 			// User not found in database, continue with clearing the room
 			// but don't exclude any occupant from notifications
-			log.Printf("User with email %s not found in database", requesterEmail)
+			slog.Error("User with email not found in database", "email", requesterEmail)
 			requester.Id = -1 // Set to invalid ID
 		} else {
 			// Other database error
@@ -2718,7 +2543,7 @@ func clearRoom(roomUUID uuid.UUID, tx *sql.Tx, notificationQueue *models.BumpNot
 	for _, occupantID := range currentOccupants {
 		// if the occupant is the person who is clearing the room, don't send a notification
 		if occupantID == requester.Id {
-			log.Println("Occupant " + strconv.Itoa(occupantID) + " is the requester, so not sending a notification")
+			slog.Debug("Occupant is the requester, so not sending a notification", "user", occupantID)
 			continue
 		}
 		notificationQueue.Add(occupantID, roomID, dormName)
@@ -2731,7 +2556,7 @@ func clearRoom(roomUUID uuid.UUID, tx *sql.Tx, notificationQueue *models.BumpNot
 		return err
 	}
 	// if the room is in a suite group, disband the suite group
-	log.Println("Clearing suite group with uuid " + suiteGroupUUID.String())
+	slog.Info("Clearing suite group", "suite_group", suiteGroupUUID)
 	if suiteGroupUUID != uuid.Nil {
 		_, err := disbandSuiteGroup(suiteGroupUUID, tx)
 		if err != nil {
@@ -2803,7 +2628,7 @@ func disbandSuiteGroup(sgroupUUID uuid.UUID, tx *sql.Tx) (models.UUIDArray, erro
 		return nil, err
 	}
 
-	log.Println("Disbanded suite group with uuid " + sgroupUUID.String())
+	slog.Info("Disbanded suite group", "suite_group", sgroupUUID)
 	return roomsInSuiteGroup, nil
 }
 
@@ -2813,11 +2638,11 @@ func PreplaceOccupants(c *gin.Context) {
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
-	log.Println(userFullName.(string) + " is attempting to preplace occupants in room " + roomUUIDParam)
+	logging.FromContext(c).Info("Attempting to preplace occupants", "user", userFullName, "room", roomUUIDParam)
 
 	// the request body should contain the occupants to be preplaced
 	var request models.PreplacedRequest
@@ -2829,23 +2654,24 @@ func PreplaceOccupants(c *gin.Context) {
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
 		if r := recover(); r != nil {
-			log.Println("Result for " + userFullName.(string) + ": failed to preplace occupants in room " + roomUUIDParam + " because of panic " + r.(error).Error())
+			logging.FromContext(c).Error("Failed to preplace occupants", "user", userFullName, "room", roomUUIDParam, "panic", r)
 			tx.Rollback()
 			panic(r)
 		} else if err != nil {
-			log.Println("Result for " + userFullName.(string) + ": failed to preplace occupants in room " + roomUUIDParam + " because of error " + err.Error())
+			logging.FromContext(c).Error("Failed to preplace occupants", "user", userFullName, "room", roomUUIDParam, "error", err)
 			tx.Rollback()
 		} else {
-			log.Println("Result for " + userFullName.(string) + ": successfully preplaced occupants in room " + roomUUIDParam)
+			logging.FromContext(c).Info("Preplaced occupants", "user", userFullName, "room", roomUUIDParam)
 			err = tx.Commit()
 
 			if err == nil {
@@ -2879,7 +2705,7 @@ func PreplaceOccupants(c *gin.Context) {
 	}
 
 	// log room uuid
-	log.Println(currentRoomInfo.RoomUUID)
+	logging.FromContext(c).Debug("Preplacing into room", "room", currentRoomInfo.RoomUUID)
 
 	// make sure the room does not have frosh
 	if currentRoomInfo.HasFrosh {
@@ -2938,6 +2764,13 @@ func PreplaceOccupants(c *gin.Context) {
 		return
 	}
 
+	// lock the proposed occupants so a concurrent pull in another suite cannot place them too
+	err = lockUsersForUpdate(tx, request.ProposedOccupants)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock proposed occupants"})
+		return
+	}
+
 	var occupantsAlreadyInRoom models.IntArray
 	rows, err = tx.Query("SELECT id FROM users WHERE id = ANY($1) AND room_uuid IS NOT NULL", pq.Array(request.ProposedOccupants))
 	if err != nil {
@@ -2978,28 +2811,11 @@ func PreplaceOccupants(c *gin.Context) {
 		return
 	}
 
-	// check if either user has reslife Role of mentor or proctor, reslife_role column is in the users table and needs to be 'mentor' or 'proctor'
-	var isReslife bool
-	for _, occupant := range request.ProposedOccupants {
-		var reslifeRole string
-		err = tx.QueryRow("SELECT reslife_role FROM users WHERE id = $1", occupant).Scan(&reslifeRole)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reslife role from users table"})
-			return
-		}
-		if reslifeRole == "mentor" || reslifeRole == "proctor" {
-			isReslife = true
-			break
-		}
-	}
-
-	// if isReslife is true, set the reslife_room field in the suites table to the room_uuid
-	if isReslife {
-		_, err = tx.Exec("UPDATE suites SET reslife_room = $1 WHERE suite_uuid = $2", currentRoomInfo.RoomUUID, currentRoomInfo.SuiteUUID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reslife_room in suites table"})
-			return
-		}
+	// ResLife staff are placed in their suite's ResLife room through /admin/reslife
+	if suiteInfo.ReslifeRoom == currentRoomInfo.RoomUUID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Room is a ResLife room, assign ResLife staff to it instead"})
+		err = errors.New("room is a reslife room")
+		return
 	}
 
 	// pull priority for the proposed occupants
@@ -3047,7 +2863,7 @@ func PreplaceOccupants(c *gin.Context) {
 		}
 
 		// For other errors, log a warning but continue
-		log.Printf("Warning: Failed to update gender preferences for suite %s: %v", currentRoomInfo.SuiteUUID, updateErr)
+		logging.FromContext(c).Warn("Failed to update gender preferences", "suite", currentRoomInfo.SuiteUUID, "error", updateErr)
 	}
 }
 
@@ -3059,33 +2875,34 @@ func RemovePreplacedOccupantsHandler(c *gin.Context) {
 	// Get the user's full name for logging
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
-	log.Println(userFullName.(string) + " is attempting to remove preplaced occupants from room " + roomUUIDParam)
+	logging.FromContext(c).Info("Attempting to remove preplaced occupants", "user", userFullName, "room", roomUUIDParam)
 
 	// Create a notification queue for any occupants that need to be notified
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
 		if r := recover(); r != nil {
-			log.Println("Result for " + userFullName.(string) + ": failed to remove preplaced occupants from room " + roomUUIDParam + " because of panic " + r.(error).Error())
+			logging.FromContext(c).Error("Failed to remove preplaced occupants", "user", userFullName, "room", roomUUIDParam, "panic", r)
 			tx.Rollback()
 			panic(r)
 		} else if err != nil {
-			log.Println("Result for " + userFullName.(string) + ": failed to remove preplaced occupants from room " + roomUUIDParam + " because of error " + err.Error())
+			logging.FromContext(c).Error("Failed to remove preplaced occupants", "user", userFullName, "room", roomUUIDParam, "error", err)
 			tx.Rollback()
 		} else {
-			log.Println("Result for " + userFullName.(string) + ": successfully removed preplaced occupants from room " + roomUUIDParam)
+			logging.FromContext(c).Info("Removed preplaced occupants", "user", userFullName, "room", roomUUIDParam)
 			err = tx.Commit()
 
 			if err == nil {
@@ -3140,7 +2957,7 @@ func RemovePreplacedOccupantsHandler(c *gin.Context) {
 	// Clear the room
 	err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, email)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to remove the occupants of the room", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove the occupants of the room"})
 		return
 	}
@@ -3148,9 +2965,9 @@ func RemovePreplacedOccupantsHandler(c *gin.Context) {
 	// Update gender preferences for the suite after clearing the room
 	updateErr := UpdateSuiteGenderPreferencesBySuiteUUID(tx, currentRoomInfo.SuiteUUID)
 	if updateErr != nil {
-		log.Printf("Warning: Failed to update gender preferences for suite %s after removing preplaced occupants: %v", currentRoomInfo.SuiteUUID, updateErr)
+		logging.FromContext(c).Warn("Failed to update gender preferences after removing preplaced occupants", "suite", currentRoomInfo.SuiteUUID, "error", updateErr)
 	} else {
-		log.Printf("Successfully updated gender preferences for suite %s after removing preplaced occupants", currentRoomInfo.SuiteUUID)
+		logging.FromContext(c).Info("Updated gender preferences after removing preplaced occupants", "suite", currentRoomInfo.SuiteUUID)
 	}
 }
 
@@ -3199,11 +3016,12 @@ func GetRoom(c *gin.Context) {
 	roomUUIDParam := c.Param("roomuuid")
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -3247,16 +3065,21 @@ func GetRoom(c *gin.Context) {
 
 // Add this new function after one of the existing handler functions
 
-// ClearRoomHandler handles clearing all occupants from a room
-func ClearRoomHandler(c *gin.Context) {
+// ClearRoomHandler clears a room, limiting each user to draw.MaxDailyClears per day
+func ClearRoomHandler(draw config.DrawConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clearRoomHandler(c, draw)
+	}
+}
+
+func clearRoomHandler(c *gin.Context, draw config.DrawConfig) {
 	// Get the room UUID from the URL
 	roomUUIDParam := c.Param("roomuuid")
-	print("roomUUIDParam: ", roomUUIDParam)
 
 	// Get the user's email
 	email, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		if !c.Writer.Written() {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "User email not found"})
 		}
@@ -3267,19 +3090,15 @@ func ClearRoomHandler(c *gin.Context) {
 	// Log the user who is attempting to clear the room
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
-	log.Println(userFullName.(string) + " is attempting to clear room " + roomUUIDParam)
+	logging.FromContext(c).Info("Attempting to clear room", "user", userFullName, "room", roomUUIDParam)
 
-	const MAX_DAILY_CLEARS = 10 // Keep your constant
+	maxDailyClears := draw.MaxDailyClears
 
-	// Get the current date in Pacific Time (needed for comparison and potential insert/update)
-	loc, errLoadLocation := time.LoadLocation("America/Los_Angeles")
-	if errLoadLocation != nil {
-		log.Printf("Error loading Pacific timezone: %v", errLoadLocation)
-		loc = time.UTC // Fallback
-	}
+	// Get the current date in the draw timezone (needed for comparison and potential insert/update)
+	loc := draw.Location()
 	pacificNow := time.Now().In(loc)
 	today := pacificNow.Format("2006-01-02") // YYYY-MM-DD format
 	todayDate, _ := time.Parse("2006-01-02", today)
@@ -3301,9 +3120,9 @@ func ClearRoomHandler(c *gin.Context) {
 			initialUserLimit.IsBlocklisted = false
 			initialUserLimit.ClearRoomDate.Valid = true // Assume we'll set today if record is created
 			initialUserLimit.ClearRoomDate.Time = todayDate
-			log.Printf("No existing rate limit record for %s.", emailStr)
+			logging.FromContext(c).Info("No existing rate limit record", "email", emailStr)
 		} else {
-			log.Printf("Error checking initial rate limits for %s: %v", emailStr, errRateLimit)
+			logging.FromContext(c).Error("Error checking initial rate limits", "email", emailStr, "error", errRateLimit)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limits"})
 			}
@@ -3312,7 +3131,8 @@ func ClearRoomHandler(c *gin.Context) {
 	} else {
 		// Record exists, check if blocklisted
 		if initialUserLimit.IsBlocklisted {
-			log.Printf("User %s is already blocklisted. Denying clear room request.", emailStr)
+			logging.FromContext(c).Info("User is already blocklisted, denying clear room request", "email", emailStr)
+			metrics.RateLimitHitsTotal.WithLabelValues("blocklisted").Inc()
 			if !c.Writer.Written() {
 				c.JSON(http.StatusForbidden, gin.H{"error": "Your account is restricted due to previous activity. Please contact an administrator.", "blocklisted": true})
 			}
@@ -3325,14 +3145,15 @@ func ClearRoomHandler(c *gin.Context) {
 			recordDateStr = initialUserLimit.ClearRoomDate.Time.Format("2006-01-02")
 		}
 		if recordDateStr != today {
-			log.Printf("Rate limit date mismatch for %s (%s vs %s). Count will be reset.", emailStr, recordDateStr, today)
+			logging.FromContext(c).Info("Rate limit date mismatch, count will be reset", "email", emailStr, "record_date", recordDateStr, "today", today)
 			// The update/insert logic within the transaction will handle the reset.
 			initialUserLimit.ClearRoomCount = 0 // Reset count conceptually for pre-check
 		}
 
-		// Pre-check if already over limit (e.g., if MAX_DAILY_CLEARS was lowered)
-		if initialUserLimit.ClearRoomCount >= MAX_DAILY_CLEARS {
-			log.Printf("User %s already met or exceeded clear limit (%d) for today (%s). Denying clear room request.", emailStr, initialUserLimit.ClearRoomCount, today)
+		// Pre-check if already over limit (e.g., if maxDailyClears was lowered)
+		if initialUserLimit.ClearRoomCount >= maxDailyClears {
+			logging.FromContext(c).Info("User already met or exceeded clear limit for today, denying clear room request", "email", emailStr, "clear_count", initialUserLimit.ClearRoomCount, "today", today)
+			metrics.RateLimitHitsTotal.WithLabelValues("daily_limit").Inc()
 			// Optionally blocklist here, though the logic later will catch it too.
 			if !c.Writer.Written() {
 				c.JSON(http.StatusTooManyRequests, gin.H{
@@ -3357,11 +3178,12 @@ func ClearRoomHandler(c *gin.Context) {
 	notificationQueue := models.NewBumpNotificationQueue()
 
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	var commitErr error
 	var clearedOccupantsForLog models.IntArray = previousRoomState.Occupants
@@ -3370,14 +3192,14 @@ func ClearRoomHandler(c *gin.Context) {
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
-			log.Printf("PANIC during CLEAR_ROOM for %s by %s: %v", roomUUIDParam, emailStr, r)
+			logging.FromContext(c).Error("Panic during CLEAR_ROOM", "entity", roomUUIDParam, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
 			return
 		}
 		if err != nil {
-			log.Printf("Rolling back transaction for CLEAR_ROOM %s by %s due to error: %v", roomUUIDParam, emailStr, err)
+			logging.FromContext(c).Error("Rolling back CLEAR_ROOM", "entity", roomUUIDParam, "error", err)
 			tx.Rollback()
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
@@ -3387,7 +3209,7 @@ func ClearRoomHandler(c *gin.Context) {
 
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("Failed to commit transaction for CLEAR_ROOM %s by %s: %v", emailStr, roomUUIDParam, commitErr)
+			logging.FromContext(c).Error("Failed to commit CLEAR_ROOM", "entity", roomUUIDParam, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -3395,17 +3217,18 @@ func ClearRoomHandler(c *gin.Context) {
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Successfully committed CLEAR_ROOM for room %s by %s", roomUUIDParam, emailStr)
+		logging.FromContext(c).Info("Committed CLEAR_ROOM", "entity", roomUUIDParam)
+		metrics.ClearRoomsTotal.Inc()
 
 		// Send Bump Notifications
 		for _, notification := range notificationQueue.Notifications {
-			go SendBumpNotification(notification.UserID, notification.RoomID, notification.DormName)
+			SendBumpNotificationAsync(notification.UserID, notification.RoomID, notification.DormName)
 		}
 
 		// --- Transactional Logging: Get New State & Log ---
 		newRoomState, fetchErr := getRoomStateRaw(roomUUIDParam)
 		if fetchErr != nil {
-			log.Printf("Error fetching new room state for CLEAR_ROOM %s: %v", roomUUIDParam, fetchErr)
+			logging.FromContext(c).Error("Error fetching new room state for CLEAR_ROOM", "room", roomUUIDParam, "error", fetchErr)
 		}
 
 		logDetails := map[string]interface{}{
@@ -3413,7 +3236,7 @@ func ClearRoomHandler(c *gin.Context) {
 		}
 		loggingErr := logging.LogOperation(c, "CLEAR_ROOM", models.EntityTypeRoom, roomUUIDParam, previousRoomState, newRoomState, logDetails)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed to log CLEAR_ROOM operation for %s: %v", roomUUIDParam, loggingErr)
+			logging.FromContext(c).Warn("Failed to log CLEAR_ROOM operation", "room", roomUUIDParam, "error", loggingErr)
 		}
 
 		// --- Rate Limiting Post-Commit Fetch & Blocklist Logic ---
@@ -3428,7 +3251,7 @@ func ClearRoomHandler(c *gin.Context) {
 
 		if rateLimitFetchErr != nil {
 			// This is problematic - the count was likely updated, but we can't confirm or check blocklist easily.
-			log.Printf("CRITICAL: Error fetching updated rate limit for %s after successful clear: %v. Blocklist check skipped.", emailStr, rateLimitFetchErr)
+			logging.FromContext(c).Error("Error fetching updated rate limit after successful clear, blocklist check skipped", "email", emailStr, "error", rateLimitFetchErr)
 			// Fallback: Use the initial count + 1 if the room wasn't empty? Less accurate.
 			updatedUserLimit = initialUserLimit // Start with initial state
 			if !roomAlreadyEmpty {
@@ -3437,31 +3260,33 @@ func ClearRoomHandler(c *gin.Context) {
 			// Cannot reliably check IsBlocklisted status here.
 		} else {
 			// Log the fetched updated count
-			log.Printf("Fetched updated clear count for user %s: %d", emailStr, updatedUserLimit.ClearRoomCount)
+			logging.FromContext(c).Info("Fetched updated clear count", "email", emailStr, "clear_count", updatedUserLimit.ClearRoomCount)
 
 			// Check if this operation pushed the user over the limit and blocklist them if so
-			if updatedUserLimit.ClearRoomCount >= MAX_DAILY_CLEARS && !updatedUserLimit.IsBlocklisted {
-				log.Printf("User %s reached clear limit (%d). Attempting to blocklist.", emailStr, updatedUserLimit.ClearRoomCount)
+			if updatedUserLimit.ClearRoomCount >= maxDailyClears && !updatedUserLimit.IsBlocklisted {
+				logging.FromContext(c).Info("User reached clear limit, attempting to blocklist", "email", emailStr, "clear_count", updatedUserLimit.ClearRoomCount)
 				// Start a new transaction specifically for blocklisting
+				// Not bound to the request context: the clear already committed, so the blocklist must apply even if the request deadline has passed
 				blocklistTx, btErr := database.DB.Begin()
 				if btErr != nil {
-					log.Printf("Error starting blocklist transaction for %s: %v", emailStr, btErr)
+					logging.FromContext(c).Error("Error starting blocklist transaction", "email", emailStr, "error", btErr)
 				} else {
 					now := time.Now() // Use current time for blocklist timestamp
-					reason := fmt.Sprintf("Exceeded daily clear room limit (%d) on %s", MAX_DAILY_CLEARS, today)
+					reason := fmt.Sprintf("Exceeded daily clear room limit (%d) on %s", maxDailyClears, today)
 					_, execBlErr := blocklistTx.Exec(
 						"UPDATE user_rate_limits SET is_blocklisted = true, blocklisted_at = $1, blocklisted_reason = $2 WHERE email = $3",
 						now, reason, emailStr)
 					if execBlErr != nil {
-						log.Printf("Error executing blocklist update for %s: %v", emailStr, execBlErr)
+						logging.FromContext(c).Error("Error executing blocklist update", "email", emailStr, "error", execBlErr)
 						blocklistTx.Rollback()
 					} else {
 						blCommitErr := blocklistTx.Commit()
 						if blCommitErr != nil {
-							log.Printf("Error committing blocklist transaction for %s: %v", emailStr, blCommitErr)
+							logging.FromContext(c).Error("Error committing blocklist transaction", "email", emailStr, "error", blCommitErr)
 						} else {
 							updatedUserLimit.IsBlocklisted = true // Update local struct reflect change
-							log.Printf("User %s successfully blocklisted.", emailStr)
+							logging.FromContext(c).Info("User successfully blocklisted", "email", emailStr)
+							metrics.RateLimitHitsTotal.WithLabelValues("blocklisted_now").Inc()
 							// TODO: Consider sending a blocklist notification email here?
 						}
 					}
@@ -3477,8 +3302,8 @@ func ClearRoomHandler(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message":         fmt.Sprintf("Room %s cleared successfully", roomUUIDParam),
 			"clearRoomCount":  updatedUserLimit.ClearRoomCount, // Use the fetched/calculated count
-			"maxDailyClears":  MAX_DAILY_CLEARS,
-			"remainingClears": max(0, MAX_DAILY_CLEARS-updatedUserLimit.ClearRoomCount), // Ensure non-negative
+			"maxDailyClears":  maxDailyClears,
+			"remainingClears": max(0, maxDailyClears-updatedUserLimit.ClearRoomCount), // Ensure non-negative
 			"resetsInMinutes": minutesUntilReset,
 			"pacificDate":     today,
 			"isBlocklisted":   updatedUserLimit.IsBlocklisted, // Use fetched/updated status
@@ -3521,19 +3346,19 @@ func ClearRoomHandler(c *gin.Context) {
 
 	// Check if the room is already empty
 	roomAlreadyEmpty = currentRoomInfo.CurrentOccupancy == 0
-	log.Printf("Room %s current occupancy: %d (empty: %v)", roomUUIDParam, currentRoomInfo.CurrentOccupancy, roomAlreadyEmpty)
+	logging.FromContext(c).Info("Room current occupancy", "room", roomUUIDParam, "current_occupancy", currentRoomInfo.CurrentOccupancy, "empty", roomAlreadyEmpty)
 
 	// Clear the room
 	err = clearRoom(currentRoomInfo.RoomUUID, tx, notificationQueue, emailStr)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Failed to clear room", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear room"})
 		return
 	}
 
 	// Only increment the clear count if the room wasn't already empty
 	if !roomAlreadyEmpty {
-		log.Printf("Incrementing clear count for user %s (room was not empty)", emailStr)
+		logging.FromContext(c).Info("Incrementing clear count, room was not empty", "email", emailStr)
 
 		// Upsert the clear count within the same transaction (insert if not exists, update if exists)
 		_, err = tx.Exec(`
@@ -3544,23 +3369,24 @@ func ClearRoomHandler(c *gin.Context) {
 			    clear_room_date = CURRENT_DATE
 		`, emailStr)
 		if err != nil {
-			log.Printf("Error updating clear count: %v", err)
+			logging.FromContext(c).Error("Error updating clear count", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update clear count"})
 			return
 		}
 	} else {
-		log.Printf("Not incrementing clear count for user %s (room was already empty)", emailStr)
+		logging.FromContext(c).Info("Not incrementing clear count, room was already empty", "email", emailStr)
 	}
 }
 
 // GetRoomsPagedAndSorted handles getting rooms with pagination, sorting and filtering
 func GetRoomsPagedAndSorted(c *gin.Context) {
 	// Start a transaction
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -3679,7 +3505,7 @@ func GetRoomsPagedAndSorted(c *gin.Context) {
 	var totalRecords int
 	err = tx.QueryRow(countQuery, args...).Scan(&totalRecords)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database count query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database count query failed"})
 		return
 	}
@@ -3695,7 +3521,7 @@ func GetRoomsPagedAndSorted(c *gin.Context) {
 	// Execute the query
 	rows, err := tx.Query(query, args...)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -3707,7 +3533,7 @@ func GetRoomsPagedAndSorted(c *gin.Context) {
 		if err := rows.Scan(&room.RoomUUID, &room.Dorm, &room.DormName, &room.RoomID,
 			&room.SuiteUUID, &room.MaxOccupancy, &room.CurrentOccupancy, &room.Occupants,
 			&room.PullPriority, &room.SGroupUUID, &room.HasFrosh, &room.FroshRoomType); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
 			return
 		}
@@ -3,13 +3,15 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
 	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
 	"roomdraw/backend/pkg/models"
 	"strings"
+	"time"
 
 	"git.sr.ht/~jamesponddotco/bunnystorage-go"
 	"github.com/gin-gonic/gin"
@@ -31,39 +33,47 @@ func getSuiteStateRaw(suiteUUID string) (*models.SuiteRaw, error) {
 	return &suite, nil
 }
 
-func SetSuiteDesign(c *gin.Context) {
+// SetSuiteDesign uploads suite designs to the BunnyNet storage zone in bunny
+func SetSuiteDesign(bunny config.BunnyNetConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setSuiteDesign(c, bunny)
+	}
+}
+
+func setSuiteDesign(c *gin.Context, bunny config.BunnyNetConfig) {
 	suiteUUID := c.Param("suiteuuid")
 
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
-	log.Printf("%s (%s) is attempting to set suite design for suite %s", userFullName, userEmail, suiteUUID)
+	logging.FromContext(c).Info("Attempting to set suite design", "user", userFullName, "email", userEmail, "suite", suiteUUID)
 
 	var previousSuiteState *models.SuiteRaw // Use pointer type
 	previousSuiteState, err = getSuiteStateRaw(suiteUUID)
 	if err != nil {
-		log.Printf("Error fetching previous suite state %s for LOCK_PULL: %v", previousSuiteState.SuiteUUID.String(), err)
+		logging.FromContext(c).Error("Error fetching previous suite state for SET_SUITE_DESIGN", "suite", suiteUUID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve suite state"})
 		return
 	}
 	if previousSuiteState == nil {
 		// This shouldn't happen if the room exists, implies data inconsistency
-		log.Printf("ERROR: Suite %s not found for existing room during LOCK_PULL", suiteUUID)
+		logging.FromContext(c).Error("Suite not found during SET_SUITE_DESIGN", "suite", suiteUUID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal data inconsistency: Suite not found"})
 		return
 	}
@@ -76,7 +86,7 @@ func SetSuiteDesign(c *gin.Context) {
 
 		if r := recover(); r != nil {
 			tx.Rollback()
-			log.Printf("PANIC during SET_SUITE_DESIGN for suite %s by %s: %v", suiteUUID, userEmail, r)
+			logging.FromContext(c).Error("Panic during SET_SUITE_DESIGN", "entity", suiteUUID, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
@@ -84,14 +94,14 @@ func SetSuiteDesign(c *gin.Context) {
 			return
 		}
 		if originalErr != nil {
-			log.Printf("Rolling back transaction for SET_SUITE_DESIGN for suite %s by %s due to error: %v", suiteUUID, userEmail, originalErr)
+			logging.FromContext(c).Error("Rolling back SET_SUITE_DESIGN", "entity", suiteUUID, "error", originalErr)
 			tx.Rollback()
 			return // Error response should have been sent
 		}
 
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("ERROR during COMMIT for SET_SUITE_DESIGN for suite %s by %s: %v", suiteUUID, userEmail, commitErr)
+			logging.FromContext(c).Error("Error committing SET_SUITE_DESIGN", "suite", suiteUUID, "email", userEmail, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -99,12 +109,12 @@ func SetSuiteDesign(c *gin.Context) {
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Committed transaction for SET_SUITE_DESIGN for suite %s by %s", suiteUUID, userEmail)
+		logging.FromContext(c).Info("Committed SET_SUITE_DESIGN", "suite", suiteUUID, "email", userEmail)
 
 		// --- Transactional Logging ---
 		newSuiteState, fetchErr := getSuiteStateRaw(suiteUUID)
 		if fetchErr != nil {
-			log.Printf("Error fetching new suite state %s for SET_SUITE_DESIGN: %v", suiteUUID, fetchErr)
+			logging.FromContext(c).Error("Error fetching new suite state for SET_SUITE_DESIGN", "suite", suiteUUID, "error", fetchErr)
 		}
 
 		logDetails := map[string]interface{}{
@@ -122,7 +132,7 @@ func SetSuiteDesign(c *gin.Context) {
 			logDetails,             // Additional Details
 		)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed to log SET_SUITE_DESIGN operation for %s: %v", suiteUUID, loggingErr)
+			logging.FromContext(c).Warn("Failed to log SET_SUITE_DESIGN operation", "suite", suiteUUID, "error", loggingErr)
 		}
 
 		// Send success response *after* logging attempt
@@ -156,16 +166,16 @@ func SetSuiteDesign(c *gin.Context) {
 	// extract file name from URL
 	currentSuiteDesign = currentSuiteDesign[strings.LastIndex(currentSuiteDesign, "/")+1:]
 
-	if config.BunnyNetWriteAPIKey == "" || config.BunnyNetStorageZone == "" {
-		log.Printf("DEV MODE: BunnyNet credentials not set, skipping image upload for suite %s", suiteUUID)
+	if bunny.WriteAPIKey == "" || bunny.StorageZone == "" {
+		logging.FromContext(c).Info("DEV MODE: BunnyNet credentials not set, skipping image upload", "suite", suiteUUID)
 		c.JSON(http.StatusOK, gin.H{"message": "Suite design skipped (dev mode — no CDN credentials)"})
 		return
 	}
 
 	cfg := &bunnystorage.Config{
-		StorageZone: config.BunnyNetStorageZone,
-		Key:         config.BunnyNetWriteAPIKey,
-		ReadOnlyKey: config.BunnyNetReadAPIKey,
+		StorageZone: bunny.StorageZone,
+		Key:         bunny.WriteAPIKey,
+		ReadOnlyKey: bunny.ReadAPIKey,
 		Endpoint:    bunnystorage.EndpointLosAngeles,
 	}
 
@@ -230,15 +240,15 @@ func SetSuiteDesign(c *gin.Context) {
 	}
 
 	if uploadRes.Status != http.StatusCreated {
-		log.Println("uploadRes", uploadRes)
-		log.Println("Failed to upload suite design to BunnyStorage:", uploadRes.Status)
+		logging.FromContext(c).Debug("Suite design upload response", "status", uploadRes.Status)
+		logging.FromContext(c).Error("Failed to upload suite design to BunnyStorage", "status", uploadRes.Status)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload suite design"})
 		return
 	}
 
-	imageUrl := config.CDNURL + "/suite_designs/" + imageFilename
+	imageUrl := bunny.CDNURL + "/suite_designs/" + imageFilename
 
-	log.Println(suiteUUID)
+	logging.FromContext(c).Debug("Uploaded suite design", "suite", suiteUUID)
 
 	// Add a new link to the CDN to the suite design
 	_, err = tx.Exec("UPDATE suites SET suite_design = $1 WHERE suite_uuid = $2", imageUrl, suiteUUID)
@@ -247,7 +257,7 @@ func SetSuiteDesign(c *gin.Context) {
 		return
 	}
 
-	log.Println("Uploaded suite design to BunnyStorage:", imageUrl)
+	logging.FromContext(c).Info("Uploaded suite design to BunnyStorage", "image_url", imageUrl)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Suite design updated"})
 }
@@ -257,37 +267,38 @@ func DeleteSuiteDesign(c *gin.Context) {
 
 	userEmail, exists := c.Get("email")
 	if !exists {
-		log.Print("Error: email not found in context")
+		logging.FromContext(c).Error("email not found in context")
 		userEmail = "unknown user email"
 	}
 
 	userFullName, exists := c.Get("user_full_name")
 	if !exists {
-		log.Print("Error: user_full_name not found in context")
+		logging.FromContext(c).Error("user_full_name not found in context")
 		userFullName = "unknown user"
 	}
 
-	log.Printf("%s (%s) is attempting to delete suite design for suite %s", userFullName, userEmail, suiteUUID)
+	logging.FromContext(c).Info("Attempting to delete suite design", "user", userFullName, "email", userEmail, "suite", suiteUUID)
 
 	var previousSuiteState *models.SuiteRaw // Use pointer type
 	previousSuiteState, err := getSuiteStateRaw(suiteUUID)
 	if err != nil {
-		log.Printf("Error fetching previous suite state %s for DELETE_SUITE_DESIGN: %v", previousSuiteState.SuiteUUID.String(), err)
+		logging.FromContext(c).Error("Error fetching previous suite state for DELETE_SUITE_DESIGN", "suite", suiteUUID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve suite state"})
 		return
 	}
 	if previousSuiteState == nil {
 		// This shouldn't happen if the room exists, implies data inconsistency
-		log.Printf("ERROR: Suite %s not found for existing room during DELETE_SUITE_DESIGN", suiteUUID)
+		logging.FromContext(c).Error("Suite not found during DELETE_SUITE_DESIGN", "suite", suiteUUID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal data inconsistency: Suite not found"})
 		return
 	}
 
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Defer rollback/commit and logging logic
 	var commitErr error
@@ -297,7 +308,7 @@ func DeleteSuiteDesign(c *gin.Context) {
 
 		if r := recover(); r != nil {
 			tx.Rollback()
-			log.Printf("PANIC during DELETE_SUITE_DESIGN for suite %s by %s: %v", suiteUUID, userEmail, r)
+			logging.FromContext(c).Error("Panic during DELETE_SUITE_DESIGN", "entity", suiteUUID, "panic", r)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
 			}
@@ -305,13 +316,13 @@ func DeleteSuiteDesign(c *gin.Context) {
 			return
 		}
 		if originalErr != nil {
-			log.Printf("Rolling back transaction for DELETE_SUITE_DESIGN for suite %s by %s due to error: %v", suiteUUID, userEmail, originalErr)
+			logging.FromContext(c).Error("Rolling back DELETE_SUITE_DESIGN", "entity", suiteUUID, "error", originalErr)
 			tx.Rollback()
 			return // Error response should have been sent
 		}
 		commitErr = tx.Commit()
 		if commitErr != nil {
-			log.Printf("ERROR during COMMIT for DELETE_SUITE_DESIGN for suite %s by %s: %v", suiteUUID, userEmail, commitErr)
+			logging.FromContext(c).Error("Error committing DELETE_SUITE_DESIGN", "suite", suiteUUID, "email", userEmail, "error", commitErr)
 			if !c.Writer.Written() {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
 			}
@@ -319,7 +330,7 @@ func DeleteSuiteDesign(c *gin.Context) {
 		}
 
 		// --- COMMIT SUCCEEDED ---
-		log.Printf("Committed transaction for DELETE_SUITE_DESIGN for suite %s by %s", suiteUUID, userEmail)
+		logging.FromContext(c).Info("Committed DELETE_SUITE_DESIGN", "suite", suiteUUID, "email", userEmail)
 
 		// --- TODO Optional: Delete file from BunnyNet ---
 		// This should happen *after* commit ideally. If it fails, log a warning.
@@ -331,7 +342,7 @@ func DeleteSuiteDesign(c *gin.Context) {
 		// --- Transactional Logging ---
 		newSuiteState, fetchErr := getSuiteStateRaw(suiteUUID) // Fetch state after deletion
 		if fetchErr != nil {
-			log.Printf("Error fetching new suite state %s for DELETE_SUITE_DESIGN: %v", suiteUUID, fetchErr)
+			logging.FromContext(c).Error("Error fetching new suite state for DELETE_SUITE_DESIGN", "suite", suiteUUID, "error", fetchErr)
 		}
 
 		logDetails := map[string]interface{}{
@@ -347,7 +358,7 @@ func DeleteSuiteDesign(c *gin.Context) {
 			logDetails,             // Additional Details
 		)
 		if loggingErr != nil {
-			log.Printf("WARNING: Failed to log DELETE_SUITE_DESIGN operation for %s: %v", suiteUUID, loggingErr)
+			logging.FromContext(c).Warn("Failed to log DELETE_SUITE_DESIGN operation", "suite", suiteUUID, "error", loggingErr)
 		}
 
 		// Send success response *after* logging attempt
@@ -381,11 +392,12 @@ func DeleteSuiteDesign(c *gin.Context) {
 }
 
 func UpdateSuiteGenderPreference(c *gin.Context) {
-	tx, err := database.DB.Begin()
+	tx, err := database.BeginTx(c.Request.Context(), nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
 		return
 	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
 
 	// Ensure the transaction is either committed or rolled back
 	defer func() {
@@ -399,10 +411,10 @@ func UpdateSuiteGenderPreference(c *gin.Context) {
 		}
 	}()
 
-	// Get all suites that can be gender preferenced
-	suiteRows, err := tx.Query("SELECT suite_uuid FROM suites WHERE can_be_gender_preferenced = true")
+	// Get all suites, including those that cannot be gender preferenced so Rule 1 is recorded for them too
+	suiteRows, err := tx.Query("SELECT suite_uuid FROM suites")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get gender-preferenceable suites"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suites"})
 		return
 	}
 
@@ -424,7 +436,7 @@ func UpdateSuiteGenderPreference(c *gin.Context) {
 	for _, suiteUUID := range suiteUUIDs {
 		err = UpdateSuiteGenderPreferencesBySuiteUUID(tx, suiteUUID)
 		if err != nil {
-			log.Printf("Failed to update gender preferences for suite %s: %v", suiteUUID, err)
+			logging.FromContext(c).Error("Failed to update gender preferences", "suite", suiteUUID, "error", err)
 			continue
 		}
 	}
@@ -433,49 +445,92 @@ func UpdateSuiteGenderPreference(c *gin.Context) {
 
 // UpdateSuiteGenderPreferencesBySuiteUUID is a helper function that updates a suite's gender preferences
 // based on its occupants. This should be called after any changes to room occupants.
+// The rule that decided the preference is stored with it, and any change is logged.
 func UpdateSuiteGenderPreferencesBySuiteUUID(tx *sql.Tx, suiteUUID uuid.UUID) error {
 	// --- Check if suite can be gender preferenced (Rule 1) ---
 	var canBeGenderPreferenced bool
-	err := tx.QueryRow("SELECT can_be_gender_preferenced FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&canBeGenderPreferenced)
+	var previous models.GenderPreferenceDecision
+	var previousPreferences pq.StringArray
+	var previousDecidedBy models.IntArray
+	err := tx.QueryRow("SELECT can_be_gender_preferenced, gender_preferences, gender_preference_rule, gender_preference_decided_by FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(
+		&canBeGenderPreferenced, &previousPreferences, &previous.Rule, &previousDecidedBy)
 	if err != nil {
-		log.Printf("Failed to check if suite %s can be gender preferenced: %v", suiteUUID, err)
+		slog.Error("Failed to check if suite can be gender preferenced", "suite", suiteUUID, "error", err)
 		return err // Propagate DB errors
 	}
+	previous.GenderPreferences = previousPreferences
+	previous.DecidedBy = previousDecidedBy
+
+	// --- Get dormId (as before) ---
+	var dormId int
+	err = tx.QueryRow("SELECT dorm FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&dormId)
+	if err != nil {
+		slog.Error("Failed to get dorm ID", "suite", suiteUUID, "error", err)
+		return err
+	}
 
-	if !canBeGenderPreferenced {
-		log.Printf("Suite %s cannot be gender preferenced. Ensuring preference is empty.", suiteUUID)
-		_, err = tx.Exec("UPDATE suites SET gender_preferences = '{}' WHERE suite_uuid = $1", suiteUUID)
+	var users []models.UserRaw
+	if canBeGenderPreferenced {
+		users, err = loadSuiteOccupants(tx, suiteUUID, uuid.Nil)
 		if err != nil {
-			log.Printf("Failed to clear gender preferences for non-preferenced suite %s: %v", suiteUUID, err)
-			return err // Propagate DB errors
+			return err
 		}
-		return nil // No further action needed
+	} else {
+		slog.Info("Suite cannot be gender preferenced, ensuring preference is empty", "suite", suiteUUID)
 	}
 
-	// --- Get dormId (as before) ---
-	var dormId int
-	err = tx.QueryRow("SELECT dorm FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&dormId)
+    // Log who we are considering
+	userNames := make([]string, 0, len(users))
+	for _, u := range users {
+        preplacedMarker := ""
+        if u.Preplaced {
+            preplacedMarker = " (P)"
+        }
+		userNames = append(userNames, fmt.Sprintf("%s %s%s %v", u.FirstName, u.LastName, preplacedMarker, u.GenderPreferences))
+	}
+	slog.Info("Calculating gender preferences for suite", "suite", suiteUUID, "users", userNames)
+
+
+	// --- Apply the rules, or the suite's approved proposal ---
+	decision, err := resolveSuiteGenderPreference(tx, suiteUUID, canBeGenderPreferenced, users, dormId)
 	if err != nil {
-		log.Printf("Failed to get dorm ID for suite %s: %v", suiteUUID, err)
+		slog.Error("Failed to check gender preference proposals", "suite", suiteUUID, "error", err)
 		return err
 	}
 
+	if len(decision.GenderPreferences) > 0 {
+		slog.Info("Setting gender preferences", "suite", suiteUUID, "gender_preferences", decision.GenderPreferences, "rule", decision.Rule)
+	} else {
+		// No specific preference determined (conflict, none specified, rule 3b, etc.) -> Set to empty
+		slog.Info("No specific gender preference determined, clearing it", "suite", suiteUUID, "rule", decision.Rule)
+	}
+
+	return saveSuiteGenderPreference(tx, suiteUUID, previous, decision)
+}
+
+// loadSuiteOccupants returns the users living in a suite, leaving out the occupants
+// of excludeRoom (uuid.Nil leaves out nobody)
+func loadSuiteOccupants(tx *sql.Tx, suiteUUID uuid.UUID, excludeRoom uuid.UUID) ([]models.UserRaw, error) {
 	// Get all rooms in the suite
 	var roomUUIDs models.UUIDArray
-	err = tx.QueryRow("SELECT rooms FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&roomUUIDs)
+	err := tx.QueryRow("SELECT rooms FROM suites WHERE suite_uuid = $1", suiteUUID).Scan(&roomUUIDs)
 	if err != nil {
-		log.Printf("Failed to get rooms for suite %s: %v", suiteUUID, err)
-		return err
+		slog.Error("Failed to get rooms", "suite", suiteUUID, "error", err)
+		return nil, err
 	}
 
 	// Get all users in the suite - directly join with the rooms table to ensure we only get actual room occupants
 	var users []models.UserRaw
 	for _, roomUUID := range roomUUIDs {
+		if roomUUID == excludeRoom {
+			continue
+		}
+
 		// Get occupants directly from the rooms table for this room
 		var occupantIds models.IntArray
 		err = tx.QueryRow("SELECT occupants FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&occupantIds)
 		if err != nil {
-			log.Printf("Failed to get occupants for room %s: %v", roomUUID, err)
+			slog.Error("Failed to get occupants", "room", roomUUID, "error", err)
 			continue
 		}
 
@@ -490,13 +545,13 @@ func UpdateSuiteGenderPreferencesBySuiteUUID(tx *sql.Tx, suiteUUID uuid.UUID) er
 			var userRoomUUID uuid.UUID
 			err = tx.QueryRow("SELECT room_uuid FROM users WHERE id = $1", occupantId).Scan(&userRoomUUID)
 			if err != nil {
-				log.Printf("Failed to check room for user ID %d: %v", occupantId, err)
+				slog.Error("Failed to check room for user", "user", occupantId, "error", err)
 				continue
 			}
 
 			// Skip if user isn't actually in this room anymore
 			if userRoomUUID != roomUUID {
-				log.Printf("User %d is not in room %s (in room %s instead), skipping", occupantId, roomUUID, userRoomUUID)
+				slog.Info("User is not in room, skipping", "user", occupantId, "room", roomUUID, "user_room", userRoomUUID)
 				continue
 			}
 
@@ -514,49 +569,14 @@ func UpdateSuiteGenderPreferencesBySuiteUUID(tx *sql.Tx, suiteUUID uuid.UUID) er
 				&user.GenderPreferences,
 			)
 			if err != nil {
-				log.Printf("Failed to get user data for ID %d: %v", occupantId, err)
+				slog.Error("Failed to get user data", "user", occupantId, "error", err)
 				continue
 			}
 			users = append(users, user)
 		}
 	}
 
-    // Log who we are considering
-	userNames := make([]string, 0, len(users))
-	for _, u := range users {
-        preplacedMarker := ""
-        if u.Preplaced {
-            preplacedMarker = " (P)"
-        }
-		userNames = append(userNames, fmt.Sprintf("%s %s%s %v", u.FirstName, u.LastName, preplacedMarker, u.GenderPreferences))
-	}
-	log.Printf("Calculating gender preferences for suite %s based on users: [%s]", suiteUUID, strings.Join(userNames, ", "))
-
-
-	// --- Call the corrected helper function ---
-	finalSuitePreference, found := GetSuiteGenderPreference(users, dormId) // Call the FIXED helper
-
-	// --- Update the database based *only* on the helper's result ---
-	var dbPreferenceArray pq.StringArray // Use pq.StringArray for potential empty array
-	if found {
-		// A specific preference was determined
-        dbPreferenceArray = pq.StringArray(finalSuitePreference)
-		log.Printf("Suite %s: Setting gender preferences to %v based on rules.", suiteUUID, finalSuitePreference)
-	} else {
-		// No specific preference determined (conflict, none specified, rule 3b, etc.) -> Set to empty
-        dbPreferenceArray = pq.StringArray([]string{})
-        log.Printf("Suite %s: Setting gender preferences to {} as no specific preference was determined by rules.", suiteUUID)
-	}
-
-	_, err = tx.Exec("UPDATE suites SET gender_preferences = $1 WHERE suite_uuid = $2",
-		dbPreferenceArray, suiteUUID)
-	if err != nil {
-		log.Printf("Failed to update gender preferences for suite %s to %v: %v", suiteUUID, dbPreferenceArray, err)
-		return err
-	}
-
-	log.Printf("Successfully updated gender preferences for suite %s", suiteUUID)
-	return nil
+	return users, nil
 }
 
 func SetSuiteFlags(c *gin.Context) {
@@ -572,12 +592,12 @@ func SetSuiteFlags(c *gin.Context) {
 		return
 	}
 
-	_, err := database.DB.Exec(
+	_, err := database.DB.ExecContext(c.Request.Context(),
 		"UPDATE suites SET animal_in_suite = $1, legacy_suite = $2, suite_notes = $3 WHERE suite_uuid = $4",
 		body.AnimalInSuite, body.LegacySuite, body.SuiteNotes, suiteUUID,
 	)
 	if err != nil {
-		log.Printf("Error updating suite flags for %s: %v", suiteUUID, err)
+		logging.FromContext(c).Error("Error updating suite flags", "suite", suiteUUID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update suite flags"})
 		return
 	}
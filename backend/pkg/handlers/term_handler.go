@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"roomdraw/backend/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// termSnapshotSources lists the live tables copied into term_snapshots when a
+// term closes, keyed by entity type, with the column that identifies a row.
+// Every table OpenTerm clears is here, including the ones emptied by deleting
+// users.
+var termSnapshotSources = map[string]struct {
+	table    string
+	idColumn string
+}{
+	models.EntityTypeUser:                     {"users", "id::text"},
+	models.EntityTypeRoom:                     {"rooms", "room_uuid::text"},
+	models.EntityTypeSuite:                    {"suites", "suite_uuid::text"},
+	models.EntityTypeSuiteGroup:               {"suitegroups", "sgroup_uuid::text"},
+	models.EntityTypeRateLimit:                {"user_rate_limits", "email"},
+	models.EntityTypeFavorite:                 {"user_favorites", "favorite_id::text"},
+	models.EntityTypeAlert:                    {"user_alerts", "alert_id::text"},
+	models.EntityTypeDrawGroup:                {"draw_groups", "group_id::text"},
+	models.EntityTypeDrawGroupMember:          {"draw_group_members", "group_id || ':' || user_id"},
+	models.EntityTypeGenderPreferenceProposal: {"suite_gender_preference_proposals", "proposal_id::text"},
+	models.EntityTypeGenderPreferenceApproval: {"suite_gender_preference_approvals", "proposal_id || ':' || user_id"},
+}
+
+// archiveTerm copies every live row of termSnapshotSources into the term's
+// snapshot, replacing rows archived before, and returns how many rows of each
+// entity type it copied
+func archiveTerm(tx *sql.Tx, termID int) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for entityType, source := range termSnapshotSources {
+		result, err := tx.Exec(`
+            INSERT INTO term_snapshots (term_id, entity_type, entity_id, data)
+            SELECT $1, $2, `+source.idColumn+`, to_jsonb(t) FROM `+source.table+` t
+            ON CONFLICT (term_id, entity_type, entity_id) DO UPDATE SET data = EXCLUDED.data`,
+			termID, entityType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %w", source.table, err)
+		}
+		counts[entityType], _ = result.RowsAffected()
+	}
+	return counts, nil
+}
+
+const termColumns = "term_id, name, status, opened_at, closed_at, opened_by, closed_by"
+
+func scanTerm(row interface{ Scan(...any) error }) (models.DrawTerm, error) {
+	var term models.DrawTerm
+	var closedAt sql.NullTime
+	err := row.Scan(&term.TermID, &term.Name, &term.Status, &term.OpenedAt, &closedAt, &term.OpenedBy, &term.ClosedBy)
+	if closedAt.Valid {
+		term.ClosedAt = &closedAt.Time
+	}
+	return term, err
+}
+
+// getTerm loads a term by the :termid URL parameter, writing an error response if it can't
+func getTerm(c *gin.Context) (models.DrawTerm, bool) {
+	termID, err := strconv.Atoi(c.Param("termid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid term ID"})
+		return models.DrawTerm{}, false
+	}
+
+	term, err := scanTerm(database.DB.QueryRowContext(c.Request.Context(),
+		"SELECT "+termColumns+" FROM draw_terms WHERE term_id = $1", termID))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Term not found"})
+		return models.DrawTerm{}, false
+	}
+	if err != nil {
+		logging.FromContext(c).Error("Error fetching term", "term", termID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch term"})
+		return models.DrawTerm{}, false
+	}
+
+	return term, true
+}
+
+// GetTerms lists every draw term, newest first
+func GetTerms(c *gin.Context) {
+	rows, err := database.DB.QueryContext(c.Request.Context(),
+		"SELECT "+termColumns+" FROM draw_terms ORDER BY term_id DESC")
+	if err != nil {
+		logging.FromContext(c).Error("Error querying draw terms", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve draw terms"})
+		return
+	}
+	defer rows.Close()
+
+	terms := []models.DrawTerm{}
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			logging.FromContext(c).Error("Error scanning draw term", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan draw terms"})
+			return
+		}
+		terms = append(terms, term)
+	}
+
+	c.JSON(http.StatusOK, terms)
+}
+
+// GetTermSnapshot returns the rows of one entity type as they were in a term.
+// Closed terms are read from term_snapshots, the open term from the live
+// tables, so the response has the same shape either way. Rooms and suites can
+// be filtered with ?dorm=<dorm number>.
+func GetTermSnapshot(entityType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		term, ok := getTerm(c)
+		if !ok {
+			return
+		}
+
+		var dorm *int
+		if dormParam := c.Query("dorm"); dormParam != "" {
+			d, err := strconv.Atoi(dormParam)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dorm"})
+				return
+			}
+			dorm = &d
+		}
+
+		var data []json.RawMessage
+		var err error
+		if term.Status == models.TermStatusOpen {
+			data, err = ExportEntities(c.Request.Context(), entityType, dorm)
+		} else {
+			data, err = scanJSONRows(database.DB.QueryContext(c.Request.Context(), `
+                SELECT data FROM term_snapshots
+                WHERE term_id = $1 AND entity_type = $2
+                  AND ($3::int IS NULL OR (data->>'dorm')::int = $3)
+                ORDER BY entity_id`, term.TermID, entityType, dorm))
+		}
+		if err != nil {
+			logging.FromContext(c).Error("Error reading snapshot", "entity_type", entityType, "term", term.TermID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve term data"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"term": term, "entityType": entityType, "data": data})
+	}
+}
+
+// ExportEntities returns the live rows of one entity type as JSON objects, in
+// the same shape they are archived in when a term closes. dorm, if set,
+// filters rooms and suites to one dorm.
+func ExportEntities(ctx context.Context, entityType string, dorm *int) ([]json.RawMessage, error) {
+	source, ok := termSnapshotSources[entityType]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity type %s", entityType)
+	}
+
+	return scanJSONRows(database.DB.QueryContext(ctx, `
+        SELECT to_jsonb(t) FROM `+source.table+` t
+        WHERE $1::int IS NULL OR (to_jsonb(t)->>'dorm')::int = $1
+        ORDER BY `+source.idColumn, dorm))
+}
+
+// scanJSONRows reads a single jsonb column from every row
+func scanJSONRows(rows *sql.Rows, err error) ([]json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []json.RawMessage{}
+	for rows.Next() {
+		var row json.RawMessage
+		if err := rows.Scan(&row); err != nil {
+			return nil, err
+		}
+		data = append(data, row)
+	}
+	return data, rows.Err()
+}
+
+// GetTermLogs returns the transaction logs recorded during a term, newest
+// first. ?limit= and ?offset= page through them.
+func GetTermLogs(c *gin.Context) {
+	term, ok := getTerm(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit < 1 || limit > 5000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 5000"})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	rows, err := database.DB.QueryContext(c.Request.Context(), `
+        SELECT to_jsonb(l) FROM transaction_logs l
+        WHERE term_id = $1
+        ORDER BY created_at DESC, log_id DESC
+        LIMIT $2 OFFSET $3`, term.TermID, limit, offset)
+	if err != nil {
+		logging.FromContext(c).Error("Error querying logs", "term", term.TermID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve term logs"})
+		return
+	}
+	defer rows.Close()
+
+	logs := []json.RawMessage{}
+	for rows.Next() {
+		var row json.RawMessage
+		if err := rows.Scan(&row); err != nil {
+			logging.FromContext(c).Error("Error scanning log", "term", term.TermID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan term logs"})
+			return
+		}
+		logs = append(logs, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"term": term, "logs": logs})
+}
+
+// CloseTerm archives the open term into term_snapshots and marks it closed.
+// Until a new term is opened the draw is read-only.
+func CloseTerm(c *gin.Context) {
+	email := c.GetString("email")
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+
+	var term models.DrawTerm
+	var snapshotCounts map[string]int64
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			logging.FromContext(c).Error("Panic during CLOSE_TERM", "panic", r)
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
+			}
+			return
+		}
+		if err != nil {
+			logging.FromContext(c).Error("Rolling back CLOSE_TERM", "error", err)
+			tx.Rollback()
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
+			}
+			return
+		}
+
+		if commitErr := tx.Commit(); commitErr != nil {
+			logging.FromContext(c).Error("Failed to commit CLOSE_TERM", "error", commitErr)
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+			}
+			return
+		}
+		logging.FromContext(c).Info("Committed CLOSE_TERM", "term", term.Name)
+
+		details := map[string]interface{}{"snapshot_counts": snapshotCounts}
+		loggingErr := logging.LogOperation(c, "CLOSE_TERM", models.EntityTypeTerm, strconv.Itoa(term.TermID), models.TermStatusOpen, models.TermStatusClosed, details)
+		if loggingErr != nil {
+			logging.FromContext(c).Warn("Failed to log CLOSE_TERM operation", "term", term.TermID, "error", loggingErr)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Term closed", "term": term, "snapshotCounts": snapshotCounts})
+	}()
+
+	term, err = scanTerm(tx.QueryRow("SELECT " + termColumns + " FROM draw_terms WHERE status = 'open' FOR UPDATE"))
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+		c.JSON(http.StatusConflict, gin.H{"error": "There is no open term to close"})
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	snapshotCounts, err = archiveTerm(tx, term.TermID)
+	if err != nil {
+		return
+	}
+
+	err = tx.QueryRow(`
+        UPDATE draw_terms SET status = 'closed', closed_at = CURRENT_TIMESTAMP, closed_by = $2
+        WHERE term_id = $1
+        RETURNING closed_at`, term.TermID, email).Scan(&term.ClosedAt)
+	term.Status = models.TermStatusClosed
+	term.ClosedBy = email
+}
+
+// OpenTerm starts a new draw term. Dorms, suites and rooms keep their layout,
+// but every placement, user, suite group and rate limit from the previous
+// term is cleared, along with the favorites, alerts, draw groups and gender
+// preference proposals of its users. Right before clearing them they are
+// archived again into the last closed term's snapshot, so nothing changed
+// since it closed is lost.
+func OpenTerm(c *gin.Context) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Term name is required"})
+		return
+	}
+	email := c.GetString("email")
+
+	tx, err := database.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
+	defer metrics.ObserveTransaction(c.FullPath(), time.Now())
+
+	var term models.DrawTerm
+	var previousTermID int
+	var snapshotCounts map[string]int64
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			logging.FromContext(c).Error("Panic during OPEN_TERM", "panic", r)
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error due to panic"})
+			}
+			return
+		}
+		if err != nil {
+			logging.FromContext(c).Error("Rolling back OPEN_TERM", "error", err)
+			tx.Rollback()
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction rollback error"})
+			}
+			return
+		}
+
+		if commitErr := tx.Commit(); commitErr != nil {
+			logging.FromContext(c).Error("Failed to commit OPEN_TERM", "error", commitErr)
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database transaction commit error"})
+			}
+			return
+		}
+		logging.FromContext(c).Info("Committed OPEN_TERM", "term", term.Name)
+
+		details := map[string]interface{}{"archived_term_id": previousTermID, "snapshot_counts": snapshotCounts}
+		loggingErr := logging.LogOperation(c, "OPEN_TERM", models.EntityTypeTerm, strconv.Itoa(term.TermID), nil, term, details)
+		if loggingErr != nil {
+			logging.FromContext(c).Warn("Failed to log OPEN_TERM operation", "term", term.TermID, "error", loggingErr)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Term opened", "term": term})
+	}()
+
+	// serialize against a concurrent close or open
+	_, err = tx.Exec("LOCK TABLE draw_terms IN EXCLUSIVE MODE")
+	if err != nil {
+		return
+	}
+
+	var openTerms, nameTaken int
+	err = tx.QueryRow("SELECT COUNT(*) FILTER (WHERE status = 'open'), COUNT(*) FILTER (WHERE name = $1) FROM draw_terms", request.Name).Scan(&openTerms, &nameTaken)
+	if err != nil {
+		return
+	}
+	if openTerms > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Close the current term before opening a new one"})
+		return
+	}
+	if nameTaken > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A term with that name already exists"})
+		return
+	}
+
+	// Archive what the reset is about to clear into the term it belongs to
+	err = tx.QueryRow("SELECT COALESCE(MAX(term_id), 0) FROM draw_terms WHERE status = 'closed'").Scan(&previousTermID)
+	if err != nil {
+		return
+	}
+	if previousTermID > 0 {
+		snapshotCounts, err = archiveTerm(tx, previousTermID)
+		if err != nil {
+			return
+		}
+	}
+
+	// Reset the draw, keeping the dorm and suite layout. Users keep their profile,
+	// notification settings and favorites and only lose their placement; what
+	// belongs to the closed term alone is deleted.
+	resetStatements := []string{
+		`UPDATE rooms SET occupants = '{}', current_occupancy = 0, pull_priority = DEFAULT,
+            sgroup_uuid = NULL, has_frosh = false`,
+		`UPDATE suites SET lock_pulled_room = NULL, gender_preferences = '{}', gender_preference_rule = '',
+            gender_preference_decided_by = '{}', suite_design = '',
+            animal_in_suite = false, suite_notes = ''`,
+		`UPDATE users SET room_uuid = NULL, sgroup_uuid = NULL, preplaced = false, in_dorm = 0,
+            reslife_role = 'none', participated = false, participation_time = NULL`,
+		`UPDATE user_favorites SET last_state = '{}', reachable = false`,
+		`DELETE FROM user_alerts`,
+		`DELETE FROM draw_groups`,
+		`DELETE FROM suite_gender_preference_proposals`,
+		`DELETE FROM suitegroups`,
+		`DELETE FROM user_rate_limits`,
+	}
+	for _, statement := range resetStatements {
+		if _, err = tx.Exec(statement); err != nil {
+			return
+		}
+	}
+
+	term, err = scanTerm(tx.QueryRow(`
+        INSERT INTO draw_terms (name, status, opened_by) VALUES ($1, 'open', $2)
+        RETURNING `+termColumns, request.Name, email))
+}
@@ -3,9 +3,9 @@ package handlers
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"net/http"
 	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
 	"roomdraw/backend/pkg/models"
 	"strconv"
 	"strings"
@@ -19,7 +19,7 @@ func GetUsers(c *gin.Context) {
 	rows, err := database.DB.Query("SELECT id, year, first_name, last_name, draw_number, preplaced, in_dorm, sgroup_uuid, participated, participation_time, room_uuid FROM users")
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -30,7 +30,7 @@ func GetUsers(c *gin.Context) {
 		var user models.UserRaw
 		if err := rows.Scan(&user.Id, &user.Year, &user.FirstName, &user.LastName, &user.DrawNumber, &user.Preplaced, &user.InDorm, &user.SGroupUUID, &user.Participated, &user.PartitipationTime, &user.RoomUUID); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
 			return
 		}
@@ -226,14 +226,14 @@ func GetUsersPagedAndSorted(c *gin.Context) {
 	}
 
 	// Log the final query for debugging
-	log.Printf("User search query: %s%s with args: %v", baseQuery, whereClause, args)
+	logging.FromContext(c).Debug("User search query", "query", baseQuery+whereClause, "args", args)
 
 	// Count total records query
 	countQuery := "SELECT COUNT(*) FROM users" + whereClause
 	var totalRecords int
 	err := database.DB.QueryRow(countQuery, args...).Scan(&totalRecords)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database count query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database count query failed"})
 		return
 	}
@@ -248,7 +248,7 @@ func GetUsersPagedAndSorted(c *gin.Context) {
 	// Execute the query
 	rows, err := database.DB.Query(query, args...)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -260,7 +260,7 @@ func GetUsersPagedAndSorted(c *gin.Context) {
 		if err := rows.Scan(&user.Id, &user.Year, &user.FirstName, &user.LastName, &user.DrawNumber,
 			&user.Preplaced, &user.InDorm, &user.SGroupUUID, &user.Participated,
 			&user.PartitipationTime, &user.RoomUUID, &user.ReslifeRole, &user.Email, &user.GenderPreferences); err != nil {
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
 			return
 		}
@@ -281,7 +281,7 @@ func GetUsersIdMap(c *gin.Context) {
 	rows, err := database.DB.Query("SELECT id, year, first_name, last_name, draw_number, preplaced, in_dorm, sgroup_uuid, participated, participation_time, room_uuid, reslife_role, gender_preferences FROM users")
 	if err != nil {
 
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -292,7 +292,7 @@ func GetUsersIdMap(c *gin.Context) {
 		var user models.UserRaw
 		if err := rows.Scan(&user.Id, &user.Year, &user.FirstName, &user.LastName, &user.DrawNumber, &user.Preplaced, &user.InDorm, &user.SGroupUUID, &user.Participated, &user.PartitipationTime, &user.RoomUUID, &user.ReslifeRole, &user.GenderPreferences); err != nil {
 			// Handle scan error
-			log.Println(err)
+			logging.FromContext(c).Error("Database scan failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database scan failed"})
 			return
 		}
@@ -320,7 +320,7 @@ func GetUser(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
 		}
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -350,7 +350,7 @@ func GetUserByEmail(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"found": false})
 			return
 		}
-		log.Println(err)
+		logging.FromContext(c).Error("Database query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database query failed"})
 		return
 	}
@@ -0,0 +1,116 @@
+// File: pkg/logging/logger.go
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loggerKey is the gin context key holding the request scoped logger
+const loggerKey = "logger"
+
+// sensitiveKeys are attribute keys whose values are never written out
+var sensitiveKeys = []string{"password", "pass", "secret", "token", "authorization", "api_key", "apikey"}
+
+// sensitivePatterns catch secrets embedded in free text, e.g. messages from the
+// standard log package which also flow through slog once Setup has run
+var sensitivePatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	// user:password@ in connection strings and URLs
+	{regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`), "${1}[REDACTED]@"},
+	// bearer tokens
+	{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-_.]+`), "${1}[REDACTED]"},
+}
+
+// ParseLevel maps a LOG_LEVEL value to a slog level
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+}
+
+// NewLogger builds a logger writing text or JSON to w, with secrets redacted
+func NewLogger(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// Setup installs logger as the default. Calls to the standard log package are
+// routed through it as well, so they are leveled and redacted too.
+func Setup(logger *slog.Logger) {
+	slog.SetDefault(logger)
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, "[REDACTED]")
+		}
+	}
+
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	}
+	return a
+}
+
+// RedactString masks passwords and tokens that appear inside s
+func RedactString(s string) string {
+	for _, p := range sensitivePatterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// RequestLoggerMiddleware puts a logger carrying the route and method into the
+// context. Later middleware add the request ID and user email with AddLogAttrs.
+func RequestLoggerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(loggerKey, slog.Default().With(
+			"route", c.FullPath(),
+			"method", c.Request.Method,
+		))
+		c.Next()
+	}
+}
+
+// AddLogAttrs adds attributes to the request logger for the rest of the request
+func AddLogAttrs(c *gin.Context, args ...any) {
+	c.Set(loggerKey, FromContext(c).With(args...))
+}
+
+// FromContext returns the request logger, or the default logger when the
+// request never went through RequestLoggerMiddleware
+func FromContext(c *gin.Context) *slog.Logger {
+	if c != nil {
+		if logger, ok := c.Get(loggerKey); ok {
+			if l, ok := logger.(*slog.Logger); ok {
+				return l
+			}
+		}
+	}
+	return slog.Default()
+}
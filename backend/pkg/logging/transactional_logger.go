@@ -2,8 +2,9 @@
 package logging
 
 import (
+	"database/sql"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"roomdraw/backend/pkg/database" // Ensure this path is correct
 
 	"github.com/gin-gonic/gin"
@@ -32,7 +33,7 @@ func LogOperation(
 
 	// If email is missing (e.g., unauthenticated endpoint somehow calling this), log a warning
 	if email == "" {
-		log.Printf("Warning: Attempted to log operation '%s' without user email in context for endpoint %s", operationType, ctx.Request.URL.Path)
+		FromContext(ctx).Warn("Attempted to log operation without user email in context", "operation", operationType, "path", ctx.Request.URL.Path)
 		// Decide if you want to return an error or proceed without user info
 		// return errors.New("user email not found in context for logging")
 	}
@@ -44,20 +45,20 @@ func LogOperation(
 	// Use helper to marshal, handling nil gracefully
 	prevStateJSON, err = marshalToJSON(previousState)
 	if err != nil {
-		log.Printf("Error marshaling previousState for log (%s, %s): %v", operationType, entityID, err)
+		FromContext(ctx).Error("Error marshaling previous state for log", "operation", operationType, "entity", entityID, "error", err)
 		// Continue logging even if state marshaling fails? Or return error?
 		// return fmt.Errorf("failed to marshal previous state: %w", err)
 	}
 
 	newStateJSON, err = marshalToJSON(newState)
 	if err != nil {
-		log.Printf("Error marshaling newState for log (%s, %s): %v", operationType, entityID, err)
+		FromContext(ctx).Error("Error marshaling new state for log", "operation", operationType, "entity", entityID, "error", err)
 		// return fmt.Errorf("failed to marshal new state: %w", err)
 	}
 
 	detailsJSON, err = marshalToJSON(details)
 	if err != nil {
-		log.Printf("Error marshaling details for log (%s, %s): %v", operationType, entityID, err)
+		FromContext(ctx).Error("Error marshaling details for log", "operation", operationType, "entity", entityID, "error", err)
 		// return fmt.Errorf("failed to marshal details: %w", err)
 	}
 
@@ -66,14 +67,14 @@ func LogOperation(
 	var requestID uuid.UUID
 	if !exists {
 		// Should ideally not happen if middleware is applied correctly, but generate one as fallback
-		log.Printf("Warning: request_id not found in context for %s, generating new one.", ctx.Request.URL.Path)
+		FromContext(ctx).Warn("request_id not found in context, generating a new one", "path", ctx.Request.URL.Path)
 		requestID = uuid.New()
 		ctx.Set("request_id", requestID) // Set it for potential subsequent logs in the same handler
 	} else {
 		var ok bool
 		requestID, ok = requestIDInterface.(uuid.UUID)
 		if !ok {
-			log.Printf("Error: request_id in context is not a UUID for %s. Generating new one.", ctx.Request.URL.Path)
+			FromContext(ctx).Warn("request_id in context is not a UUID, generating a new one", "path", ctx.Request.URL.Path)
 			requestID = uuid.New() // Fallback
 		}
 	}
@@ -82,8 +83,9 @@ func LogOperation(
 	sqlStatement := `
         INSERT INTO transaction_logs
         (operation_type, endpoint, user_email, user_name, entity_type, entity_id,
-         previous_state, new_state, details, ip_address, request_id)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+         previous_state, new_state, details, ip_address, request_id, term_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+                (SELECT term_id FROM draw_terms ORDER BY (status = 'open') DESC, term_id DESC LIMIT 1))`
 
 	_, err = database.DB.Exec(sqlStatement,
 		operationType,
@@ -101,17 +103,61 @@ func LogOperation(
 
 	if err != nil {
 		// Log the error but don't fail the original request because of logging failure
-		log.Printf("ERROR: Failed to insert transaction log for %s (%s): %v", operationType, entityID, err)
+		FromContext(ctx).Error("Failed to insert transaction log", "operation", operationType, "entity", entityID, "error", err)
 		// Return the error so the calling handler knows logging failed, but the handler should decide whether to proceed.
 		return err
 	}
 
-	log.Printf("INFO: Logged operation '%s' for entity %s:%s by user %s (RequestID: %s)",
-		operationType, entityType, entityID, email, requestID)
+	FromContext(ctx).Info("Logged operation", "operation", operationType, "entity_type", entityType, "entity", entityID)
 
 	return nil // Log successfully inserted
 }
 
+// LogSystemOperation records a change the draw rules made on their own, like a
+// recomputed suite gender preference, through the transaction that made it so the
+// entry is only kept if the change commits. It is logged as made by "system".
+func LogSystemOperation(
+	tx *sql.Tx,
+	operationType string,
+	entityType string,
+	entityID string,
+	previousState interface{},
+	newState interface{},
+	details map[string]interface{},
+) error {
+	prevStateJSON, err := marshalToJSON(previousState)
+	if err != nil {
+		slog.Error("Error marshaling previous state for log", "operation", operationType, "entity", entityID, "error", err)
+	}
+	newStateJSON, err := marshalToJSON(newState)
+	if err != nil {
+		slog.Error("Error marshaling new state for log", "operation", operationType, "entity", entityID, "error", err)
+	}
+	detailsJSON, err := marshalToJSON(details)
+	if err != nil {
+		slog.Error("Error marshaling details for log", "operation", operationType, "entity", entityID, "error", err)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO transaction_logs
+        (operation_type, endpoint, user_email, entity_type, entity_id,
+         previous_state, new_state, details, term_id)
+        VALUES ($1, 'system', 'system', $2, $3, $4, $5, $6,
+                (SELECT term_id FROM draw_terms ORDER BY (status = 'open') DESC, term_id DESC LIMIT 1))`,
+		operationType,
+		entityType,
+		entityID,
+		jsonbOrNull(prevStateJSON),
+		jsonbOrNull(newStateJSON),
+		jsonbOrNull(detailsJSON),
+	)
+	if err != nil {
+		slog.Error("Failed to insert system transaction log", "operation", operationType, "entity", entityID, "error", err)
+		return err
+	}
+	return nil
+}
+
 // TransactionLogMiddleware adds a unique request ID to the context for write operations.
 func TransactionLogMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -125,6 +171,7 @@ func TransactionLogMiddleware() gin.HandlerFunc {
 		// Add a unique request ID to the context for this request
 		requestID := uuid.New()
 		c.Set("request_id", requestID)
+		AddLogAttrs(c, "request_id", requestID.String())
 		// log.Printf("DEBUG: Set request_id %s for %s", requestID, c.Request.URL.Path) // Optional: Debug logging
 
 		// Process request
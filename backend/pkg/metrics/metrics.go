@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// PullsTotal counts pull requests by pull type and outcome
+	PullsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "roomdraw_pulls_total",
+		Help: "Pull requests by pull type and outcome.",
+	}, []string{"type", "outcome"})
+
+	// PullRejectionsTotal counts pulls turned away by the draw rules, by a fixed
+	// reason code
+	PullRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "roomdraw_pull_rejections_total",
+		Help: "Pull requests rejected by the draw rules, by pull type and reason.",
+	}, []string{"type", "reason"})
+
+	// ClearRoomsTotal counts committed room clears
+	ClearRoomsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "roomdraw_clear_rooms_total",
+		Help: "Rooms cleared by users.",
+	})
+
+	// RateLimitHitsTotal counts clear room requests stopped by the daily limit or the blocklist
+	RateLimitHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "roomdraw_rate_limit_hits_total",
+		Help: "Clear room rate limit hits by reason.",
+	}, []string{"reason"})
+
+	// NotificationsTotal counts bump notifications by result
+	NotificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "roomdraw_notifications_total",
+		Help: "Bump notifications by result.",
+	}, []string{"result"})
+
+	// TransactionDuration measures how long handler transactions stay open
+	TransactionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "roomdraw_db_transaction_duration_seconds",
+		Help:    "Time from beginning a handler transaction to commit or rollback, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+
+	// QueueWaitDuration measures how long writes wait for their suites
+	QueueWaitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "roomdraw_queue_wait_duration_seconds",
+		Help:    "Time write requests spend waiting in the request queue, by route.",
+		Buckets: []float64{.001, .005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"route"})
+)
+
+// pullTypeNames maps request pull types to metric labels
+var pullTypeNames = map[int]string{
+	1: "self",
+	2: "normal",
+	3: "lock",
+	4: "alternative",
+}
+
+// PullTypeLabel returns the label for a pull type
+func PullTypeLabel(pullType int) string {
+	if name, ok := pullTypeNames[pullType]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// PullOutcome maps the response status of a pull to an outcome label
+func PullOutcome(status int) string {
+	switch {
+	case status < 400:
+		return "success"
+	case status < 500:
+		return "rejected"
+	default:
+		return "error"
+	}
+}
+
+// ObserveTransaction records a transaction started at start. Handlers defer it
+// right after beginning the transaction so it runs after commit or rollback.
+func ObserveTransaction(route string, start time.Time) {
+	TransactionDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+}
+
+// Handler serves every registered metric in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	h := promhttp.Handler()
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// drawWideRoutes lists write routes that touch every suite at once, or suites
+// they cannot name up front. They lock all suites so that no pull can interleave
+// with them.
+var drawWideRoutes = map[string]bool{
+	"/admin/suites/update-gender-preferences":     true,
+	"/admin/dorms/:dormid/gender-preference-mode": true,
+	"/admin/terms":            true,
+	"/admin/terms/close":      true,
+	"/admin/frosh/plan/apply": true,
+}
+
+// entityLocks hands out one lock per key (usually a suite) so that writes to
+// disjoint suites can run concurrently while writes to the same suite are
+// serialized.
+type entityLocks struct {
+	mu    sync.Mutex
+	locks map[string]*entityLock
+}
+
+type entityLock struct {
+	sem  chan struct{}
+	refs int
+}
+
+func newEntityLocks() *entityLocks {
+	return &entityLocks{locks: make(map[string]*entityLock)}
+}
+
+// acquire locks every key in sorted order so that two requests needing
+// overlapping sets of suites can never deadlock. It gives up and releases
+// anything already held if ctx is done first.
+func (l *entityLocks) acquire(ctx context.Context, keys []string) (func(), error) {
+	keys = sortedUniqueKeys(keys)
+
+	held := make([]string, 0, len(keys))
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			l.unlock(held[i])
+		}
+	}
+
+	for _, key := range keys {
+		lock := l.ref(key)
+		select {
+		case lock.sem <- struct{}{}:
+			held = append(held, key)
+		case <-ctx.Done():
+			l.unref(key)
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// ref returns the lock for key, creating it if needed, and records a waiter
+func (l *entityLocks) ref(key string) *entityLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &entityLock{sem: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// unref drops a waiter and forgets the lock once nobody references it
+func (l *entityLocks) unref(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock := l.locks[key]
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+func (l *entityLocks) unlock(key string) {
+	l.mu.Lock()
+	lock := l.locks[key]
+	l.mu.Unlock()
+
+	<-lock.sem
+	l.unref(key)
+}
+
+func sortedUniqueKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// WriteLockMiddleware records the suites a write touches so its transaction
+// locks them in postgres, without queueing in this process. It is for callers
+// outside the server, like roomdrawctl, whose writes still have to wait for
+// pulls the server is running on the same suites.
+func WriteLockMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		keys, err := resolveLockKeys(c)
+		if err != nil {
+			logging.FromContext(c).Error("Failed to resolve locks", "path", c.Request.URL.Path, "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule request"})
+			return
+		}
+
+		locks := database.WriteLocks{DrawWide: drawWideRoutes[c.FullPath()], Keys: keys}
+		c.Request = c.Request.WithContext(database.WithWriteLocks(c.Request.Context(), locks))
+		c.Next()
+	}
+}
+
+// lockKeyPeek holds the body fields that name other rooms involved in a write
+type lockKeyPeek struct {
+	PullLeaderRoom string `json:"pullLeaderRoom"`
+	TargetRoomUUID string `json:"targetRoomUUID"`
+	// Moves are the bumps of a frosh chain
+	Moves []struct {
+		FromRoomUUID string `json:"fromRoomUuid"`
+		ToRoomUUID   string `json:"toRoomUuid"`
+	} `json:"moves"`
+}
+
+// resolveLockKeys works out which suites (and groups or users) a write request
+// touches. The room or suite in the URL is always included; pulls and frosh
+// bumps also name a second room in the body, which may sit in another suite, and
+// a frosh chain names the rooms of each of its bumps.
+func resolveLockKeys(c *gin.Context) ([]string, error) {
+	if drawWideRoutes[c.FullPath()] {
+		return allSuiteLockKeys()
+	}
+
+	var keys []string
+
+	if suiteUUID := c.Param("suiteuuid"); suiteUUID != "" {
+		keys = append(keys, "suite:"+suiteUUID)
+	}
+
+	roomUUIDs := []string{c.Param("roomuuid")}
+
+	if c.ContentType() == gin.MIMEJSON && c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		// put the body back so the handler can bind it as usual
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var peek lockKeyPeek
+		if json.Unmarshal(body, &peek) == nil {
+			roomUUIDs = append(roomUUIDs, peek.PullLeaderRoom, peek.TargetRoomUUID)
+			for _, m := range peek.Moves {
+				roomUUIDs = append(roomUUIDs, m.FromRoomUUID, m.ToRoomUUID)
+			}
+		}
+	}
+
+	for _, roomUUID := range roomUUIDs {
+		key, err := suiteLockKeyForRoom(roomUUID)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	// group and user writes only serialize against writes to the same group or
+	// user. Anything else that names no room (favorites, notification and
+	// profile settings) takes no key and relies on the handler's row locks.
+	if groupID := c.Param("groupid"); groupID != "" {
+		keys = append(keys, "group:"+groupID)
+	}
+	if userID := c.Param("userid"); userID != "" {
+		keys = append(keys, "user:"+userID)
+	}
+
+	return keys, nil
+}
+
+// suiteLockKeyForRoom maps a room to the lock key of its suite. Rooms never
+// change suite, so this lookup is safe to do before the lock is held.
+func suiteLockKeyForRoom(roomUUID string) (string, error) {
+	if _, err := uuid.Parse(roomUUID); err != nil {
+		return "", nil // empty or malformed, the handler will reject it
+	}
+
+	var suiteUUID uuid.UUID
+	err := database.DB.QueryRow("SELECT suite_uuid FROM rooms WHERE room_uuid = $1", roomUUID).Scan(&suiteUUID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "room:" + roomUUID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "suite:" + suiteUUID.String(), nil
+}
+
+func allSuiteLockKeys() ([]string, error) {
+	rows, err := database.DB.Query("SELECT suite_uuid FROM suites")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var suiteUUID uuid.UUID
+		if err := rows.Scan(&suiteUUID); err != nil {
+			return nil, err
+		}
+		keys = append(keys, "suite:"+suiteUUID.String())
+	}
+
+	return keys, rows.Err()
+}
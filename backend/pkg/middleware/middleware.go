@@ -1,14 +1,21 @@
 package middleware
 
 import (
+	"context"
 	"crypto/rsa"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
+	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/database"
+	"roomdraw/backend/pkg/logging"
+	"roomdraw/backend/pkg/metrics"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -44,33 +51,85 @@ var (
 	keysCacheTime time.Time
 )
 
+// googleKeysClient bounds how long a key refresh may take
+var googleKeysClient = &http.Client{Timeout: 10 * time.Second}
+
+// keysFetchMutex lets one caller at a time refresh the keys. The fetch runs
+// without cacheMutex held, so token checks keep using the cached keys meanwhile.
+var keysFetchMutex sync.Mutex
+
+// googleKeysFresh reports whether the cached keys are less than a day old
+func googleKeysFresh() bool {
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+
+	return time.Since(keysCacheTime) < 24*time.Hour && len(keysCache.Keys) > 0
+}
+
 // FetchGooglePublicKeys fetches and caches Google's public keys for JWT validation.
 func FetchGooglePublicKeys() error {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
+	if googleKeysFresh() {
+		return nil
+	}
+
+	keysFetchMutex.Lock()
+	defer keysFetchMutex.Unlock()
 
-	// Check if keys are still fresh, let's assume keys are refreshed every 24 hours for this example
-	if time.Since(keysCacheTime) < 24*time.Hour && len(keysCache.Keys) > 0 {
-		return nil // Keys are still fresh
+	// another caller may have refreshed the keys while this one waited
+	if googleKeysFresh() {
+		return nil
 	}
 
-	resp, err := http.Get(googleCertsURL)
+	resp, err := googleKeysClient.Get(googleCertsURL)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching Google public keys: %s", resp.Status)
+	}
+
 	var certs GooglePublicKeysResponse
 	if err := json.NewDecoder(resp.Body).Decode(&certs); err != nil {
-		log.Println("Response:", resp)
+		slog.Error("Failed to decode Google public keys", "status", resp.Status, "error", err)
 		return err
 	}
 
+	cacheMutex.Lock()
 	keysCache = certs
 	keysCacheTime = time.Now()
+	cacheMutex.Unlock()
 	return nil
 }
 
+// RefreshGooglePublicKeys keeps the key cache filled until ctx is done, checking
+// every interval, so that neither readiness nor token checks wait on Google
+func RefreshGooglePublicKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := FetchGooglePublicKeys(); err != nil {
+			slog.Warn("Failed to refresh Google public keys", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GooglePublicKeysAge reports how long ago the key cache was filled, and
+// whether it holds any keys at all
+func GooglePublicKeysAge() (time.Duration, bool) {
+	cacheMutex.RLock()
+	defer cacheMutex.RUnlock()
+
+	return time.Since(keysCacheTime), len(keysCache.Keys) > 0
+}
+
 // getKeyFunc is a helper function to select the appropriate key for JWT validation.
 func getKeyFunc(token *jwt.Token) (interface{}, error) {
 	// Ensure the token method conforms to "RS256"
@@ -118,64 +177,168 @@ func getKeyFunc(token *jwt.Token) (interface{}, error) {
 func getGooglePublicKey(token *jwt.Token) (interface{}, error) {
 	err := FetchGooglePublicKeys()
 	if err != nil {
-		log.Println("Error fetching Google public keys:", err)
+		slog.Error("Error fetching Google public keys", "error", err)
 		return nil, err
 	}
 	return getKeyFunc(token)
 }
 
-// RequestQueue represents a queue for serializing requests
+// RequestQueue serializes write requests that touch the same suites. Writes to
+// disjoint suites run concurrently. Its locks only cover this process; the
+// handlers' transactions take the same suites as postgres advisory locks (see
+// database.BeginTx), so the queue is what keeps waiting cheap and bounded.
+// Once maxDepth requests are waiting or running, new ones are turned away.
 type RequestQueue struct {
-	queue chan func()
+	locks    *entityLocks
+	stats    *queueStats
+	maxDepth int
 }
 
-// NewRequestQueue creates a new request queue with a specified concurrency
-func NewRequestQueue(concurrency int) *RequestQueue {
-	queue := &RequestQueue{
-		queue: make(chan func(), 100), // Buffer size of 100 pending requests
+// NewRequestQueue creates a new request queue backed by per-suite locks. A
+// maxDepth of 0 never rejects requests.
+func NewRequestQueue(maxDepth int) *RequestQueue {
+	return &RequestQueue{
+		locks:    newEntityLocks(),
+		stats:    newQueueStats(),
+		maxDepth: maxDepth,
 	}
+}
 
-	// Start worker goroutines
-	for i := 0; i < concurrency; i++ {
-		go queue.worker()
-	}
+// Drain stops the queue from admitting new writes and waits for the ones
+// already admitted to finish, or for ctx to be done
+func (q *RequestQueue) Drain(ctx context.Context) error {
+	q.stats.close()
 
-	return queue
-}
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
 
-// worker processes jobs from the queue
-func (q *RequestQueue) worker() {
-	for job := range q.queue {
-		job()
+	for q.stats.inFlight() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+	return nil
 }
 
-// QueueMiddleware creates middleware that serializes write operations
+// Draining reports whether the queue has stopped admitting writes
+func (q *RequestQueue) Draining() bool {
+	return q.stats.isClosed()
+}
+
+// QueueMiddleware creates middleware that serializes write operations on the same suites
 func QueueMiddleware(queue *RequestQueue) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Create a channel to signal when the request is done
-		done := make(chan struct{})
+		logger := logging.FromContext(c)
+		route := c.FullPath()
+		switch queue.stats.admit(route, queue.maxDepth) {
+		case rejectedClosed:
+			logger.Warn("Server is shutting down, rejecting write", "path", c.Request.URL.Path)
+			c.Header("Retry-After", "30")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is restarting, please try again shortly"})
+			return
+		case rejectedFull:
+			retryAfter := queue.stats.retryAfter()
+			logger.Warn("Write queue full, rejecting request", "path", c.Request.URL.Path, "retry_after_s", retryAfter)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is busy, please try again shortly"})
+			return
+		}
+		enqueued := time.Now()
 
-		// Create a timeout channel
-		timeout := time.After(30 * time.Second)
+		keys, err := resolveLockKeys(c)
+		if err != nil {
+			logger.Error("Failed to resolve locks", "path", c.Request.URL.Path, "error", err)
+			queue.stats.dropped(route)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule request"})
+			return
+		}
 
-		// Queue the request processing
-		queue.queue <- func() {
-			defer close(done)
-			// This executes the next handler in the chain
-			c.Next()
+		// The deadline covers both waiting for the suites and running the handler
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		release, err := queue.locks.acquire(ctx, keys)
+		if err != nil {
+			// Request timed out before it could start, so it never runs
+			logger.Warn("Request timed out waiting for its suites", "path", c.Request.URL.Path)
+			queue.stats.timedOut(route)
+			c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "Request processing timed out"})
+			return
 		}
+		defer release()
 
-		// Wait for completion or timeout
-		select {
-		case <-done:
-			// Request completed normally
-			log.Printf("Request processed: %s", c.Request.URL.Path)
-		case <-timeout:
-			// Request timed out
-			log.Printf("Request timed out: %s", c.Request.URL.Path)
+		// The deadline may have passed (or the client gone away) just as the locks were handed over
+		if ctx.Err() != nil {
+			logger.Warn("Request expired before it started, skipping", "path", c.Request.URL.Path)
+			queue.stats.timedOut(route)
 			c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "Request processing timed out"})
+			return
 		}
+
+		// Handlers begin their transactions with this context, so a request that
+		// runs past its deadline is rolled back and can never commit. It also
+		// carries the suites so the transaction locks them in postgres too,
+		// which is what keeps other instances and roomdrawctl out.
+		c.Request = c.Request.WithContext(database.WithWriteLocks(ctx, database.WriteLocks{DrawWide: drawWideRoutes[route], Keys: keys}))
+
+		started := time.Now()
+		queue.stats.started(route, started.Sub(enqueued))
+		metrics.QueueWaitDuration.WithLabelValues(route).Observe(started.Sub(enqueued).Seconds())
+		defer func() {
+			// runs even if a handler panics so the depth never drifts
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				queue.stats.expired(route, time.Since(started))
+			} else {
+				queue.stats.finished(route, time.Since(started))
+			}
+		}()
+
+		// This executes the next handler in the chain
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// A handler that committed before the deadline has already answered
+			// with a success, anything else never got its transaction through
+			if c.Writer.Written() && c.Writer.Status() < http.StatusBadRequest {
+				logging.FromContext(c).Warn("Request finished after its deadline", "path", c.Request.URL.Path, "status", c.Writer.Status())
+				return
+			}
+			logging.FromContext(c).Warn("Request ran past its deadline and was rolled back", "path", c.Request.URL.Path)
+			if !c.Writer.Written() {
+				c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "Request processing timed out"})
+			}
+			return
+		}
+		logging.FromContext(c).Info("Request processed", "path", c.Request.URL.Path, "status", c.Writer.Status())
+	}
+}
+
+// OpenTermMiddleware rejects writes while no draw term is open. Once a term is
+// closed its data is read-only until an admin opens the next one.
+func OpenTermMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		var open bool
+		err := database.DB.QueryRowContext(c.Request.Context(),
+			"SELECT EXISTS (SELECT 1 FROM draw_terms WHERE status = 'open')").Scan(&open)
+		if err != nil {
+			logging.FromContext(c).Error("Error checking for an open draw term", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		if !open {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "The draw term is closed, no changes can be made until a new term is opened"})
+			return
+		}
+
+		c.Next()
 	}
 }
 
@@ -196,12 +359,12 @@ func CORSMiddleware() gin.HandlerFunc {
 }
 
 // JWTAuthMiddleware checks if the JWT token is present and valid default value to false
-func JWTAuthMiddleware(requiresAdmin bool) gin.HandlerFunc {
+func JWTAuthMiddleware(auth config.AuthConfig, requiresAdmin bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		const BEARER_SCHEMA = "Bearer "
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			log.Println("Authorization header missing")
+			logging.FromContext(c).Warn("Authorization header missing")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
 			return
 		}
@@ -209,7 +372,7 @@ func JWTAuthMiddleware(requiresAdmin bool) gin.HandlerFunc {
 		tokenString := strings.TrimPrefix(authHeader, BEARER_SCHEMA)
 		token, err := jwt.Parse(tokenString, getGooglePublicKey)
 		if err != nil {
-			log.Println("Error parsing token:", err)
+			logging.FromContext(c).Warn("Error parsing token", "error", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
 		}
@@ -218,12 +381,12 @@ func JWTAuthMiddleware(requiresAdmin bool) gin.HandlerFunc {
 		if ve, ok := err.(*jwt.ValidationError); ok {
 			if ve.Errors&jwt.ValidationErrorExpired != 0 {
 				// Token is expired
-				log.Println("Token expired")
+				logging.FromContext(c).Warn("Token expired")
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token is expired"})
 				return
 			} else {
 				// Handle other validation errors
-				log.Println("Error parsing token:", err)
+				logging.FromContext(c).Warn("Error parsing token", "error", err)
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 				return
 			}
@@ -232,17 +395,18 @@ func JWTAuthMiddleware(requiresAdmin bool) gin.HandlerFunc {
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 			// print type of claims
 			if email, ok := claims["email"].(string); ok && strings.HasSuffix(email, "@g.hmc.edu") {
-				if requiresAdmin { // admins are tlam, smao
+				if requiresAdmin { // admins come from the ADMIN_USERS setting
 					user := strings.Split(email, "@")[0]
-					if user != "smao" && user != "tlam" && user != "aniksharma" && user != "elli" {
+					if !auth.IsAdmin(user) {
 						c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access to admin endpoint"})
 						return
 					}
 				}
 
 				c.Set("email", email)                   // Pass the email to the next middleware or handler
+				logging.AddLogAttrs(c, "email", email)  // Tag every later log line with the user
 				c.Set("user_full_name", claims["name"]) // Pass the user's full name to the next middleware or handler
-				log.Println("Email:", email)
+				logging.FromContext(c).Debug("Authenticated request")
 				c.Next()
 				return
 			}
@@ -276,7 +440,7 @@ func BlocklistCheckMiddleware() gin.HandlerFunc {
 				// User not in the rate limits table yet, so not blocklisted
 				isBlocklisted = false
 			} else {
-				log.Printf("Error checking blocklist status for %s: %v", email, err)
+				logging.FromContext(c).Error("Error checking blocklist status", "error", err)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 				c.Abort()
 				return
@@ -284,7 +448,7 @@ func BlocklistCheckMiddleware() gin.HandlerFunc {
 		}
 
 		if isBlocklisted {
-			log.Printf("Blocked request from blocklisted user: %s", email)
+			logging.FromContext(c).Warn("Blocked request from blocklisted user")
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":       "Your account has been temporarily restricted due to unusual activity. Please contact an administrator.",
 				"blocklisted": true,
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queueStats tracks how long write requests wait for their suites and how long
+// they take to run, broken down by route
+type queueStats struct {
+	mu     sync.Mutex
+	routes map[string]*routeStats
+	depth  int  // requests waiting or running across all routes
+	closed bool // set once the server starts shutting down
+}
+
+type routeStats struct {
+	waiting   int
+	running   int
+	processed int64
+	timedOut  int64
+	rejected  int64
+	started   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+	totalExec time.Duration
+	maxExec   time.Duration
+}
+
+// RouteQueueStats is the per-route view served by QueueStatsHandler
+type RouteQueueStats struct {
+	Route     string  `json:"route"`
+	Waiting   int     `json:"waiting"`
+	Running   int     `json:"running"`
+	Processed int64   `json:"processed"`
+	TimedOut  int64   `json:"timedOut"`
+	Rejected  int64   `json:"rejected"`
+	AvgWaitMs float64 `json:"avgWaitMs"`
+	MaxWaitMs float64 `json:"maxWaitMs"`
+	AvgExecMs float64 `json:"avgExecMs"`
+	MaxExecMs float64 `json:"maxExecMs"`
+}
+
+// QueueStats is a snapshot of the whole write queue
+type QueueStats struct {
+	Depth    int               `json:"depth"`
+	MaxDepth int               `json:"maxDepth"`
+	Routes   []RouteQueueStats `json:"routes"`
+}
+
+func newQueueStats() *queueStats {
+	return &queueStats{routes: make(map[string]*routeStats)}
+}
+
+func (s *queueStats) route(route string) *routeStats {
+	r, ok := s.routes[route]
+	if !ok {
+		r = &routeStats{}
+		s.routes[route] = r
+	}
+	return r
+}
+
+type admission int
+
+const (
+	admitted admission = iota
+	rejectedFull
+	rejectedClosed
+)
+
+// admit registers a new request unless the queue is closed or already
+// maxDepth deep
+func (s *queueStats) admit(route string, maxDepth int) admission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.route(route)
+	if s.closed {
+		r.rejected++
+		return rejectedClosed
+	}
+	if maxDepth > 0 && s.depth >= maxDepth {
+		r.rejected++
+		return rejectedFull
+	}
+	s.depth++
+	r.waiting++
+	return admitted
+}
+
+// close stops admitting requests
+func (s *queueStats) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+}
+
+func (s *queueStats) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// started moves a request from waiting to running
+func (s *queueStats) started(route string, wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.route(route)
+	r.waiting--
+	r.running++
+	r.started++
+	r.totalWait += wait
+	r.maxWait = max(r.maxWait, wait)
+}
+
+// timedOut drops a request that never got to run
+func (s *queueStats) timedOut(route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.route(route)
+	r.waiting--
+	r.timedOut++
+	s.depth--
+}
+
+// dropped forgets a request that could not be scheduled at all
+func (s *queueStats) dropped(route string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.route(route).waiting--
+	s.depth--
+}
+
+// expired records a request that started but ran past its deadline
+func (s *queueStats) expired(route string, exec time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.route(route)
+	r.running--
+	r.timedOut++
+	r.maxExec = max(r.maxExec, exec)
+	s.depth--
+}
+
+// finished records a request that ran to completion
+func (s *queueStats) finished(route string, exec time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.route(route)
+	r.running--
+	r.processed++
+	r.totalExec += exec
+	r.maxExec = max(r.maxExec, exec)
+	s.depth--
+}
+
+// inFlight returns how many requests are waiting or running
+func (s *queueStats) inFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.depth
+}
+
+// retryAfter estimates how many seconds the current backlog needs to clear
+func (s *queueStats) retryAfter() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var processed int64
+	var totalExec time.Duration
+	for _, r := range s.routes {
+		processed += r.processed
+		totalExec += r.totalExec
+	}
+	if processed == 0 {
+		return 1
+	}
+
+	avgExec := totalExec / time.Duration(processed)
+	seconds := int(math.Ceil((avgExec * time.Duration(s.depth)).Seconds()))
+	return min(max(seconds, 1), 30)
+}
+
+func (s *queueStats) snapshot(maxDepth int) QueueStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := QueueStats{Depth: s.depth, MaxDepth: maxDepth, Routes: []RouteQueueStats{}}
+	for route, r := range s.routes {
+		rs := RouteQueueStats{
+			Route:     route,
+			Waiting:   r.waiting,
+			Running:   r.running,
+			Processed: r.processed,
+			TimedOut:  r.timedOut,
+			Rejected:  r.rejected,
+			MaxWaitMs: durationMs(r.maxWait),
+			MaxExecMs: durationMs(r.maxExec),
+		}
+		if r.started > 0 {
+			rs.AvgWaitMs = durationMs(r.totalWait) / float64(r.started)
+		}
+		if r.processed > 0 {
+			rs.AvgExecMs = durationMs(r.totalExec) / float64(r.processed)
+		}
+		stats.Routes = append(stats.Routes, rs)
+	}
+	sort.Slice(stats.Routes, func(i, j int) bool { return stats.Routes[i].Route < stats.Routes[j].Route })
+
+	return stats
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// QueueStatsHandler serves queue depth, wait and execution times per route
+func QueueStatsHandler(queue *RequestQueue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, queue.stats.snapshot(queue.maxDepth))
+	}
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SuitePullPolicy represents an entry in the suite_pull_policies table. It decides
+// which rooms a normal pull can take and how far a pull leader's suite group can
+// grow. Dorms name one, and a suite can override its dorm's.
+//
+// A pull leader in a single can always pull one other room of their suite into a
+// suite group. The rest of the rules apply once the group grows past two rooms.
+type SuitePullPolicy struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// MaxRooms is the most rooms one pull leader's number can hold in a suite, their own included
+	MaxRooms int `json:"maxRooms"`
+	// SuiteRoomCounts limits groups past two rooms to suites with one of these room counts. Empty allows any
+	SuiteRoomCounts []int `json:"suiteRoomCounts"`
+	// RoomCombinations are the max occupancies a group past two rooms can be made of, e.g. [1, 1, 3]
+	// for two singles and a triple. Empty allows any
+	RoomCombinations [][]int `json:"roomCombinations"`
+	// InDormLeaderPast is how many rooms a group can have before its leader needs in dorm. 0 never needs it
+	InDormLeaderPast int `json:"inDormLeaderPast"`
+	// InDormSingles requires a single added past two rooms to be pulled by one student with in dorm
+	InDormSingles bool `json:"inDormSingles"`
+	// NormalPullTriples are triples a single with in dorm can normal pull three people into, and
+	// those three need not have in dorm
+	NormalPullTriples []string `json:"normalPullTriples"`
+}
+
+// DefaultSuitePullPolicy applies to dorms that do not name a known policy
+var DefaultSuitePullPolicy = SuitePullPolicy{
+	Name:     SuitePullPolicyNone,
+	MaxRooms: 2,
+}
+
+// SuiteGroupPull is a pull leader in a suite group pulling another room into it
+type SuiteGroupPull struct {
+	SuiteRoomCount int
+	// GroupRooms are the max occupancies of the rooms already in the group, the leader's included
+	GroupRooms []int
+	// RoomSize is the max occupancy of the room being pulled
+	RoomSize            int
+	Occupants           int
+	LeaderHasInDorm     bool
+	OccupantsHaveInDorm bool
+}
+
+// IsNormalPullTriple reports whether a room can be normal pulled as a triple
+func (p SuitePullPolicy) IsNormalPullTriple(roomID string) bool {
+	return slices.Contains(p.NormalPullTriples, roomID)
+}
+
+// CheckAddRoom returns why the policy does not allow a suite group pull, or nil if it does
+func (p SuitePullPolicy) CheckAddRoom(pull SuiteGroupPull) error {
+	rooms := len(pull.GroupRooms) + 1
+	if rooms > p.MaxRooms {
+		return fmt.Errorf("you can only pull %d rooms with your number in this suite", p.MaxRooms)
+	}
+	if rooms <= 2 {
+		return nil
+	}
+
+	if len(p.SuiteRoomCounts) > 0 && !slices.Contains(p.SuiteRoomCounts, pull.SuiteRoomCount) {
+		return fmt.Errorf("you can only pull more than two rooms with your number in a suite with %s rooms", joinInts(p.SuiteRoomCounts, " or "))
+	}
+
+	if p.InDormLeaderPast > 0 && rooms > p.InDormLeaderPast && !pull.LeaderHasInDorm {
+		return fmt.Errorf("you can only pull more than %d rooms with your number in this suite if the pull leader has in dorm", p.InDormLeaderPast)
+	}
+
+	if len(p.RoomCombinations) > 0 {
+		sizes := append(slices.Clone(pull.GroupRooms), pull.RoomSize)
+		allowed := false
+		var names []string
+		for _, combination := range p.RoomCombinations {
+			if isSubCombination(sizes, combination) {
+				allowed = true
+			}
+			names = append(names, joinInts(combination, " + "))
+		}
+		if !allowed {
+			return fmt.Errorf("the rooms pulled with one number in this suite must have sizes %s", strings.Join(names, " or "))
+		}
+	}
+
+	if p.InDormSingles && pull.RoomSize == 1 {
+		if pull.Occupants != 1 {
+			return errors.New("a single pulled into a suite group must have 1 occupant")
+		}
+		if !pull.OccupantsHaveInDorm {
+			return errors.New("the occupant of a single pulled into a suite group must have in dorm")
+		}
+	}
+
+	return nil
+}
+
+// isSubCombination reports whether every room size in sizes can be matched to a
+// different room size in combination
+func isSubCombination(sizes, combination []int) bool {
+	remaining := slices.Clone(combination)
+	for _, size := range sizes {
+		i := slices.Index(remaining, size)
+		if i < 0 {
+			return false
+		}
+		remaining = slices.Delete(remaining, i, i+1)
+	}
+	return true
+}
+
+func joinInts(values []int, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,138 @@
+package models
+
+import "testing"
+
+// the policies as seeded by migration 0011
+var (
+	southPolicy = SuitePullPolicy{
+		Name:            "three_room_suite",
+		MaxRooms:        3,
+		SuiteRoomCounts: []int{3},
+	}
+	drinkwardPolicy = SuitePullPolicy{
+		Name:              "single_and_triple",
+		MaxRooms:          3,
+		RoomCombinations:  [][]int{{1, 1, 3}},
+		InDormLeaderPast:  2,
+		InDormSingles:     true,
+		NormalPullTriples: []string{"123C", "124C"},
+	}
+)
+
+func TestCheckAddRoom(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  SuitePullPolicy
+		pull    SuiteGroupPull
+		wantErr bool
+	}{
+		{
+			name:   "default allows a second room",
+			policy: DefaultSuitePullPolicy,
+			pull:   SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1}, RoomSize: 1, Occupants: 1},
+		},
+		{
+			name:    "default refuses a third room",
+			policy:  DefaultSuitePullPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 3, GroupRooms: []int{1, 1}, RoomSize: 1, Occupants: 1},
+			wantErr: true,
+		},
+		{
+			name:   "south allows a third room in a three room suite",
+			policy: southPolicy,
+			pull:   SuiteGroupPull{SuiteRoomCount: 3, GroupRooms: []int{1, 1}, RoomSize: 1, Occupants: 1},
+		},
+		{
+			name:    "south refuses a third room in a four room suite",
+			policy:  southPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 1}, RoomSize: 1, Occupants: 1},
+			wantErr: true,
+		},
+		{
+			name:    "south refuses a fourth room",
+			policy:  southPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 3, GroupRooms: []int{1, 1, 1}, RoomSize: 1, Occupants: 1},
+			wantErr: true,
+		},
+		{
+			name:   "south does not need in dorm",
+			policy: southPolicy,
+			pull:   SuiteGroupPull{SuiteRoomCount: 3, GroupRooms: []int{2, 1}, RoomSize: 1, Occupants: 1},
+		},
+		{
+			name:   "drinkward allows a second room without in dorm",
+			policy: drinkwardPolicy,
+			pull:   SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1}, RoomSize: 3, Occupants: 3},
+		},
+		{
+			name:   "drinkward allows a single and a triple with in dorm",
+			policy: drinkwardPolicy,
+			pull: SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 3}, RoomSize: 1, Occupants: 1,
+				LeaderHasInDorm: true, OccupantsHaveInDorm: true},
+		},
+		{
+			name:   "drinkward allows the triple last",
+			policy: drinkwardPolicy,
+			pull:   SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 1}, RoomSize: 3, Occupants: 3, LeaderHasInDorm: true},
+		},
+		{
+			name:    "drinkward needs the leader to have in dorm past two rooms",
+			policy:  drinkwardPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 3}, RoomSize: 1, Occupants: 1, OccupantsHaveInDorm: true},
+			wantErr: true,
+		},
+		{
+			name:    "drinkward refuses two triples",
+			policy:  drinkwardPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 3}, RoomSize: 3, Occupants: 3, LeaderHasInDorm: true},
+			wantErr: true,
+		},
+		{
+			name:    "drinkward refuses three singles",
+			policy:  drinkwardPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 1}, RoomSize: 1, Occupants: 1, LeaderHasInDorm: true, OccupantsHaveInDorm: true},
+			wantErr: true,
+		},
+		{
+			name:   "drinkward refuses a single without in dorm",
+			policy: drinkwardPolicy,
+			pull: SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 3}, RoomSize: 1, Occupants: 1,
+				LeaderHasInDorm: true},
+			wantErr: true,
+		},
+		{
+			name:   "drinkward refuses an empty single",
+			policy: drinkwardPolicy,
+			pull: SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 3}, RoomSize: 1, Occupants: 0,
+				LeaderHasInDorm: true, OccupantsHaveInDorm: true},
+			wantErr: true,
+		},
+		{
+			name:    "drinkward refuses a fourth room",
+			policy:  drinkwardPolicy,
+			pull:    SuiteGroupPull{SuiteRoomCount: 4, GroupRooms: []int{1, 1, 3}, RoomSize: 1, Occupants: 1, LeaderHasInDorm: true, OccupantsHaveInDorm: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.CheckAddRoom(tt.pull)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckAddRoom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsNormalPullTriple(t *testing.T) {
+	if !drinkwardPolicy.IsNormalPullTriple("123C") {
+		t.Error("123C should be a normal pull triple")
+	}
+	if drinkwardPolicy.IsNormalPullTriple("123A") {
+		t.Error("123A should not be a normal pull triple")
+	}
+	if DefaultSuitePullPolicy.IsNormalPullTriple("123C") {
+		t.Error("the default policy has no normal pull triples")
+	}
+}
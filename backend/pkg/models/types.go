@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -116,9 +117,11 @@ type SuiteRaw struct {
 	ReslifeRoom            uuid.UUID      `db:"reslife_room"`
 	GenderPreferences      pq.StringArray `db:"gender_preferences"`
 	CanBeGenderPreferenced bool           `db:"can_be_gender_preferenced"`
+	GenderPreferenceRule   string         `db:"gender_preference_rule"` // see GenderPreferenceRule*
 	AnimalInSuite          bool           `db:"animal_in_suite"`
 	LegacySuite            bool           `db:"legacy_suite"`
 	SuiteNotes             string         `db:"suite_notes"`
+	SuitePullPolicy        string         `db:"suite_pull_policy"` // overrides the dorm's policy if set
 }
 
 type DormSimple struct {
@@ -145,11 +148,13 @@ type SuiteSimple struct {
 	SuiteDesign       string         `json:"suiteDesign"`
 	SuiteUUID         uuid.UUID      `json:"suiteUUID"`
 	GenderPreferences pq.StringArray `json:"genderPreferences"`
-	AlternativePull   bool           `json:"alternative_pull"`
-	CanLockPull       bool           `json:"can_lock_pull"`
-	AnimalInSuite     bool           `json:"animalInSuite"`
-	LegacySuite       bool           `json:"legacySuite"`
-	SuiteNotes        string         `json:"suiteNotes"`
+	// GenderPreferenceRule is the rule that decided GenderPreferences
+	GenderPreferenceRule string `json:"genderPreferenceRule"`
+	AlternativePull      bool   `json:"alternative_pull"`
+	CanLockPull          bool   `json:"can_lock_pull"`
+	AnimalInSuite        bool   `json:"animalInSuite"`
+	LegacySuite          bool   `json:"legacySuite"`
+	SuiteNotes           string `json:"suiteNotes"`
 }
 
 type SuiteSimpler struct {
@@ -284,7 +289,228 @@ type UserRateLimit struct {
 }
 
 const (
-	EntityTypeRoom  = "ROOM"
-	EntityTypeUser  = "USER"
-	EntityTypeSuite = "SUITE"
-)
\ No newline at end of file
+	EntityTypeRoom          = "ROOM"
+	EntityTypeUser          = "USER"
+	EntityTypeSuite         = "SUITE"
+	EntityTypeSuiteGroup    = "SUITEGROUP"
+	EntityTypeRateLimit     = "RATE_LIMIT"
+	EntityTypeTerm          = "TERM"
+	EntityTypeDrawGroup     = "DRAW_GROUP"
+	EntityTypeDorm          = "DORM"
+	EntityTypeProfileWindow = "PROFILE_WINDOW"
+
+	EntityTypeFavorite                 = "FAVORITE"
+	EntityTypeAlert                    = "ALERT"
+	EntityTypeDrawGroupMember          = "DRAW_GROUP_MEMBER"
+	EntityTypeGenderPreferenceProposal = "GENDER_PREFERENCE_PROPOSAL"
+	EntityTypeGenderPreferenceApproval = "GENDER_PREFERENCE_APPROVAL"
+)
+
+// DrawTerm represents an entry in the draw_terms table
+type DrawTerm struct {
+	TermID   int        `json:"termId"`
+	Name     string     `json:"name"`
+	Status   string     `json:"status"` // open or closed
+	OpenedAt time.Time  `json:"openedAt"`
+	ClosedAt *time.Time `json:"closedAt"`
+	OpenedBy string     `json:"openedBy"`
+	ClosedBy string     `json:"closedBy"`
+}
+
+const (
+	TermStatusOpen   = "open"
+	TermStatusClosed = "closed"
+)
+
+// DrawGroup is a group of students who agreed before the draw to pull together
+type DrawGroup struct {
+	GroupID     int               `json:"groupId"`
+	Name        string            `json:"name"`
+	LeaderID    int               `json:"leaderId"`
+	Status      string            `json:"status"` // forming, confirmed or pulled
+	CreatedAt   time.Time         `json:"createdAt"`
+	ConfirmedAt *time.Time        `json:"confirmedAt"`
+	Members     []DrawGroupMember `json:"members"`
+	// EffectivePriority is the priority of the best accepted member, who the group pulls with
+	EffectivePriority *PullPriority `json:"effectivePriority"`
+	PriorityUserID    *int          `json:"priorityUserId"`
+	GenderPreferences []string      `json:"genderPreferences"`
+	// Issues lists why the group cannot be confirmed or pull right now
+	Issues []string `json:"issues"`
+}
+
+// DrawGroupMember is a student invited to a draw group
+type DrawGroupMember struct {
+	UserID      int        `json:"userId"`
+	FirstName   string     `json:"firstName"`
+	LastName    string     `json:"lastName"`
+	Email       string     `json:"email"`
+	Status      string     `json:"status"` // invited, accepted or declined
+	InvitedAt   time.Time  `json:"invitedAt"`
+	RespondedAt *time.Time `json:"respondedAt"`
+}
+
+const (
+	DrawGroupStatusForming   = "forming"
+	DrawGroupStatusConfirmed = "confirmed"
+	DrawGroupStatusPulled    = "pulled"
+
+	DrawGroupMemberInvited  = "invited"
+	DrawGroupMemberAccepted = "accepted"
+	DrawGroupMemberDeclined = "declined"
+)
+
+// Dorm represents an entry in the dorms table, the rules the draw applies in a dorm
+type Dorm struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// FroshPolicy is how frosh rooms are placed: whole_suite, one_per_suite or room
+	FroshPolicy string `json:"froshPolicy"`
+	// SuitePullPolicy names the suite_pull_policies entry its suites follow
+	SuitePullPolicy string   `json:"suitePullPolicy"`
+	Capabilities    []string `json:"capabilities"`
+	// GenderPreferenceMode is whether pulls have to respect suite gender preferences: advisory or enforced
+	GenderPreferenceMode string `json:"genderPreferenceMode"`
+}
+
+// Can reports whether the dorm has a capability
+func (d Dorm) Can(capability string) bool {
+	return slices.Contains(d.Capabilities, capability)
+}
+
+const (
+	FroshPolicyWholeSuite  = "whole_suite"
+	FroshPolicyOnePerSuite = "one_per_suite"
+	FroshPolicyRoom        = "room"
+
+	SuitePullPolicyNone = "none"
+
+	DormCapabilityInDorm = "in_dorm"
+	DormCapabilityFrosh  = "frosh"
+
+	GenderPreferenceModeAdvisory = "advisory"
+	GenderPreferenceModeEnforced = "enforced"
+)
+
+// ReslifeAllocation is a suite's ResLife room and the staff placed in it
+type ReslifeAllocation struct {
+	SuiteUUID uuid.UUID `json:"suiteUuid"`
+	Dorm      int       `json:"dorm"`
+	DormName  string    `json:"dormName"`
+	RoomUUID  uuid.UUID `json:"roomUuid"`
+	RoomID    string    `json:"roomId"`
+	// Role is who the room is held for: mentor or proctor
+	Role      string            `json:"role"`
+	Occupants []ReslifeOccupant `json:"occupants"`
+}
+
+// ReslifeOccupant is a student placed in a ResLife room
+type ReslifeOccupant struct {
+	UserID      int    `json:"userId"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	ReslifeRole string `json:"reslifeRole"`
+}
+
+const (
+	ReslifeRoleNone    = "none"
+	ReslifeRoleMentor  = "mentor"
+	ReslifeRoleProctor = "proctor"
+)
+
+// GenderPreferenceDecision is a suite's gender preference as the draw rules work it out
+// from its occupants
+type GenderPreferenceDecision struct {
+	GenderPreferences []string `json:"genderPreferences"`
+	Rule              string   `json:"rule"`
+	// DecidedBy are the occupants whose preferences were used
+	DecidedBy []int `json:"decidedBy"`
+	// Conflict is set when the preplaced occupants' preferences have nothing in common
+	Conflict bool `json:"conflict"`
+}
+
+// SuiteGenderPreference is a suite's gender preference, why it was chosen and the
+// changes its occupants have proposed
+type SuiteGenderPreference struct {
+	SuiteUUID              uuid.UUID                  `json:"suiteUuid"`
+	CanBeGenderPreferenced bool                       `json:"canBeGenderPreferenced"`
+	GenderPreferences      []string                   `json:"genderPreferences"`
+	Rule                   string                     `json:"rule"`
+	DecidedBy              []int                      `json:"decidedBy"`
+	Occupants              []int                      `json:"occupants"`
+	Proposals              []GenderPreferenceProposal `json:"proposals"`
+}
+
+// GenderPreferenceProposal is a gender preference an occupant asked for, which takes
+// over from the draw rules once every occupant has approved it
+type GenderPreferenceProposal struct {
+	ProposalID        int        `json:"proposalId"`
+	SuiteUUID         uuid.UUID  `json:"suiteUuid"`
+	ProposedBy        int        `json:"proposedBy"`
+	GenderPreferences []string   `json:"genderPreferences"`
+	Status            string     `json:"status"` // open, approved, rejected or superseded
+	CreatedAt         time.Time  `json:"createdAt"`
+	DecidedAt         *time.Time `json:"decidedAt"`
+	ApprovedBy        []int      `json:"approvedBy"`
+}
+
+const (
+	// Rule 1: the suite cannot be gender preferenced
+	GenderPreferenceRuleCannotBePreferenced = "1"
+	// Rule 2: the highest priority occupant with preferences
+	GenderPreferenceRulePriority = "2"
+	// Rule 3a: the intersection of the preplaced occupants' preferences
+	GenderPreferenceRulePreplaced = "3a"
+	// Rule 3b: preplaced occupants without preferences leave the suite open
+	GenderPreferenceRulePreplacedNone = "3b"
+	// Every occupant approved a proposal
+	GenderPreferenceRuleProposal = "proposal"
+	// Nobody lives in the suite
+	GenderPreferenceRuleEmpty = "empty"
+
+	GenderPreferenceProposalOpen       = "open"
+	GenderPreferenceProposalApproved   = "approved"
+	GenderPreferenceProposalRejected   = "rejected"
+	GenderPreferenceProposalSuperseded = "superseded"
+)
+
+// GenderPreferences are the gender preferences a student or suite can have, as
+// the housing form and the search filters spell them
+var GenderPreferences = []string{"Cis Woman", "Trans Woman", "Cis Man", "Trans Man", "Non-Binary"}
+
+// UserProfile is what a signed in student sees and can change about themselves
+type UserProfile struct {
+	UserID               int        `json:"userId"`
+	Email                string     `json:"email"`
+	FirstName            string     `json:"firstName"`
+	LastName             string     `json:"lastName"`
+	DisplayName          string     `json:"displayName"`
+	GenderPreferences    []string   `json:"genderPreferences"`
+	NotificationsEnabled bool       `json:"notificationsEnabled"`
+	NotificationChannels []string   `json:"notificationChannels"`
+	RoomUUID             *uuid.UUID `json:"roomUuid"`
+	// Editable lists the fields whose edit window is open right now
+	Editable []string `json:"editable"`
+	// EditWindows are the open and upcoming edit windows
+	EditWindows []ProfileEditWindow `json:"editWindows"`
+}
+
+// ProfileEditWindow is a time an admin lets students change one profile field
+type ProfileEditWindow struct {
+	WindowID  int       `json:"windowId"`
+	Field     string    `json:"field"` // gender_preferences, notifications or display_name
+	OpensAt   time.Time `json:"opensAt"`
+	ClosesAt  time.Time `json:"closesAt"`
+	CreatedBy string    `json:"createdBy"`
+}
+
+const (
+	ProfileFieldGenderPreferences = "gender_preferences"
+	ProfileFieldNotifications     = "notifications"
+	ProfileFieldDisplayName       = "display_name"
+
+	NotificationChannelBumps     = "bumps"
+	NotificationChannelFrosh     = "frosh"
+	NotificationChannelFavorites = "favorites"
+)
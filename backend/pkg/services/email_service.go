@@ -2,7 +2,7 @@ package services
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/smtp"
 	"roomdraw/backend/pkg/config"
 	"roomdraw/backend/pkg/models"
@@ -15,30 +15,42 @@ type EmailService struct {
 	senderPass  string
 }
 
-func NewEmailService() *EmailService {
-	log.Println("Email username:", config.EmailUsername)
+func NewEmailService(email config.EmailConfig) *EmailService {
+	slog.Info("Email service configured", "username", email.Username)
 	return &EmailService{
-		smtpHost:    "smtp.cs.hmc.edu",
-		smtpPort:    "587",
-		senderEmail: config.EmailUsername + "@cs.hmc.edu",
-		senderPass:  config.EmailPassword,
+		smtpHost:    email.SMTPHost,
+		smtpPort:    email.SMTPPort,
+		senderEmail: email.Username + "@cs.hmc.edu",
+		senderPass:  email.Password,
 	}
 }
 
 func (s *EmailService) SendBumpNotification(user models.UserRaw, roomID string, dormName string) error {
+	subject := fmt.Sprintf("(no-reply) Digital Draw Notification - Bumped from %s, %s", dormName, roomID)
+	return s.send(user, subject, fmt.Sprintf("This email is to notify you that you have been bumped from room %s in %s Dorm.", roomID, dormName))
+}
+
+func (s *EmailService) SendFavoriteAlert(user models.UserRaw, alert string) error {
+	return s.send(user, "(no-reply) Digital Draw Notification - One of your favorites changed", alert)
+}
+
+func (s *EmailService) SendFroshNotification(user models.UserRaw, change string) error {
+	return s.send(user, "(no-reply) Digital Draw Notification - Frosh placement changed in your suite", change)
+}
+
+// send emails text to user under subject, with the greeting and sign-off every
+// notification shares
+func (s *EmailService) send(user models.UserRaw, subject string, text string) error {
 	auth := smtp.PlainAuth("", s.senderEmail, s.senderPass, s.smtpHost)
 
 	to := []string{user.Email}
-	// // for testing, set to tlam@g.hmc.edu
-	// to := []string{"elli@g.hmc.edu"}
 
-	subject := fmt.Sprintf("(no-reply) Digital Draw Notification - Bumped from %s, %s", dormName, roomID)
 	body := fmt.Sprintf(
 		"Dear %s %s,\n\n"+
-			"This email is to notify you that you have been bumped from room %s in %s Dorm.\n"+
+			"%s\n"+
 			"Please log in to the room draw system to view more details.\n\n"+
 			"Best regards,\nDigiDraw System",
-		user.FirstName, user.LastName, roomID, dormName,
+		user.FirstName, user.LastName, text,
 	)
 
 	message := fmt.Sprintf("Subject: %s\r\n"+
@@ -47,7 +59,7 @@ func (s *EmailService) SendBumpNotification(user models.UserRaw, roomID string,
 		"\r\n"+
 		"%s", subject, s.senderEmail, to[0], body)
 
-	log.Printf("Attempting to send email to %s via %s:%s", to[0], s.smtpHost, s.smtpPort)
+	slog.Info("Sending notification email", "to", to[0], "subject", subject, "smtp_host", s.smtpHost, "smtp_port", s.smtpPort)
 
 	err := smtp.SendMail(
 		s.smtpHost+":"+s.smtpPort,
@@ -58,7 +70,7 @@ func (s *EmailService) SendBumpNotification(user models.UserRaw, roomID string,
 	)
 
 	if err != nil {
-		log.Printf("Failed to send bump notification email: %v", err)
+		slog.Error("Failed to send notification email", "to", to[0], "subject", subject, "error", err)
 		return err
 	}
 
@@ -1,5 +1,6 @@
 # import env variables
 import os
+import subprocess
 from pathlib import Path
 
 from dotenv import load_dotenv
@@ -26,32 +27,11 @@ with engine.connect() as connection:
         result = connection.execute(text(query))
         connection.commit()
 
-    with open("../sql/CreateSuitesTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
-
-    with open("../sql/CreateGroupsTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
-
-    with open("../sql/CreateUserTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
-
-    with open("../sql/CreateRoomTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
-
-    with open("../sql/CreateRateLimitTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
-
-    with open("../sql/CreateTransactionLogsTable.sql", "r", encoding="utf-8") as file:
-        query = file.read()
-        result = connection.execute(text(query))
-        connection.commit()
+# The schema itself is owned by the versioned migrations embedded in the Go
+# server, so recreate the tables by running them against the same database
+backend_dir = Path(__file__).resolve().parents[2] / "backend"
+subprocess.run(
+    ["go", "run", "./cmd/server", "migrate", "up", "-env-file", str(env_path.resolve())],
+    cwd=backend_dir,
+    check=True,
+)
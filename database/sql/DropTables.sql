@@ -3,4 +3,7 @@ DROP TABLE IF EXISTS users;
 DROP TABLE IF EXISTS suitegroups;
 DROP TABLE IF EXISTS suites;
 DROP TABLE IF EXISTS user_rate_limits;
-DROP TABLE IF EXISTS transaction_logs;
\ No newline at end of file
+DROP TABLE IF EXISTS transaction_logs;
+DROP TABLE IF EXISTS term_snapshots;
+DROP TABLE IF EXISTS draw_terms;
+DROP TABLE IF EXISTS schema_migrations;